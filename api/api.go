@@ -1,12 +1,41 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/devon-caron/metrifuge/api/internal/handlers"
+	"github.com/devon-caron/metrifuge/global"
 	"github.com/devon-caron/metrifuge/logger"
+	"github.com/go-chi/chi"
 	"github.com/sirupsen/logrus"
 )
 
-var log *logrus.Logger
+var (
+	log    *logrus.Logger
+	server *http.Server
+)
 
 func StartApi() {
 	log = logger.Get()
+
+	router := chi.NewRouter()
+	handlers.RouterHandler(router)
+
+	server = &http.Server{Addr: ":" + global.API_PORT, Handler: router}
+	go func() {
+		log.Infof("api listening on port %s", global.API_PORT)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("api server stopped: %v", err)
+		}
+	}()
+}
+
+// StopApi stops accepting requests and waits for the ones in progress to complete.
+func StopApi(ctx context.Context) error {
+	if server == nil {
+		return nil
+	}
+	return server.Shutdown(ctx)
 }
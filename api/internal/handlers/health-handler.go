@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/devon-caron/metrifuge/api/errhandler"
+	"github.com/devon-caron/metrifuge/exporter_manager/circuit_breaker"
+)
+
+// HealthResponse reports the health of every export destination
+type HealthResponse struct {
+	// "ok" when every circuit breaker is closed, "degraded" otherwise
+	Status string
+
+	// Circuit breaker status of each exporter
+	Exporters []circuit_breaker.Status
+}
+
+func HealthHandler(w http.ResponseWriter, r *http.Request) {
+	statuses := circuit_breaker.GetRegistry().Statuses()
+
+	response := HealthResponse{
+		Status:    "ok",
+		Exporters: statuses,
+	}
+	for _, status := range statuses {
+		if status.State != circuit_breaker.Closed {
+			response.Status = "degraded"
+			break
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		errhandler.InternalErrorHandler(w)
+		return
+	}
+}
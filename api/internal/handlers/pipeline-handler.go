@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/devon-caron/metrifuge/api/errhandler"
+	"github.com/devon-caron/metrifuge/pipeline"
+)
+
+// PipelineResponse reports the throughput and latency of every pipeline stage
+type PipelineResponse struct {
+	Stages []pipeline.StageStats
+}
+
+func PipelineHandler(w http.ResponseWriter, r *http.Request) {
+	response := PipelineResponse{
+		Stages: pipeline.GetInstance().Stats(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		errhandler.InternalErrorHandler(w)
+		return
+	}
+}
@@ -1 +1,2 @@
+go run ./k8s/crdgen -check || exit 1
 docker build -t metrifuge:$(date +%Y%m%d-%H%M) -t metrifuge:latest .
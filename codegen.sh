@@ -0,0 +1,46 @@
+#!/bin/bash
+
+# Regenerates the deepcopy functions of the API types, and the typed clientset, listers and
+# informers of metrifuge.com/v1alpha1 and v1beta1 in k8s/client. Run from the root of the module,
+# with CODEGEN_BIN set to a directory holding the code-generator binaries to use them instead of
+# go run.
+set -e
+
+CODEGEN_VERSION=v0.33.3
+MODULE=github.com/devon-caron/metrifuge
+HEADER=$(mktemp)
+trap 'rm -f "$HEADER"' EXIT
+
+codegen() {
+  local tool=$1
+  shift
+  if [ -n "$CODEGEN_BIN" ]; then
+    "$CODEGEN_BIN/$tool" "$@"
+  else
+    go run "k8s.io/code-generator/cmd/$tool@$CODEGEN_VERSION" "$@"
+  fi
+}
+
+echo "==== Generating deepcopy..."
+codegen deepcopy-gen --go-header-file "$HEADER" --output-file zz_generated.deepcopy.go \
+  ./k8s/api ./k8s/api/ruleset ./k8s/api/log_source ./k8s/api/exporter ./k8s/api/metrifuge/v1alpha1 \
+  ./k8s/api/metrifuge/v1beta1
+
+rm -rf k8s/client
+
+echo "==== Generating clientset..."
+codegen client-gen --go-header-file "$HEADER" --clientset-name versioned \
+  --input-base "$MODULE/k8s/api" --input metrifuge/v1alpha1 --input metrifuge/v1beta1 \
+  --output-dir k8s/client/clientset --output-pkg "$MODULE/k8s/client/clientset"
+
+echo "==== Generating listers..."
+codegen lister-gen --go-header-file "$HEADER" \
+  --output-dir k8s/client/listers --output-pkg "$MODULE/k8s/client/listers" \
+  ./k8s/api/metrifuge/v1alpha1 ./k8s/api/metrifuge/v1beta1
+
+echo "==== Generating informers..."
+codegen informer-gen --go-header-file "$HEADER" \
+  --versioned-clientset-package "$MODULE/k8s/client/clientset/versioned" \
+  --listers-package "$MODULE/k8s/client/listers" \
+  --output-dir k8s/client/informers --output-pkg "$MODULE/k8s/client/informers" \
+  ./k8s/api/metrifuge/v1alpha1 ./k8s/api/metrifuge/v1beta1
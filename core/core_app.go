@@ -3,28 +3,40 @@ package core
 import (
 	"context"
 	"fmt"
+	"os"
+	"os/signal"
 	"strconv"
 	"sync"
+	"syscall"
 	"time"
 
 	exapi "github.com/devon-caron/metrifuge/api"
 	"github.com/devon-caron/metrifuge/exporter_manager"
+	"github.com/devon-caron/metrifuge/exporter_manager/retry_queue"
 	"github.com/devon-caron/metrifuge/k8s"
 	"github.com/devon-caron/metrifuge/k8s/api"
+	"github.com/devon-caron/metrifuge/k8s/client/clientset/versioned"
+	"github.com/devon-caron/metrifuge/pipeline"
 	"github.com/devon-caron/metrifuge/resources"
+	"github.com/devon-caron/metrifuge/webhook"
 
 	"github.com/devon-caron/metrifuge/global"
 	"github.com/devon-caron/metrifuge/log_handler"
+	"github.com/devon-caron/metrifuge/log_handler/log_processor"
 	"github.com/devon-caron/metrifuge/logger"
 	"github.com/sirupsen/logrus"
 )
 
 var (
-	log *logrus.Logger
-	wg  sync.WaitGroup
-	lh  *log_handler.LogHandler
-	em  *exporter_manager.ExporterManager
-	ctx context.Context
+	log     *logrus.Logger
+	wg      sync.WaitGroup
+	lh      *log_handler.LogHandler
+	em      *exporter_manager.ExporterManager
+	pl      *pipeline.Pipeline
+	ctx     context.Context
+	watcher *k8s.ResourceWatcher
+	stopCh  = make(chan struct{}) // Closed on shutdown to stop the background loops
+	loops   sync.WaitGroup        // Background loops that must finish before the pipeline is drained
 )
 
 func Run() {
@@ -32,6 +44,10 @@ func Run() {
 	global.InitConfig()
 	log = logger.Get()
 	ctx = context.Background()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
 	log.Info("starting api")
 	exapi.StartApi()
 
@@ -41,11 +57,10 @@ func Run() {
 	}
 
 	log.Info("k8s resource definitions validated")
+
 	log.Info("initializing log and inline sources...")
 
 	rsc := resources.GetInstance()
-	lh = &log_handler.LogHandler{}
-	lh.Initialize(rsc.GetLogSources(), rsc.GetRuleSets(), log, rsc.GetKubeConfig(), rsc.GetK8sClient())
 	refresh, err := strconv.Atoi(global.REFRESH_INTERVAL)
 	if err != nil {
 		log.Warnf("failed to parse environment variable MF_REFRESH_INTERVAL: %v", err)
@@ -53,58 +68,223 @@ func Run() {
 		refresh = 60
 	}
 
-	log.Info("log/inline sources intialized, starting log handler...")
-
-	go func() {
-		curRetries := 0
-		for {
-			log.Info("updating resources...")
-			if err := getResourceUpdates(); err != nil {
-				log.Errorf("retrying due to failure to update resources: %v", err)
-				time.Sleep(3 * time.Second)
-				curRetries++
-				if curRetries > 5 {
-					log.Fatalf("failed to update resources after 5 retries")
-				}
-				continue
-			}
-			curRetries = 0
-			time.Sleep(time.Duration(refresh) * time.Second)
-			lh.Update(rsc.GetLogSources(), rsc.GetK8sClient())
-		}
-	}()
-
 	log.Info("initializing exporter manager...")
 
 	// Then pass the combined slice
+	maxLateness, err := time.ParseDuration(global.METRIC_MAX_LATENESS)
+	if err != nil {
+		log.Fatalf("failed to parse environment variable MF_METRIC_MAX_LATENESS: %v", err)
+	}
 	em = &exporter_manager.ExporterManager{}
-	if err := em.Initialize(ctx, rsc.GetExporters(), log); err != nil {
+	if err := em.Initialize(ctx, rsc.GetExporters(), rsc.GetLogSources(), maxLateness, log); err != nil {
 		log.Fatalf("failed to initialize exporter manager: %v", err)
 	}
 
-	log.Info("allowing time for Log Handler warm up...")
+	log.Info("initializing retry queue...")
 
-	time.Sleep(5 * time.Second)
+	rq, err := newRetryQueue()
+	if err != nil {
+		log.Fatalf("failed to initialize retry queue: %v", err)
+	}
 
-	log.Info("warm up complete")
+	log.Info("initializing pipeline...")
 
-	go func() {
-		for {
-			items := lh.ReceiveBucketContents()
-			if len(items) > 0 {
-				if err := em.ProcessItems(ctx, items); err != nil {
-					log.Errorf("failed to process items: %v", err)
-				} else {
-					log.Infof("processed %d items and cleared bucket", len(items))
+	sourceParallelism, err := strconv.Atoi(global.SOURCE_PARALLELISM)
+	if err != nil {
+		log.Fatalf("failed to parse environment variable MF_SOURCE_PARALLELISM: %v", err)
+	}
+	lp := &log_processor.LogProcessor{}
+	lp.Initialize(rsc.GetLogSources(), rsc.GetRuleSets(), sourceParallelism, rsc.GetK8sClient(), log)
+	pl = pipeline.GetInstance()
+	config, err := newPipelineConfig()
+	if err != nil {
+		log.Fatalf("failed to configure pipeline: %v", err)
+	}
+	if err := pl.Initialize(config, lp, em, rq, log); err != nil {
+		log.Fatalf("failed to initialize pipeline: %v", err)
+	}
+	pl.Start(ctx)
+
+	log.Info("pipeline started, starting log handler...")
+
+	lh = &log_handler.LogHandler{}
+	lh.Initialize(rsc.GetLogSources(), rsc.GetRuleSets(), lp, pl.Input(), log, rsc.GetKubeConfig(), rsc.GetK8sClient())
+
+	isK8s, err := strconv.ParseBool(global.RUNNING_IN_K8S)
+	if err != nil {
+		log.Fatalf("failed to parse environment variable MF_RUNNING_IN_K8S: %v", err)
+	}
+
+	if isK8s {
+		// Resource changes arrive through the informers, so only statuses are written periodically
+		loops.Add(2)
+		go func() {
+			defer loops.Done()
+			watchResources()
+		}()
+		go func() {
+			defer loops.Done()
+			for sleep(time.Duration(refresh) * time.Second) {
+				reportStatuses()
+			}
+		}()
+	} else {
+		loops.Add(1)
+		go func() {
+			defer loops.Done()
+			curRetries := 0
+			for {
+				log.Info("updating resources...")
+				if err := getResourceUpdates(); err != nil {
+					log.Errorf("retrying due to failure to update resources: %v", err)
+					if !sleep(3 * time.Second) {
+						return
+					}
+					curRetries++
+					if curRetries > 5 {
+						log.Fatalf("failed to update resources after 5 retries")
+					}
+					continue
+				}
+				curRetries = 0
+				if !sleep(time.Duration(refresh) * time.Second) {
+					return
+				}
+				lh.Update(rsc.GetLogSources(), rsc.GetRuleSets(), rsc.GetK8sClient())
+				if err := em.UpdateExporters(ctx, rsc.GetExporters(), rsc.GetLogSources()); err != nil {
+					log.Errorf("failed to apply exporter changes: %v", err)
+				}
+				if err := em.UpdateLogSources(ctx, rsc.GetLogSources()); err != nil {
+					log.Errorf("failed to update exporter bindings: %v", err)
 				}
-			} else {
-				log.Debug("no items to process, bucket empty")
 			}
-			time.Sleep(time.Duration(refresh) * time.Second)
+		}()
+	}
+
+	// Items that failed to export wait in the retry queue until their backoff expires
+	loops.Add(1)
+	go func() {
+		defer loops.Done()
+		for sleep(rq.PollInterval()) {
+			retryFailedItems(ctx, rq)
 		}
 	}()
 
-	time.Sleep(30 * time.Minute)
+	sig := <-signals
+	log.Infof("received %v, shutting down...", sig)
+	shutdown(rq)
+	log.Info("shutdown complete")
+}
+
+// retryFailedItems exports every batch in the retry queue that is due.
+func retryFailedItems(ctx context.Context, rq *retry_queue.RetryQueue) {
+	exported, err := rq.Process(ctx, em.RetryItems)
+	if err != nil {
+		log.Errorf("failed to retry items: %v", err)
+	}
+	if exported > 0 {
+		log.Infof("retried %d items, %d batches pending retry", exported, rq.Len())
+	}
+}
+
+// shutdown stops the background loops and the log sources, exports what is left in the pipeline
+// and flushes the exporters, all within MF_SHUTDOWN_TIMEOUT. Items that still fail to export
+// stay in the retry queue on disk.
+func shutdown(rq *retry_queue.RetryQueue) {
+	timeout, err := time.ParseDuration(global.SHUTDOWN_TIMEOUT)
+	if err != nil {
+		log.Warnf("failed to parse environment variable MF_SHUTDOWN_TIMEOUT: %v", err)
+		log.Warnf("using default value of %s", global.DEFAULT_SHUTDOWN_TIMEOUT)
+		timeout, _ = time.ParseDuration(global.DEFAULT_SHUTDOWN_TIMEOUT)
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	log.Info("stopping background loops...")
+	close(stopCh)
+	loops.Wait()
+
+	log.Info("stopping log sources...")
+	lh.ShutDown()
+
+	log.Info("draining pipeline...")
+	if err := pl.Stop(shutdownCtx); err != nil {
+		log.Errorf("failed to drain pipeline: %v", err)
+	}
+	retryFailedItems(shutdownCtx, rq)
+
+	log.Info("flushing and shutting down exporters...")
+	if err := em.Shutdown(shutdownCtx); err != nil {
+		log.Errorf("failed to shut down exporters: %v", err)
+	}
+
+	if err := exapi.StopApi(shutdownCtx); err != nil {
+		log.Errorf("failed to stop api: %v", err)
+	}
+	if err := webhook.StopWebhook(shutdownCtx); err != nil {
+		log.Errorf("failed to stop webhooks: %v", err)
+	}
+}
+
+// sleep waits for d, and reports false instead if shutdown started in the meantime.
+func sleep(d time.Duration) bool {
+	select {
+	case <-stopCh:
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+func newRetryQueue() (*retry_queue.RetryQueue, error) {
+	capacity, err := strconv.Atoi(global.RETRY_QUEUE_CAPACITY)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse environment variable MF_RETRY_QUEUE_CAPACITY: %v", err)
+	}
+	maxAttempts, err := strconv.Atoi(global.RETRY_MAX_ATTEMPTS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse environment variable MF_RETRY_MAX_ATTEMPTS: %v", err)
+	}
+	backoffBase, err := time.ParseDuration(global.RETRY_BACKOFF_BASE)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse environment variable MF_RETRY_BACKOFF_BASE: %v", err)
+	}
+	backoffMax, err := time.ParseDuration(global.RETRY_BACKOFF_MAX)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse environment variable MF_RETRY_BACKOFF_MAX: %v", err)
+	}
+
+	rq := &retry_queue.RetryQueue{}
+	if err := rq.Initialize(global.RETRY_QUEUE_DIR, capacity, maxAttempts, backoffBase, backoffMax, log); err != nil {
+		return nil, err
+	}
+	return rq, nil
+}
+
+// newPipelineConfig reads the sizes of the pipeline from the environment. MF_PIPELINE_EVAL_WORKERS
+// must not exceed MF_PIPELINE_PARSE_WORKERS, which the pipeline rejects when it is initialized.
+func newPipelineConfig() (pipeline.Config, error) {
+	var config pipeline.Config
+	var err error
+	if config.BufferSize, err = strconv.Atoi(global.PIPELINE_BUFFER_SIZE); err != nil {
+		return config, fmt.Errorf("failed to parse environment variable MF_PIPELINE_BUFFER_SIZE: %v", err)
+	}
+	if config.ParseWorkers, err = strconv.Atoi(global.PIPELINE_PARSE_WORKERS); err != nil {
+		return config, fmt.Errorf("failed to parse environment variable MF_PIPELINE_PARSE_WORKERS: %v", err)
+	}
+	if config.EvalWorkers, err = strconv.Atoi(global.PIPELINE_EVAL_WORKERS); err != nil {
+		return config, fmt.Errorf("failed to parse environment variable MF_PIPELINE_EVAL_WORKERS: %v", err)
+	}
+	if config.ExportWorkers, err = strconv.Atoi(global.PIPELINE_EXPORT_WORKERS); err != nil {
+		return config, fmt.Errorf("failed to parse environment variable MF_PIPELINE_EXPORT_WORKERS: %v", err)
+	}
+	if config.BatchSize, err = strconv.Atoi(global.PIPELINE_BATCH_SIZE); err != nil {
+		return config, fmt.Errorf("failed to parse environment variable MF_PIPELINE_BATCH_SIZE: %v", err)
+	}
+	if config.FlushInterval, err = time.ParseDuration(global.PIPELINE_FLUSH_INTERVAL); err != nil {
+		return config, fmt.Errorf("failed to parse environment variable MF_PIPELINE_FLUSH_INTERVAL: %v", err)
+	}
+	return config, nil
 }
 
 func validateK8sResources() error {
@@ -116,6 +296,11 @@ func validateK8sResources() error {
 
 	log.Infof("It is %v that the application is running in k8s", isK8s)
 
+	webhookEnabled, err := strconv.ParseBool(global.WEBHOOK_ENABLED)
+	if err != nil {
+		return fmt.Errorf("failed to parse environment variable MF_WEBHOOK_ENABLED: %v", err)
+	}
+
 	rsc := resources.GetInstance()
 
 	if isK8s {
@@ -131,11 +316,46 @@ func validateK8sResources() error {
 		}
 		rsc.SetK8sClient(k8sClient)
 
-		if err = k8s.ValidateResources(kubeConfig); err != nil {
+		metrifugeClient, err := versioned.NewForConfig(kubeConfig)
+		if err != nil {
+			return fmt.Errorf("failed to create metrifuge client: %v", err)
+		}
+		rsc.SetMetrifugeClient(metrifugeClient)
+
+		if err = k8s.ValidateResources(kubeConfig, webhookEnabled); err != nil {
 			return fmt.Errorf("failed to validate kubernetes resources: %v", err)
 		}
 	}
 
+	// Started once the kubernetes client is set, which validating RuleSets may need for their
+	// pattern ConfigMaps, and before the watcher, whose RuleSets go through the conversion webhook
+	if err := startWebhook(webhookEnabled); err != nil {
+		return err
+	}
+
+	if isK8s {
+		watcher = &k8s.ResourceWatcher{}
+		if err := watcher.Initialize(rsc.GetK8sClient(), global.API_VERSION, 0); err != nil {
+			return fmt.Errorf("failed to initialize resource watcher: %v", err)
+		}
+		log.Info("waiting for resource informers to sync...")
+		if err := watcher.Start(stopCh); err != nil {
+			return fmt.Errorf("failed to start resource watcher: %v", err)
+		}
+
+		migrateStorageVersion, err := strconv.ParseBool(global.MIGRATE_STORAGE_VERSION)
+		if err != nil {
+			return fmt.Errorf("failed to parse environment variable MF_MIGRATE_STORAGE_VERSION: %v", err)
+		}
+		if migrateStorageVersion {
+			go func() {
+				if err := k8s.MigrateStorageVersion(rsc.GetKubeConfig()); err != nil {
+					log.Errorf("failed to migrate resources to the storage version: %v", err)
+				}
+			}()
+		}
+	}
+
 	if err := getResourceUpdates(); err != nil {
 		return fmt.Errorf("error updating resources after initialization: %v", err)
 	}
@@ -143,6 +363,18 @@ func validateK8sResources() error {
 	return nil
 }
 
+// startWebhook starts the admission and conversion webhooks if MF_WEBHOOK_ENABLED is set.
+func startWebhook(webhookEnabled bool) error {
+	if !webhookEnabled {
+		return nil
+	}
+	log.Info("starting webhooks")
+	if err := webhook.StartWebhook(); err != nil {
+		return fmt.Errorf("failed to start webhooks: %v", err)
+	}
+	return nil
+}
+
 func getResourceUpdates() error {
 
 	log.Info("retrieving resources from cluster...")
@@ -160,7 +392,7 @@ func getResourceUpdates() error {
 	go func() {
 		log.Info("retrieving rulesets from cluster...")
 		defer wg.Done()
-		if ruleSets, myErr := getRuleSetUpdates(isK8s, rsc.GetK8sClient()); myErr != nil {
+		if ruleSets, myErr := getRuleSetUpdates(isK8s, watcher); myErr != nil {
 			err = fmt.Errorf("%v{error updating rulesets : %v}\n", err, myErr)
 		} else {
 			rsc.SetRuleSets(ruleSets)
@@ -171,7 +403,7 @@ func getResourceUpdates() error {
 	go func() {
 		log.Info("retrieving log sources from cluster...")
 		defer wg.Done()
-		if logSources, myErr := getLogSourceUpdates(isK8s, rsc.GetK8sClient()); myErr != nil {
+		if logSources, myErr := getLogSourceUpdates(isK8s, watcher); myErr != nil {
 			err = fmt.Errorf("%v{error updating log sources : %v}\n", err, myErr)
 		} else {
 			rsc.SetLogSources(logSources)
@@ -182,7 +414,7 @@ func getResourceUpdates() error {
 	go func() {
 		log.Info("retrieving exporters from cluster...")
 		defer wg.Done()
-		if exporters, myErr := getExporterUpdates(isK8s, rsc.GetK8sClient()); myErr != nil {
+		if exporters, myErr := getExporterUpdates(isK8s, watcher); myErr != nil {
 			err = fmt.Errorf("%v{error updating exporters : %v}\n", err, myErr)
 		} else {
 			rsc.SetExporters(exporters)
@@ -13,7 +13,7 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-func getRuleSetUpdates(isK8s bool, k8sClient *api.K8sClientWrapper) ([]rs.RuleSet, error) {
+func getRuleSetUpdates(isK8s bool, watcher *k8s.ResourceWatcher) ([]rs.RuleSet, error) {
 	if !isK8s {
 		ruleFilePath := os.Getenv("MF_RULES_FILEPATH")
 		data, err := os.ReadFile(ruleFilePath)
@@ -29,7 +29,7 @@ func getRuleSetUpdates(isK8s bool, k8sClient *api.K8sClientWrapper) ([]rs.RuleSe
 		return myRuleSets, nil
 	}
 
-	myResources, err := k8s.GetK8sResources(k8sClient, global.RULESET_CRD_NAME, "v1alpha1", "rulesets")
+	myResources, err := watcher.List(global.RULESET_CRD_NAME)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get k8s resources: %v", err)
 	}
@@ -42,7 +42,7 @@ func getRuleSetUpdates(isK8s bool, k8sClient *api.K8sClientWrapper) ([]rs.RuleSe
 	return myRulesets, nil
 }
 
-func getLogSourceUpdates(isK8s bool, k8sClient *api.K8sClientWrapper) ([]ls.LogSource, error) {
+func getLogSourceUpdates(isK8s bool, watcher *k8s.ResourceWatcher) ([]ls.LogSource, error) {
 	if !isK8s {
 		logSourceFilePath := os.Getenv("MF_LOG_SOURCES_FILEPATH")
 		data, err := os.ReadFile(logSourceFilePath)
@@ -57,7 +57,7 @@ func getLogSourceUpdates(isK8s bool, k8sClient *api.K8sClientWrapper) ([]ls.LogS
 		return myLogSources, nil
 	}
 
-	myResources, err := k8s.GetK8sResources(k8sClient, global.LOGSOURCE_CRD_NAME, "v1alpha1", "logsources")
+	myResources, err := watcher.List(global.LOGSOURCE_CRD_NAME)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get k8s resources: %v", err)
 	}
@@ -70,7 +70,7 @@ func getLogSourceUpdates(isK8s bool, k8sClient *api.K8sClientWrapper) ([]ls.LogS
 	return myLogSources, nil
 }
 
-func getExporterUpdates(isK8s bool, k8sClient *api.K8sClientWrapper) ([]e.Exporter, error) {
+func getExporterUpdates(isK8s bool, watcher *k8s.ResourceWatcher) ([]e.Exporter, error) {
 	if !isK8s {
 		exporterFilePath := os.Getenv("MF_EXPORTERS_FILEPATH")
 		data, err := os.ReadFile(exporterFilePath)
@@ -85,7 +85,7 @@ func getExporterUpdates(isK8s bool, k8sClient *api.K8sClientWrapper) ([]e.Export
 		return myExporters, nil
 	}
 
-	myResources, err := k8s.GetK8sResources(k8sClient, global.EXPORTER_CRD_NAME, "v1alpha1", "exporters")
+	myResources, err := watcher.List(global.EXPORTER_CRD_NAME)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get k8s resources: %v", err)
 	}
@@ -0,0 +1,179 @@
+package core
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/devon-caron/metrifuge/exporter_manager/circuit_breaker"
+	"github.com/devon-caron/metrifuge/global"
+	"github.com/devon-caron/metrifuge/k8s"
+	"github.com/devon-caron/metrifuge/resources"
+	"github.com/devon-caron/metrifuge/status_tracker"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	ConditionReady     = "Ready"
+	ConditionStreaming = "Streaming"
+	ConditionDegraded  = "Degraded"
+)
+
+// reportedStatus is what was last written to a resource's status subresource. Conditions are kept
+// so that their lastTransitionTime only moves when their status actually changes.
+type reportedStatus struct {
+	conditions []metav1.Condition
+	errors     int64
+	encoded    string
+}
+
+// Last status written to each resource, keyed by kind and then by status_tracker key
+var lastStatuses = map[string]map[string]*reportedStatus{
+	"logsources": {},
+	"rulesets":   {},
+	"exporters":  {},
+}
+
+// reportStatuses writes what metrifuge has observed about every LogSource, RuleSet and Exporter
+// to their status subresources.
+func reportStatuses() {
+	isK8s, err := strconv.ParseBool(global.RUNNING_IN_K8S)
+	if err != nil || !isK8s {
+		return
+	}
+
+	rsc := resources.GetInstance()
+	tracker := status_tracker.GetInstance()
+
+	for _, logSource := range rsc.GetLogSources() {
+		key := status_tracker.Key(logSource.Metadata.Namespace, logSource.Metadata.Name)
+		stats := tracker.GetLogSourceStats(key)
+		last := lastStatus("logsources", key)
+
+		ready := metav1.Condition{Type: ConditionReady, Status: metav1.ConditionTrue, Reason: "SourceConfigured"}
+		if !stats.Ready {
+			ready.Status = metav1.ConditionFalse
+			ready.Reason = "Pending"
+			if stats.ReadyReason != "" {
+				ready.Reason = stats.ReadyReason
+			}
+		}
+		streaming := metav1.Condition{Type: ConditionStreaming, Status: metav1.ConditionTrue, Reason: "StreamRunning"}
+		degraded := metav1.Condition{Type: ConditionDegraded, Status: metav1.ConditionFalse, Reason: "AsExpected"}
+		if !stats.Streaming {
+			streaming.Status = metav1.ConditionFalse
+			streaming.Reason = "StreamStopped"
+			if stats.LastError != "" {
+				streaming.Message = stats.LastError
+				degraded = metav1.Condition{Type: ConditionDegraded, Status: metav1.ConditionTrue, Reason: "StreamFailed", Message: stats.LastError}
+			}
+		}
+
+		last.report(rsc, "logsources", logSource.Metadata.Namespace, logSource.Metadata.Name, map[string]any{
+			"linesRead":  stats.LinesRead,
+			"lateEvents": stats.LateEvents,
+			"lastError":  stats.LastError,
+		}, ready, streaming, degraded)
+	}
+
+	for _, ruleSet := range rsc.GetRuleSets() {
+		key := status_tracker.Key(ruleSet.Metadata.Namespace, ruleSet.Metadata.Name)
+		stats := tracker.GetRuleSetStats(key)
+		last := lastStatus("rulesets", key)
+
+		ready := metav1.Condition{Type: ConditionReady, Status: metav1.ConditionTrue, Reason: "RulesLoaded"}
+		if stats.LoadError != "" {
+			ready = metav1.Condition{Type: ConditionReady, Status: metav1.ConditionFalse, Reason: "InvalidRules", Message: stats.LoadError}
+		}
+		degraded := metav1.Condition{Type: ConditionDegraded, Status: metav1.ConditionFalse, Reason: "AsExpected"}
+		// Only errors since the last report degrade the RuleSet, so it recovers once they stop
+		if stats.Errors > last.errors {
+			degraded = metav1.Condition{Type: ConditionDegraded, Status: metav1.ConditionTrue, Reason: "RuleErrors", Message: stats.LastError}
+		}
+		last.errors = stats.Errors
+
+		samples := make([]map[string]any, 0, len(stats.ErrorSamples))
+		for _, sample := range stats.ErrorSamples {
+			samples = append(samples, map[string]any{
+				"rule":  sample.Rule,
+				"stage": sample.Stage,
+				"line":  sample.Line,
+				"error": sample.Error,
+				"time":  sample.Time.UTC().Format(time.RFC3339),
+			})
+		}
+
+		last.report(rsc, "rulesets", ruleSet.Metadata.Namespace, ruleSet.Metadata.Name, map[string]any{
+			"rulesMatched": stats.RulesMatched,
+			"lastError":    stats.LastError,
+			"ruleErrors":   stats.RuleErrors,
+			"errorSamples": samples,
+		}, ready, degraded)
+	}
+
+	registry := circuit_breaker.GetRegistry()
+	for _, exporter := range rsc.GetExporters() {
+		name := exporter.GetMetadata().Name
+		key := status_tracker.Key(exporter.GetMetadata().Namespace, name)
+		stats := tracker.GetExporterStats(key)
+		last := lastStatus("exporters", key)
+
+		status := map[string]any{
+			"itemsExported": stats.ItemsExported,
+			"lastError":     stats.LastError,
+		}
+		ready := metav1.Condition{Type: ConditionReady, Status: metav1.ConditionFalse, Reason: "NotBound"}
+		degraded := metav1.Condition{Type: ConditionDegraded, Status: metav1.ConditionFalse, Reason: "AsExpected"}
+		if stats.Errors > last.errors {
+			degraded = metav1.Condition{Type: ConditionDegraded, Status: metav1.ConditionTrue, Reason: "ExportErrors", Message: stats.LastError}
+		}
+		last.errors = stats.Errors
+
+		if breaker, ok := registry.Status(name); ok {
+			status["circuitBreaker"] = breaker
+			ready = metav1.Condition{Type: ConditionReady, Status: metav1.ConditionTrue, Reason: "Bound"}
+			if breaker.State != circuit_breaker.Closed {
+				degraded = metav1.Condition{Type: ConditionDegraded, Status: metav1.ConditionTrue,
+					Reason: "CircuitBreaker" + string(breaker.State), Message: breaker.LastError}
+			}
+		}
+
+		last.report(rsc, "exporters", exporter.GetMetadata().Namespace, name, status, ready, degraded)
+	}
+}
+
+func lastStatus(kindPlural, key string) *reportedStatus {
+	last, ok := lastStatuses[kindPlural][key]
+	if !ok {
+		last = &reportedStatus{}
+		lastStatuses[kindPlural][key] = last
+	}
+	return last
+}
+
+// report merges the conditions into the previously reported ones and patches the status
+// subresource, unless nothing changed since the last report.
+func (rs *reportedStatus) report(rsc *resources.Resources, kindPlural, namespace, name string, status map[string]any, conditions ...metav1.Condition) {
+	merged := append([]metav1.Condition(nil), rs.conditions...)
+	for _, condition := range conditions {
+		meta.SetStatusCondition(&merged, condition)
+	}
+	status["conditions"] = merged
+
+	encoded, err := json.Marshal(status)
+	if err != nil {
+		log.Warnf("failed to encode status of %s %s/%s: %v", kindPlural, namespace, name, err)
+		return
+	}
+	if string(encoded) == rs.encoded {
+		return
+	}
+
+	if err := k8s.PatchResourceStatus(rsc.GetMetrifugeClient(), kindPlural, namespace, name, status); err != nil {
+		log.Warnf("failed to report status of %s %s/%s: %v", kindPlural, namespace, name, err)
+		return
+	}
+	rs.conditions = merged
+	rs.encoded = string(encoded)
+}
@@ -0,0 +1,107 @@
+package core
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/devon-caron/metrifuge/global"
+	"github.com/devon-caron/metrifuge/k8s"
+	"github.com/devon-caron/metrifuge/k8s/api"
+	"github.com/devon-caron/metrifuge/resources"
+)
+
+// watchResources applies resource changes from the informers as they arrive, until stopCh is closed.
+func watchResources() {
+	for {
+		select {
+		case <-stopCh:
+			return
+		case event := <-watcher.Events():
+			handleResourceEvent(event)
+		}
+	}
+}
+
+func handleResourceEvent(event k8s.ResourceEvent) {
+	rsc := resources.GetInstance()
+
+	switch event.Kind {
+	case global.LOGSOURCE_CRD_NAME:
+		logSources, changed := applyEvent(rsc.GetLogSources(), event)
+		if !changed {
+			return
+		}
+		log.Infof("log source %s/%s %s", event.Namespace, event.Name, event.Type)
+		rsc.SetLogSources(logSources)
+
+		if event.Type == k8s.ResourceUpdated {
+			// Restart the source so that its new spec takes effect
+			lh.StopSource(event.Namespace, event.Name)
+		}
+		if err := lh.Update(logSources, rsc.GetRuleSets(), rsc.GetK8sClient()); err != nil {
+			log.Errorf("failed to update log handler: %v", err)
+		}
+		if err := em.UpdateLogSources(ctx, logSources); err != nil {
+			log.Errorf("failed to update exporter bindings: %v", err)
+		}
+	case global.RULESET_CRD_NAME:
+		ruleSets, changed := applyEvent(rsc.GetRuleSets(), event)
+		if !changed {
+			return
+		}
+		log.Infof("rule set %s/%s %s", event.Namespace, event.Name, event.Type)
+		rsc.SetRuleSets(ruleSets)
+
+		if err := lh.Update(rsc.GetLogSources(), ruleSets, rsc.GetK8sClient()); err != nil {
+			log.Errorf("failed to update log handler: %v", err)
+		}
+	case global.EXPORTER_CRD_NAME:
+		exporters, changed := applyEvent(rsc.GetExporters(), event)
+		if !changed {
+			return
+		}
+		log.Infof("exporter %s/%s %s", event.Namespace, event.Name, event.Type)
+		rsc.SetExporters(exporters)
+
+		if err := em.UpdateExporters(ctx, exporters, rsc.GetLogSources()); err != nil {
+			log.Errorf("failed to apply exporter changes: %v", err)
+		}
+	default:
+		log.Warnf("ignoring event for unknown kind %s", event.Kind)
+	}
+}
+
+// applyEvent returns a copy of resources with the event applied, and whether anything changed.
+// Added events for resources that are already known at the same generation, such as those
+// delivered for the informers' initial list, change nothing.
+func applyEvent[T api.MetrifugeK8sResource](resources []T, event k8s.ResourceEvent) ([]T, bool) {
+	index := slices.IndexFunc(resources, func(resource T) bool {
+		metadata := resource.GetMetadata()
+		return metadata.Namespace == event.Namespace && metadata.Name == event.Name
+	})
+
+	if event.Type == k8s.ResourceDeleted {
+		if index < 0 {
+			return resources, false
+		}
+		return slices.Delete(slices.Clone(resources), index, index+1), true
+	}
+
+	resource, ok := event.Resource.(T)
+	if !ok {
+		log.Warnf("ignoring %s event for %s %s/%s of type %T", event.Type, event.Kind, event.Namespace, event.Name, event.Resource)
+		return resources, false
+	}
+	if index < 0 {
+		return append(slices.Clone(resources), resource), true
+	}
+
+	current := resources[index].GetMetadata()
+	metadata := resource.GetMetadata()
+	if current.Generation == metadata.Generation && maps.Equal(current.Labels, metadata.Labels) {
+		return resources, false
+	}
+	updated := slices.Clone(resources)
+	updated[index] = resource
+	return updated, true
+}
@@ -0,0 +1,267 @@
+package circuit_breaker
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/devon-caron/metrifuge/k8s/api"
+	e "github.com/devon-caron/metrifuge/k8s/api/exporter"
+)
+
+type State string
+
+const (
+	Closed   State = "Closed"
+	Open     State = "Open"
+	HalfOpen State = "HalfOpen"
+)
+
+var (
+	DefaultFailureThreshold = 5
+	DefaultSuccessThreshold = 1
+	DefaultOpenDuration     = 30 * time.Second
+)
+
+// ErrOpen is returned instead of calling a destination whose breaker is open.
+var ErrOpen = errors.New("circuit breaker is open")
+
+// Config holds the thresholds of a circuit breaker.
+type Config struct {
+	FailureThreshold int           // consecutive failures that open a closed breaker
+	SuccessThreshold int           // consecutive half-open successes that close the breaker again
+	OpenDuration     time.Duration // how long the breaker stays open before allowing a probe
+}
+
+// Status is a snapshot of a circuit breaker, as exposed through the API and the Exporter status.
+type Status struct {
+	Name                string    `json:"name"`
+	State               State     `json:"state"`
+	ConsecutiveFailures int       `json:"consecutiveFailures"`
+	LastError           string    `json:"lastError,omitempty"`
+	LastTransitionTime  time.Time `json:"lastTransitionTime"`
+}
+
+// ConfigFromSpec builds a Config from an exporter's circuitBreaker block, using the defaults for
+// anything left unset.
+func ConfigFromSpec(spec *api.CircuitBreakerConfig) (Config, error) {
+	config := Config{
+		FailureThreshold: DefaultFailureThreshold,
+		SuccessThreshold: DefaultSuccessThreshold,
+		OpenDuration:     DefaultOpenDuration,
+	}
+	if spec == nil {
+		return config, nil
+	}
+	if spec.FailureThreshold < 0 || spec.SuccessThreshold < 0 {
+		return Config{}, fmt.Errorf("circuit breaker thresholds must not be negative: %+v", *spec)
+	}
+	if spec.FailureThreshold > 0 {
+		config.FailureThreshold = spec.FailureThreshold
+	}
+	if spec.SuccessThreshold > 0 {
+		config.SuccessThreshold = spec.SuccessThreshold
+	}
+	if spec.OpenDuration != "" {
+		openDuration, err := time.ParseDuration(spec.OpenDuration)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to parse circuit breaker openDuration: %w", err)
+		}
+		config.OpenDuration = openDuration
+	}
+	return config, nil
+}
+
+/**
+ * CircuitBreaker guards a single export destination. It opens after FailureThreshold consecutive
+ * failures, rejects calls for OpenDuration, then lets probes through in the half-open state until
+ * SuccessThreshold consecutive successes close it again (or a failure reopens it).
+ */
+type CircuitBreaker struct {
+	name               string
+	config             Config
+	state              State
+	failures           int
+	successes          int
+	probing            bool
+	openedAt           time.Time
+	lastError          string
+	lastTransitionTime time.Time
+	now                func() time.Time // clock of the breaker, replaced in tests
+	mu                 sync.Mutex
+}
+
+func newCircuitBreaker(name string, config Config) *CircuitBreaker {
+	return &CircuitBreaker{
+		name:               name,
+		config:             config,
+		state:              Closed,
+		lastTransitionTime: time.Now(),
+		now:                time.Now,
+	}
+}
+
+// Allow reports whether a call may go through, returning ErrOpen if it may not. Every allowed
+// call must be followed by a call to Record with its result.
+func (cb *CircuitBreaker) Allow() error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case Open:
+		if cb.now().Sub(cb.openedAt) < cb.config.OpenDuration {
+			return fmt.Errorf("%w for %s", ErrOpen, cb.name)
+		}
+		cb.transition(HalfOpen)
+		cb.probing = true
+		return nil
+	case HalfOpen:
+		// Only one probe at a time while half-open
+		if cb.probing {
+			return fmt.Errorf("%w for %s", ErrOpen, cb.name)
+		}
+		cb.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// Record updates the breaker with the result of a call that was allowed through.
+func (cb *CircuitBreaker) Record(err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.probing = false
+	if err != nil {
+		cb.lastError = err.Error()
+		cb.successes = 0
+		cb.failures++
+		if cb.state == HalfOpen || cb.failures >= cb.config.FailureThreshold {
+			cb.openedAt = cb.now()
+			cb.transition(Open)
+		}
+		return
+	}
+
+	cb.failures = 0
+	if cb.state == HalfOpen {
+		cb.successes++
+		if cb.successes >= cb.config.SuccessThreshold {
+			cb.successes = 0
+			cb.transition(Closed)
+		}
+	}
+}
+
+func (cb *CircuitBreaker) transition(state State) {
+	if cb.state == state {
+		return
+	}
+	cb.state = state
+	cb.lastTransitionTime = cb.now()
+}
+
+func (cb *CircuitBreaker) Status() Status {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	state := cb.state
+	// Report an expired open breaker as half-open even if no call has probed it yet
+	if state == Open && cb.now().Sub(cb.openedAt) >= cb.config.OpenDuration {
+		state = HalfOpen
+	}
+	return Status{
+		Name:                cb.name,
+		State:               state,
+		ConsecutiveFailures: cb.failures,
+		LastError:           cb.lastError,
+		LastTransitionTime:  cb.lastTransitionTime,
+	}
+}
+
+func (cb *CircuitBreaker) setConfig(config Config) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.config = config
+}
+
+var (
+	instance *Registry
+	once     sync.Once
+)
+
+// Registry holds one circuit breaker per exporter, shared by every log source bound to it.
+type Registry struct {
+	breakers map[string]*CircuitBreaker
+	mu       sync.RWMutex
+}
+
+// GetRegistry returns the singleton instance of Registry
+func GetRegistry() *Registry {
+	once.Do(func() {
+		instance = &Registry{
+			breakers: make(map[string]*CircuitBreaker),
+		}
+	})
+	return instance
+}
+
+// Get returns the circuit breaker for an exporter, creating it if needed. The breaker keeps its
+// state across calls, but always takes the latest config.
+func (r *Registry) Get(name string, config Config) *CircuitBreaker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if cb, ok := r.breakers[name]; ok {
+		cb.setConfig(config)
+		return cb
+	}
+	cb := newCircuitBreaker(name, config)
+	r.breakers[name] = cb
+	return cb
+}
+
+// ForExporter returns the circuit breaker guarding an exporter's destination.
+func (r *Registry) ForExporter(exporter e.Exporter) (*CircuitBreaker, error) {
+	config, err := ConfigFromSpec(exporter.Spec.CircuitBreaker)
+	if err != nil {
+		return nil, fmt.Errorf("invalid circuit breaker for exporter %s: %w", exporter.GetMetadata().Name, err)
+	}
+	return r.Get(exporter.GetMetadata().Name, config), nil
+}
+
+func (r *Registry) Remove(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.breakers, name)
+}
+
+// Statuses returns the status of every circuit breaker, ordered by name.
+func (r *Registry) Statuses() []Status {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	statuses := make([]Status, 0, len(r.breakers))
+	for _, cb := range r.breakers {
+		statuses = append(statuses, cb.Status())
+	}
+	slices.SortFunc(statuses, func(a, b Status) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return statuses
+}
+
+func (r *Registry) Status(name string) (Status, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cb, ok := r.breakers[name]
+	if !ok {
+		return Status{}, false
+	}
+	return cb.Status(), true
+}
@@ -0,0 +1,93 @@
+package circuit_breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// step is a call through a breaker, or a wait if it makes no call.
+type step struct {
+	wait    time.Duration
+	call    bool
+	allowed bool  // whether the call is expected to go through
+	err     error // result recorded for an allowed call
+	hold    bool  // whether the allowed call is left without a result, as a slow probe is
+	want    State // state reported after the step
+}
+
+func ok(want State) step { return step{call: true, allowed: true, want: want} }
+func fail(want State) step {
+	return step{call: true, allowed: true, err: errors.New("unavailable"), want: want}
+}
+func rejected(want State) step              { return step{call: true, want: want} }
+func wait(d time.Duration, want State) step { return step{wait: d, want: want} }
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	config := Config{FailureThreshold: 2, SuccessThreshold: 2, OpenDuration: 10 * time.Second}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"closed below the failure threshold", []step{fail(Closed), ok(Closed), fail(Closed)}},
+		{"opens at the failure threshold", []step{fail(Closed), fail(Open), rejected(Open)}},
+		{"rejects until the cooldown is over", []step{
+			fail(Closed), fail(Open), wait(9*time.Second, Open), rejected(Open),
+			wait(time.Second, HalfOpen), ok(HalfOpen),
+		}},
+		{"closes after enough successful probes", []step{
+			fail(Closed), fail(Open), wait(10*time.Second, HalfOpen), ok(HalfOpen), ok(Closed), fail(Closed),
+		}},
+		{"reopens on a failed probe", []step{
+			fail(Closed), fail(Open), wait(10*time.Second, HalfOpen), ok(HalfOpen), fail(Open),
+			wait(9*time.Second, Open), rejected(Open), wait(time.Second, HalfOpen),
+		}},
+		{"one probe at a time", []step{
+			fail(Closed), fail(Open), wait(10*time.Second, HalfOpen),
+			{call: true, allowed: true, hold: true, want: HalfOpen}, rejected(HalfOpen),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Unix(0, 0)
+			cb := newCircuitBreaker("test", config)
+			cb.now = func() time.Time { return now }
+
+			for i, s := range tt.steps {
+				now = now.Add(s.wait)
+				if s.call {
+					err := cb.Allow()
+					if allowed := err == nil; allowed != s.allowed {
+						t.Fatalf("step %d: Allow() = %v, want allowed %v", i, err, s.allowed)
+					}
+					if err != nil && !errors.Is(err, ErrOpen) {
+						t.Fatalf("step %d: Allow() = %v, want ErrOpen", i, err)
+					}
+					if err == nil && !s.hold {
+						cb.Record(s.err)
+					}
+				}
+				if got := cb.Status().State; got != s.want {
+					t.Fatalf("step %d: state = %s, want %s", i, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerStatus(t *testing.T) {
+	now := time.Unix(0, 0)
+	cb := newCircuitBreaker("otlp", Config{FailureThreshold: 1, SuccessThreshold: 1, OpenDuration: time.Minute})
+	cb.now = func() time.Time { return now }
+
+	now = now.Add(time.Second)
+	if err := cb.Allow(); err != nil {
+		t.Fatal(err)
+	}
+	cb.Record(errors.New("connection refused"))
+	status := cb.Status()
+	if status.Name != "otlp" || status.State != Open || status.ConsecutiveFailures != 1 ||
+		status.LastError != "connection refused" || !status.LastTransitionTime.Equal(now) {
+		t.Errorf("Status() = %+v, want an open breaker since %v", status, now)
+	}
+}
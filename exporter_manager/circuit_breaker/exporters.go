@@ -0,0 +1,56 @@
+package circuit_breaker
+
+import (
+	"context"
+
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// metricExporter skips exports to its destination while the breaker is open. It always asks for
+// cumulative metrics, so that the first export after the breaker closes sends what the skipped
+// ones would have.
+type metricExporter struct {
+	sdkmetric.Exporter
+	cb *CircuitBreaker
+}
+
+func WrapMetricExporter(exporter sdkmetric.Exporter, cb *CircuitBreaker) sdkmetric.Exporter {
+	return &metricExporter{Exporter: exporter, cb: cb}
+}
+
+// Temporality overrides the temporality of the wrapped exporter, which can be set to delta through
+// OTEL_EXPORTER_OTLP_METRICS_TEMPORALITY_PREFERENCE.
+func (me *metricExporter) Temporality(sdkmetric.InstrumentKind) metricdata.Temporality {
+	return metricdata.CumulativeTemporality
+}
+
+func (me *metricExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	if err := me.cb.Allow(); err != nil {
+		return err
+	}
+	err := me.Exporter.Export(ctx, rm)
+	me.cb.Record(err)
+	return err
+}
+
+// logExporter skips exports to its destination while the breaker is open. The logs it skips are
+// returned as failed to the log exporter client, which hands them to the retry queue.
+type logExporter struct {
+	sdklog.Exporter
+	cb *CircuitBreaker
+}
+
+func WrapLogExporter(exporter sdklog.Exporter, cb *CircuitBreaker) sdklog.Exporter {
+	return &logExporter{Exporter: exporter, cb: cb}
+}
+
+func (le *logExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	if err := le.cb.Allow(); err != nil {
+		return err
+	}
+	err := le.Exporter.Export(ctx, records)
+	le.cb.Record(err)
+	return err
+}
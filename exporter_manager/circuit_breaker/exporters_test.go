@@ -0,0 +1,60 @@
+package circuit_breaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// failingLogExporter fails every export, counting them.
+type failingLogExporter struct {
+	exports int
+}
+
+func (fe *failingLogExporter) Export(context.Context, []sdklog.Record) error {
+	fe.exports++
+	return errors.New("unavailable")
+}
+
+func (fe *failingLogExporter) Shutdown(context.Context) error   { return nil }
+func (fe *failingLogExporter) ForceFlush(context.Context) error { return nil }
+
+func TestLogExporterRejectsWhileOpen(t *testing.T) {
+	cb := newCircuitBreaker("test", Config{FailureThreshold: 2, SuccessThreshold: 1, OpenDuration: time.Hour})
+	inner := &failingLogExporter{}
+	exporter := WrapLogExporter(inner, cb)
+
+	for range 2 {
+		if err := exporter.Export(context.Background(), nil); err == nil || errors.Is(err, ErrOpen) {
+			t.Fatalf("Export() = %v, want the destination's error", err)
+		}
+	}
+	if err := exporter.Export(context.Background(), nil); !errors.Is(err, ErrOpen) {
+		t.Fatalf("Export() = %v, want ErrOpen", err)
+	}
+	if inner.exports != 2 {
+		t.Errorf("destination called %d times, want 2", inner.exports)
+	}
+}
+
+func TestMetricExporterIsCumulative(t *testing.T) {
+	exporter := WrapMetricExporter(deltaMetricExporter{}, newCircuitBreaker("test", Config{}))
+	if got := exporter.Temporality(sdkmetric.InstrumentKindCounter); got != metricdata.CumulativeTemporality {
+		t.Errorf("Temporality() = %v, want cumulative", got)
+	}
+}
+
+// deltaMetricExporter prefers delta temporality, as OTLP exporters do when configured so through
+// the environment.
+type deltaMetricExporter struct {
+	sdkmetric.Exporter
+}
+
+func (deltaMetricExporter) Temporality(sdkmetric.InstrumentKind) metricdata.Temporality {
+	return metricdata.DeltaTemporality
+}
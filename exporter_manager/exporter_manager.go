@@ -2,48 +2,230 @@ package exporter_manager
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"math/rand"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/devon-caron/metrifuge/exporter_manager/circuit_breaker"
 	"github.com/devon-caron/metrifuge/exporter_manager/log_exporter_client"
 	"github.com/devon-caron/metrifuge/exporter_manager/metric_exporter_client"
 	"github.com/devon-caron/metrifuge/global"
 	"github.com/devon-caron/metrifuge/k8s/api"
 	e "github.com/devon-caron/metrifuge/k8s/api/exporter"
+	ls "github.com/devon-caron/metrifuge/k8s/api/log_source"
+	"github.com/devon-caron/metrifuge/status_tracker"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/sdk/resource"
 )
 
 type ExporterManager struct {
-	exporters map[string]e.Exporter
-	log       *logrus.Logger
-	mc        *metric_exporter_client.MetricExporterClient
-	lc        *log_exporter_client.LogExporterClient
+	exporters   map[string]e.Exporter
+	bindings    map[api.LogSourceInfo][]string           // Names of the exporters currently bound to each log source
+	resources   map[api.LogSourceInfo]*resource.Resource // Resource of the logs forwarded from each bound log source
+	maxLateness time.Duration                            // Age past which metrics of timestamped events are dropped
+	log         *logrus.Logger
+	mc          *metric_exporter_client.MetricExporterClient
+	lc          *log_exporter_client.LogExporterClient
+	mu          sync.Mutex
 }
 
-func (em *ExporterManager) Initialize(ctx context.Context, exporters []e.Exporter, log *logrus.Logger) error {
+func (em *ExporterManager) Initialize(ctx context.Context, exporters []e.Exporter, logSources []ls.LogSource,
+	maxLateness time.Duration, log *logrus.Logger) error {
 	em.log = log
+	em.maxLateness = maxLateness
 	em.exporters = make(map[string]e.Exporter)
 	for _, exporter := range exporters {
 		em.exporters[exporter.GetMetadata().Name] = exporter
 	}
+	em.bindings = make(map[api.LogSourceInfo][]string)
+	em.resources = make(map[api.LogSourceInfo]*resource.Resource)
 
 	// Initialize the clients
 	em.mc = &metric_exporter_client.MetricExporterClient{}
-	if err := em.mc.Initialize(ctx, exporters); err != nil {
-		return fmt.Errorf("failed to initialize metric client: %w", err)
-	}
-
 	em.lc = &log_exporter_client.LogExporterClient{}
-	if err := em.lc.Initialize(ctx, exporters); err != nil {
-		return fmt.Errorf("failed to initialize log client: %w", err)
+
+	if err := em.UpdateLogSources(ctx, logSources); err != nil {
+		return fmt.Errorf("failed to bind exporters to log sources: %w", err)
 	}
 
 	return nil
 }
 
+// UpdateLogSources re-resolves which exporters are bound to which log sources, so that exporters
+// using a logSourceSelector pick up log sources as they appear and drop them as they disappear.
+func (em *ExporterManager) UpdateLogSources(ctx context.Context, logSources []ls.LogSource) error {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	return em.reconcile(ctx, logSources, nil)
+}
+
+// UpdateExporters applies added, removed and modified exporters. Every log source bound to an
+// exporter that changed gets new providers, and the old ones are drained and shut down.
+func (em *ExporterManager) UpdateExporters(ctx context.Context, exporters []e.Exporter, logSources []ls.LogSource) error {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	next := make(map[string]e.Exporter)
+	for _, exporter := range exporters {
+		next[exporter.GetMetadata().Name] = exporter
+	}
+
+	changed := make(map[string]bool)
+	for name, exporter := range next {
+		if current, ok := em.exporters[name]; !ok || !reflect.DeepEqual(current, exporter) {
+			changed[name] = true
+		}
+	}
+	var removed []string
+	for name := range em.exporters {
+		if _, ok := next[name]; !ok {
+			changed[name] = true
+			removed = append(removed, name)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	em.log.Infof("applying changes to exporters %v", slices.Sorted(maps.Keys(changed)))
+	em.exporters = next
+	err := em.reconcile(ctx, logSources, changed)
+
+	// Removed exporters are unbound by now, so their breakers are no longer in use
+	for _, name := range removed {
+		circuit_breaker.GetRegistry().Remove(name)
+	}
+	return err
+}
+
+// reconcile binds every log source to the exporters that select it. Log sources are rebound when
+// their set of exporters changed, or when one of their exporters is in changed. Their logs are also
+// rebound when the resource describing them changed, such as when their labels were edited.
+func (em *ExporterManager) reconcile(ctx context.Context, logSources []ls.LogSource, changed map[string]bool) error {
+	desired := em.resolveBindings(logSources)
+	listed := make(map[api.LogSourceInfo]*ls.LogSource)
+	for i, logSource := range logSources {
+		listed[api.LogSourceInfo{Name: logSource.Metadata.Name, Namespace: logSource.Metadata.Namespace}] = &logSources[i]
+	}
+
+	var errs []error
+	for src, exporters := range desired {
+		names := make([]string, 0, len(exporters))
+		for _, exporter := range exporters {
+			names = append(names, exporter.GetMetadata().Name)
+		}
+		res, err := logSourceResource(src, listed[src])
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		sameExporters := slices.Equal(em.bindings[src], names) && !slices.ContainsFunc(names, func(name string) bool {
+			return changed[name]
+		})
+		if sameExporters && res.Equal(em.resources[src]) {
+			continue
+		}
+
+		em.log.Infof("binding exporters %v to log source %s/%s", names, src.Namespace, src.Name)
+		if !sameExporters {
+			if err := em.mc.BindLogSource(ctx, src, exporters); err != nil {
+				errs = append(errs, fmt.Errorf("failed to bind metric exporters to log source %s/%s: %w", src.Namespace, src.Name, err))
+				continue
+			}
+		}
+		if err := em.lc.BindLogSource(ctx, src, res, exporters); err != nil {
+			errs = append(errs, fmt.Errorf("failed to bind log exporters to log source %s/%s: %w", src.Namespace, src.Name, err))
+			continue
+		}
+		em.bindings[src] = names
+		em.resources[src] = res
+	}
+
+	for src := range em.bindings {
+		if _, ok := desired[src]; ok {
+			continue
+		}
+
+		em.log.Infof("unbinding exporters from log source %s/%s", src.Namespace, src.Name)
+		if err := em.mc.UnbindLogSource(ctx, src); err != nil {
+			errs = append(errs, fmt.Errorf("failed to unbind metric exporters from log source %s/%s: %w", src.Namespace, src.Name, err))
+		}
+		if err := em.lc.UnbindLogSource(ctx, src); err != nil {
+			errs = append(errs, fmt.Errorf("failed to unbind log exporters from log source %s/%s: %w", src.Namespace, src.Name, err))
+		}
+		delete(em.bindings, src)
+		delete(em.resources, src)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Shutdown flushes every exporter and shuts down their providers. Nothing can be exported afterwards.
+func (em *ExporterManager) Shutdown(ctx context.Context) error {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	em.bindings = make(map[api.LogSourceInfo][]string)
+	em.resources = make(map[api.LogSourceInfo]*resource.Resource)
+	return errors.Join(em.mc.Shutdown(ctx), em.lc.Shutdown(ctx))
+}
+
+// resolveBindings maps each log source to the exporters that select it, ordered by exporter name.
+// Exporters that reference a log source by name stay bound to it even before it has been listed.
+func (em *ExporterManager) resolveBindings(logSources []ls.LogSource) map[api.LogSourceInfo][]e.Exporter {
+	names := slices.Sorted(maps.Keys(em.exporters))
+
+	bindings := make(map[api.LogSourceInfo][]e.Exporter)
+	for _, name := range names {
+		exporter := em.exporters[name]
+		if exporter.Spec.LogSource.Name != "" {
+			src := exporter.GetLogSourceInfo()
+			bindings[src] = append(bindings[src], exporter)
+		}
+		for _, logSource := range logSources {
+			src := api.LogSourceInfo{
+				Name:      logSource.Metadata.Name,
+				Namespace: logSource.Metadata.Namespace,
+			}
+			if src == exporter.GetLogSourceInfo() || !exporter.SelectsLogSource(logSource.Metadata) {
+				continue
+			}
+			bindings[src] = append(bindings[src], exporter)
+		}
+	}
+	return bindings
+}
+
 // TODO 11/28: when ProcessItems is called, add name and namespace of the logsource to each exported metric via context.
-func (em *ExporterManager) ProcessItems(ctx context.Context, items []api.ProcessedDataItem) error {
-	for _, item := range items {
+// ProcessItems exports every item it is given and returns the items that failed. A failed item only keeps
+// the parts (metric and/or log) that could not be exported, and its log only goes to the exporters that
+// failed to export it, so that retrying it does not duplicate the rest. Logs are exported before
+// ProcessItems returns, so that the logs a destination rejects are returned rather than dropped.
+// Metrics are only recorded: their readers export them periodically, and since they export
+// cumulative values, what a failed export would have sent is sent by the next one.
+func (em *ExporterManager) ProcessItems(ctx context.Context, items []api.ProcessedDataItem) ([]api.ProcessedDataItem, error) {
+	return em.processItems(ctx, items, false)
+}
+
+// RetryItems exports items from the retry queue like ProcessItems, except that their metrics are
+// recorded however late their events are, since they were only held back by failed exports.
+func (em *ExporterManager) RetryItems(ctx context.Context, items []api.ProcessedDataItem) ([]api.ProcessedDataItem, error) {
+	return em.processItems(ctx, items, true)
+}
+
+func (em *ExporterManager) processItems(ctx context.Context, items []api.ProcessedDataItem, retry bool) ([]api.ProcessedDataItem, error) {
+	failedItems := make([]api.ProcessedDataItem, len(items))
+	itemErrs := make([]error, len(items))
+	var logItems []api.ProcessedDataItem
+	var logIndexes []int
+	for i, item := range items {
 		// Send metric if present
 		myCtx := ctx
 		if item.LogSourceInfo.Name != "" {
@@ -52,9 +234,23 @@ func (em *ExporterManager) ProcessItems(ctx context.Context, items []api.Process
 		if item.LogSourceInfo.Namespace != "" {
 			myCtx = context.WithValue(myCtx, global.SOURCE_NAMESPACE_KEY, item.LogSourceInfo.Namespace)
 		}
-		if item.Metric != nil {
+		failedItems[i] = api.ProcessedDataItem{
+			LogSourceInfo:     item.LogSourceInfo,
+			Timestamp:         item.Timestamp,
+			ObservedTimestamp: item.ObservedTimestamp,
+			Severity:          item.Severity,
+			SeverityText:      item.SeverityText,
+			LogAttributes:     item.LogAttributes,
+		}
+		if item.Metric != nil && !retry && em.isLate(item) {
+			// Instruments can only record at the current time, so a late event would be counted in
+			// the wrong interval; its log is still exported with the right timestamp
+			status_tracker.GetInstance().AddLateEvents(status_tracker.Key(item.LogSourceInfo.Namespace, item.LogSourceInfo.Name), 1)
+			em.log.Debugf("dropping metric %s of event from %s, older than %s", item.Metric.Name, item.Timestamp, em.maxLateness)
+		} else if item.Metric != nil {
 			if err := em.mc.ExportMetric(myCtx, item.Metric); err != nil {
-				return fmt.Errorf("failed to send metric: %w", err)
+				itemErrs[i] = fmt.Errorf("failed to send metric: %w", err)
+				failedItems[i].Metric = item.Metric
 			}
 		} else {
 			if rand.Intn(1000) == 0 {
@@ -64,14 +260,78 @@ func (em *ExporterManager) ProcessItems(ctx context.Context, items []api.Process
 
 		// Send log if present
 		if item.ForwardLog != "" {
-			if err := em.lc.ExportLog(myCtx, item.ForwardLog); err != nil {
-				return fmt.Errorf("failed to export log: %w", err)
-			}
+			logItems = append(logItems, item)
+			logIndexes = append(logIndexes, i)
 		} else {
 			if rand.Intn(200) == 0 {
 				em.log.Debug("blank log detected (1/200)")
 			}
 		}
 	}
-	return nil
+
+	var errs []error
+	for _, err := range itemErrs {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(logItems) > 0 {
+		failedLogs, err := em.lc.ExportLogs(ctx, logItems)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to export logs: %w", err))
+		}
+		for j, exporters := range failedLogs {
+			i := logIndexes[j]
+			failedItems[i].ForwardLog = items[i].ForwardLog
+			failedItems[i].Exporters = exporters
+			itemErrs[i] = errors.Join(itemErrs[i], logExportError(items[i], exporters))
+		}
+	}
+
+	var failed []api.ProcessedDataItem
+	for i, item := range items {
+		if failedItems[i].Metric != nil || failedItems[i].ForwardLog != "" {
+			failed = append(failed, failedItems[i])
+		}
+		em.recordExport(item, failedItems[i], itemErrs[i])
+	}
+	return failed, errors.Join(errs...)
+}
+
+// logExportError describes why an item's log failed to export from the exporters that failed to export it.
+// No exporters means that no logger is bound to the item's log source.
+func logExportError(item api.ProcessedDataItem, exporters []string) error {
+	if len(exporters) == 0 {
+		return fmt.Errorf("failed to export log: no logger bound to log source %s/%s", item.LogSourceInfo.Namespace, item.LogSourceInfo.Name)
+	}
+	return fmt.Errorf("failed to export log to %s", strings.Join(exporters, ", "))
+}
+
+// isLate reports whether an item's event happened too long ago for its metric to be recorded.
+func (em *ExporterManager) isLate(item api.ProcessedDataItem) bool {
+	return em.maxLateness > 0 && !item.Timestamp.IsZero() && time.Since(item.Timestamp) > em.maxLateness
+}
+
+// recordExport attributes the outcome of exporting an item to every exporter bound to its log source.
+// A failed log is only attributed to the exporters that failed to export it.
+func (em *ExporterManager) recordExport(item, failedItem api.ProcessedDataItem, err error) {
+	em.mu.Lock()
+	failed := make(map[string]bool) // whether the item failed to export to each exporter, by status key
+	for _, name := range em.bindings[item.LogSourceInfo] {
+		if item.Metric == nil && len(item.Exporters) > 0 && !slices.Contains(item.Exporters, name) {
+			continue
+		}
+		logFailed := failedItem.ForwardLog != "" && (len(failedItem.Exporters) == 0 || slices.Contains(failedItem.Exporters, name))
+		failed[status_tracker.Key(em.exporters[name].GetMetadata().Namespace, name)] = failedItem.Metric != nil || logFailed
+	}
+	em.mu.Unlock()
+
+	tracker := status_tracker.GetInstance()
+	for key, keyFailed := range failed {
+		if keyFailed {
+			tracker.RecordExportError(key, err)
+			continue
+		}
+		tracker.AddItemsExported(key, 1)
+	}
 }
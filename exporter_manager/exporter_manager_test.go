@@ -0,0 +1,75 @@
+package exporter_manager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/devon-caron/metrifuge/exporter_manager/log_exporter_client"
+	"github.com/devon-caron/metrifuge/exporter_manager/metric_exporter_client"
+	"github.com/devon-caron/metrifuge/k8s/api"
+	e "github.com/devon-caron/metrifuge/k8s/api/exporter"
+	"github.com/devon-caron/metrifuge/status_tracker"
+	"github.com/sirupsen/logrus"
+)
+
+func TestLatenessOnlyAppliesToNewItems(t *testing.T) {
+	em := &ExporterManager{
+		maxLateness: time.Minute,
+		log:         logrus.New(),
+		mc:          &metric_exporter_client.MetricExporterClient{},
+		lc:          &log_exporter_client.LogExporterClient{},
+	}
+	// The log source has no exporters, so a metric that is recorded fails for want of a meter
+	late := []api.ProcessedDataItem{{
+		Metric:        &api.MetricData{Name: "requests", Kind: "counter", ValueInt: 1},
+		LogSourceInfo: api.LogSourceInfo{Namespace: "default", Name: "app"},
+		Timestamp:     time.Now().Add(-time.Hour),
+	}}
+
+	if failed, err := em.ProcessItems(context.Background(), late); err != nil || len(failed) != 0 {
+		t.Errorf("ProcessItems() = %v, %v, want the late metric dropped", failed, err)
+	}
+	if failed, _ := em.RetryItems(context.Background(), late); len(failed) != 1 || failed[0].Metric == nil {
+		t.Errorf("RetryItems() = %v, want the late metric recorded", failed)
+	}
+}
+
+func TestFailedLogErrorNamesTheCause(t *testing.T) {
+	src := api.LogSourceInfo{Namespace: "default", Name: "app"}
+	em := &ExporterManager{
+		log:       logrus.New(),
+		mc:        &metric_exporter_client.MetricExporterClient{},
+		lc:        &log_exporter_client.LogExporterClient{},
+		exporters: map[string]e.Exporter{"otlp": {Metadata: api.Metadata{Name: "otlp", Namespace: "default"}}},
+		bindings:  map[api.LogSourceInfo][]string{src: {"otlp"}},
+	}
+	// The log client has no logger for the log source, so its log fails without an exporter to blame
+	items := []api.ProcessedDataItem{{ForwardLog: "GET /", LogSourceInfo: src}}
+
+	failed, err := em.ProcessItems(context.Background(), items)
+	if err == nil || len(failed) != 1 || failed[0].ForwardLog != "GET /" {
+		t.Fatalf("ProcessItems() = %v, %v, want the log to fail", failed, err)
+	}
+	want := "failed to export log: no logger bound to log source default/app"
+	if got := status_tracker.GetInstance().GetExporterStats(status_tracker.Key("default", "otlp")).LastError; got != want {
+		t.Errorf("last error = %q, want %q", got, want)
+	}
+}
+
+func TestLogExportError(t *testing.T) {
+	item := api.ProcessedDataItem{LogSourceInfo: api.LogSourceInfo{Namespace: "default", Name: "app"}}
+	tests := []struct {
+		exporters []string
+		want      string
+	}{
+		{nil, "failed to export log: no logger bound to log source default/app"},
+		{[]string{"loki"}, "failed to export log to loki"},
+		{[]string{"loki", "otlp"}, "failed to export log to loki, otlp"},
+	}
+	for _, tt := range tests {
+		if got := logExportError(item, tt.exporters).Error(); got != tt.want {
+			t.Errorf("logExportError(%v) = %q, want %q", tt.exporters, got, tt.want)
+		}
+	}
+}
@@ -2,48 +2,134 @@ package log_exporter_client
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
-	"github.com/devon-caron/metrifuge/global"
+	"github.com/devon-caron/metrifuge/exporter_manager/circuit_breaker"
+	"github.com/devon-caron/metrifuge/k8s/api"
 	e "github.com/devon-caron/metrifuge/k8s/api/exporter"
 	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
 	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
 	"go.opentelemetry.io/otel/log"
 	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
 )
 
 type LogExporterClient struct {
-	loggerProviders map[string]map[string]*sdklog.LoggerProvider
-	loggers         map[string]map[string]log.Logger
-	destinations    map[string]map[string][]sdklog.LoggerProviderOption
+	bindings map[string]map[string]*loggerBinding // Logger providers by log source namespace and name
+	mu       sync.RWMutex
 }
 
-func (le *LogExporterClient) Initialize(ctx context.Context, exporters []e.Exporter) error {
+// loggerBinding is the logger provider of a log source, along with the exports still using it.
+type loggerBinding struct {
+	provider   *sdklog.LoggerProvider
+	logger     log.Logger
+	processors []*exportProcessor // one per exporter, ordered as the exporters were given
+	inflight   sync.WaitGroup
+}
+
+// BindLogSource (re)builds the logger provider for a log source so that its forwarded logs are
+// sent to every given exporter, as coming from the given resource. Binding a log source to no
+// exporters removes its logger provider.
+func (le *LogExporterClient) BindLogSource(ctx context.Context, src api.LogSourceInfo, res *resource.Resource, exporters []e.Exporter) error {
+	if len(exporters) == 0 {
+		return le.UnbindLogSource(ctx, src)
+	}
+
+	destinations := []sdklog.LoggerProviderOption{sdklog.WithResource(res)}
+	var processors []*exportProcessor
 	for _, exporter := range exporters {
+		cb, err := circuit_breaker.GetRegistry().ForExporter(exporter)
+		if err != nil {
+			return err
+		}
+		var processor *exportProcessor
 		if exporter.GetDestinationType() == "OtelCollector" {
 			// Create OTLP gRPC exporter for OTEL collector
-			if err := le.addOtelCollector(ctx, exporter); err != nil {
+			if processor, err = le.addOtelCollector(ctx, exporter, cb); err != nil {
 				return fmt.Errorf("failed to add Otel collector: %v", err)
 			}
-		} else if exporter.GetDestinationType() == "honeycomb" {
+		} else if exporter.GetDestinationType() == "Honeycomb" {
 			// Create OTLP HTTP exporter for Honeycomb
-			if err := le.addHoneycombLogExporter(ctx, exporter); err != nil {
+			if processor, err = le.addHoneycombLogExporter(ctx, exporter, cb); err != nil {
 				return fmt.Errorf("failed to add Honeycomb log exporter: %w", err)
 			}
 		} else {
 			return fmt.Errorf("unknown destination type: %s", exporter.GetDestinationType())
 		}
+		destinations = append(destinations, sdklog.WithProcessor(processor))
+		processors = append(processors, processor)
+	}
+
+	provider := sdklog.NewLoggerProvider(destinations...)
+	binding := &loggerBinding{provider: provider, logger: provider.Logger("metrifuge"), processors: processors}
+
+	le.mu.Lock()
+	if le.bindings == nil {
+		le.bindings = make(map[string]map[string]*loggerBinding)
+	}
+	if le.bindings[src.Namespace] == nil {
+		le.bindings[src.Namespace] = make(map[string]*loggerBinding)
+	}
+	old := le.bindings[src.Namespace][src.Name]
+	le.bindings[src.Namespace][src.Name] = binding
+	le.mu.Unlock()
+
+	// New logs already go to the new provider, so the old one can be drained at leisure
+	return retire(ctx, src, old)
+}
+
+// UnbindLogSource shuts down and removes the logger provider for a log source.
+func (le *LogExporterClient) UnbindLogSource(ctx context.Context, src api.LogSourceInfo) error {
+	le.mu.Lock()
+	old := le.bindings[src.Namespace][src.Name]
+	delete(le.bindings[src.Namespace], src.Name)
+	le.mu.Unlock()
+
+	return retire(ctx, src, old)
+}
+
+// Shutdown flushes and shuts down the logger provider of every log source.
+func (le *LogExporterClient) Shutdown(ctx context.Context) error {
+	le.mu.Lock()
+	bindings := le.bindings
+	le.bindings = nil
+	le.mu.Unlock()
+
+	var errs []error
+	for ns, byName := range bindings {
+		for name, binding := range byName {
+			binding.inflight.Wait()
+			if err := binding.provider.ForceFlush(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("failed to flush logger provider for %s/%s: %w", ns, name, err))
+			}
+			if err := binding.provider.Shutdown(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("failed to shut down logger provider for %s/%s: %w", ns, name, err))
+			}
+		}
 	}
+	return errors.Join(errs...)
+}
 
+// retire waits for the exports still using a replaced logger provider, then shuts it down.
+func retire(ctx context.Context, src api.LogSourceInfo, binding *loggerBinding) error {
+	if binding == nil {
+		return nil
+	}
+	binding.inflight.Wait()
+	if err := binding.provider.Shutdown(ctx); err != nil {
+		return fmt.Errorf("failed to shut down logger provider for %s/%s: %w", src.Namespace, src.Name, err)
+	}
 	return nil
 }
 
-func (le *LogExporterClient) addOtelCollector(ctx context.Context, exporter e.Exporter) error {
+func (le *LogExporterClient) addOtelCollector(ctx context.Context, exporter e.Exporter, cb *circuit_breaker.CircuitBreaker) (*exportProcessor, error) {
 
 	endpoint := exporter.Spec.Destination.OtelCollector.Endpoint
 	if endpoint == "" {
-		return fmt.Errorf("otel collector endpoint is required")
+		return nil, fmt.Errorf("otel collector endpoint is required")
 	}
 	insecure := exporter.Spec.Destination.OtelCollector.Insecure
 
@@ -54,49 +140,22 @@ func (le *LogExporterClient) addOtelCollector(ctx context.Context, exporter e.Ex
 
 	otlpExporter, err := otlploggrpc.New(ctx, options...)
 	if err != nil {
-		return fmt.Errorf("failed to create OTLP gRPC log exporter: %w", err)
-	}
-	if le.loggerProviders == nil {
-		le.loggerProviders = make(map[string]map[string]*sdklog.LoggerProvider)
-	}
-	if le.loggers == nil {
-		le.loggers = make(map[string]map[string]log.Logger)
-	}
-	if le.destinations == nil {
-		le.destinations = make(map[string]map[string][]sdklog.LoggerProviderOption)
-	}
-	ns := exporter.GetLogSourceInfo().Namespace
-	name := exporter.GetLogSourceInfo().Name
-	if le.loggerProviders[ns] == nil {
-		le.loggerProviders[ns] = make(map[string]*sdklog.LoggerProvider)
-	}
-	if le.loggers[ns] == nil {
-		le.loggers[ns] = make(map[string]log.Logger)
+		return nil, fmt.Errorf("failed to create OTLP gRPC log exporter: %w", err)
 	}
-	if le.destinations[ns] == nil {
-		le.destinations[ns] = make(map[string][]sdklog.LoggerProviderOption)
-	}
-	le.destinations[ns][name] = append(le.destinations[ns][name],
-		sdklog.WithProcessor(
-			sdklog.NewBatchProcessor(otlpExporter),
-		),
-	)
-	le.loggerProviders[ns][name] = sdklog.NewLoggerProvider(le.destinations[ns][name]...)
-	le.loggers[ns][name] = le.loggerProviders[ns][name].Logger("metrifuge")
-	return nil
+	return &exportProcessor{name: exporter.GetMetadata().Name, exporter: circuit_breaker.WrapLogExporter(otlpExporter, cb)}, nil
 }
 
-func (le *LogExporterClient) addHoneycombLogExporter(ctx context.Context, exporter e.Exporter) error {
+func (le *LogExporterClient) addHoneycombLogExporter(ctx context.Context, exporter e.Exporter, cb *circuit_breaker.CircuitBreaker) (*exportProcessor, error) {
 	// Validate Honeycomb config
 	honeycombConfig := exporter.Spec.Destination.Honeycomb
 	if honeycombConfig == nil {
-		return fmt.Errorf("honeycomb configuration is required")
+		return nil, fmt.Errorf("honeycomb configuration is required")
 	}
 	if honeycombConfig.APIKey == "" {
-		return fmt.Errorf("honeycomb API key is required")
+		return nil, fmt.Errorf("honeycomb API key is required")
 	}
 	if honeycombConfig.Dataset == "" {
-		return fmt.Errorf("honeycomb dataset is required")
+		return nil, fmt.Errorf("honeycomb dataset is required")
 	}
 
 	// Build headers for Honeycomb
@@ -116,67 +175,84 @@ func (le *LogExporterClient) addHoneycombLogExporter(ctx context.Context, export
 		otlploghttp.WithHeaders(headers),
 	)
 	if err != nil {
-		return fmt.Errorf("failed to create Honeycomb OTLP HTTP log exporter: %w", err)
+		return nil, fmt.Errorf("failed to create Honeycomb OTLP HTTP log exporter: %w", err)
 	}
 
-	// Initialize maps if needed
-	if le.loggerProviders == nil {
-		le.loggerProviders = make(map[string]map[string]*sdklog.LoggerProvider)
-	}
-	if le.loggers == nil {
-		le.loggers = make(map[string]map[string]log.Logger)
-	}
-	if le.destinations == nil {
-		le.destinations = make(map[string]map[string][]sdklog.LoggerProviderOption)
+	return &exportProcessor{name: exporter.GetMetadata().Name, exporter: circuit_breaker.WrapLogExporter(honeycombExporter, cb)}, nil
+}
+
+// ExportLogs emits the forwarded logs of items with the fields their rules attached, stamped with
+// the time and severity of their events if known, and exports them to the exporters of their log
+// sources. Logs of unknown severity are emitted as INFO. It returns the exporters that failed to
+// export the log of each failed item, by index of the item, nil if its log source has no logger.
+func (le *LogExporterClient) ExportLogs(ctx context.Context, items []api.ProcessedDataItem) (map[int][]string, error) {
+	bySource := make(map[api.LogSourceInfo][]int)
+	for i, item := range items {
+		bySource[item.LogSourceInfo] = append(bySource[item.LogSourceInfo], i)
 	}
 
-	ns := exporter.GetLogSourceInfo().Namespace
-	name := exporter.GetLogSourceInfo().Name
+	failed := make(map[int][]string)
+	var errs []error
+	for src, indexes := range bySource {
+		le.mu.RLock()
+		binding := le.bindings[src.Namespace][src.Name]
+		if binding != nil {
+			binding.inflight.Add(1)
+		}
+		le.mu.RUnlock()
+		if binding == nil {
+			for _, i := range indexes {
+				failed[i] = items[i].Exporters
+			}
+			errs = append(errs, fmt.Errorf("logger not found for namespace %s and name %s", src.Namespace, src.Name))
+			continue
+		}
 
-	if le.loggerProviders[ns] == nil {
-		le.loggerProviders[ns] = make(map[string]*sdklog.LoggerProvider)
-	}
-	if le.loggers[ns] == nil {
-		le.loggers[ns] = make(map[string]log.Logger)
-	}
-	if le.destinations[ns] == nil {
-		le.destinations[ns] = make(map[string][]sdklog.LoggerProviderOption)
+		logs := &pendingLogs{
+			records: make(map[*exportProcessor][]sdklog.Record),
+			items:   make(map[*exportProcessor][]int),
+		}
+		for _, i := range indexes {
+			emitCtx := context.WithValue(ctx, emissionKey{}, &emission{logs: logs, index: i, only: items[i].Exporters})
+			binding.logger.Emit(emitCtx, newRecord(items[i]))
+		}
+		for _, processor := range binding.processors {
+			records := logs.records[processor]
+			if len(records) == 0 {
+				continue
+			}
+			if err := processor.exporter.Export(ctx, records); err != nil {
+				for _, i := range logs.items[processor] {
+					failed[i] = append(failed[i], processor.name)
+				}
+				errs = append(errs, fmt.Errorf("failed to export %d logs of %s/%s to %s: %w", len(records), src.Namespace, src.Name, processor.name, err))
+			}
+		}
+		binding.inflight.Done()
 	}
-
-	le.destinations[ns][name] = append(le.destinations[ns][name],
-		sdklog.WithProcessor(
-			sdklog.NewBatchProcessor(honeycombExporter),
-		),
-	)
-	le.loggerProviders[ns][name] = sdklog.NewLoggerProvider(le.destinations[ns][name]...)
-	le.loggers[ns][name] = le.loggerProviders[ns][name].Logger("metrifuge")
-	return nil
+	return failed, errors.Join(errs...)
 }
 
-func (le *LogExporterClient) ExportLog(ctx context.Context, logMessage string) error {
-	// Get cached logger using the correct namespace and exporter name
-	namespace := ""
-	name := ""
-	// Extract namespace and exporter name from context if available
-	if ns, ok := ctx.Value(global.SOURCE_NAMESPACE_KEY).(string); ok && ns != "" {
-		namespace = ns
+// newRecord creates the log record of an item.
+func newRecord(item api.ProcessedDataItem) log.Record {
+	now := time.Now()
+	var record log.Record
+	record.SetTimestamp(item.Timestamp)
+	if item.Timestamp.IsZero() {
+		record.SetTimestamp(now)
 	}
-	if expName, ok := ctx.Value(global.SOURCE_NAME_KEY).(string); ok && expName != "" {
-		name = expName
+	record.SetObservedTimestamp(item.ObservedTimestamp)
+	if item.ObservedTimestamp.IsZero() {
+		record.SetObservedTimestamp(now)
 	}
-	logger := le.loggers[namespace][name]
-	if logger == nil {
-		return fmt.Errorf("logger not found for namespace %s and name %s", namespace, name)
+	record.SetBody(log.StringValue(item.ForwardLog))
+	record.SetSeverity(item.Severity)
+	if item.Severity == log.SeverityUndefined {
+		record.SetSeverity(log.SeverityInfo)
 	}
-
-	// Create a log record
-	var record log.Record
-	record.SetTimestamp(time.Now())
-	record.SetBody(log.StringValue(logMessage))
-	record.SetSeverity(log.SeverityInfo)
-
-	// Emit the log record
-	logger.Emit(ctx, record)
-
-	return nil
+	record.SetSeverityText(item.SeverityText)
+	for _, kv := range item.LogAttributes {
+		record.AddAttributes(log.String(string(kv.Key), kv.Value.Emit()))
+	}
+	return record
 }
@@ -0,0 +1,121 @@
+package log_exporter_client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/devon-caron/metrifuge/k8s/api"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// fakeExporter records the bodies of the logs it exports, or fails every export if err is set.
+type fakeExporter struct {
+	err    error
+	bodies []string
+}
+
+func (fe *fakeExporter) Export(_ context.Context, records []sdklog.Record) error {
+	if fe.err != nil {
+		return fe.err
+	}
+	for _, record := range records {
+		fe.bodies = append(fe.bodies, record.Body().AsString())
+	}
+	return nil
+}
+
+func (fe *fakeExporter) Shutdown(context.Context) error   { return nil }
+func (fe *fakeExporter) ForceFlush(context.Context) error { return nil }
+
+func newTestClient(src api.LogSourceInfo, exporters map[string]*fakeExporter, names ...string) *LogExporterClient {
+	var options []sdklog.LoggerProviderOption
+	var processors []*exportProcessor
+	for _, name := range names {
+		processor := &exportProcessor{name: name, exporter: exporters[name]}
+		options = append(options, sdklog.WithProcessor(processor))
+		processors = append(processors, processor)
+	}
+	provider := sdklog.NewLoggerProvider(options...)
+	return &LogExporterClient{bindings: map[string]map[string]*loggerBinding{
+		src.Namespace: {src.Name: {provider: provider, logger: provider.Logger("metrifuge"), processors: processors}},
+	}}
+}
+
+func TestExportLogsReturnsRejectedLogs(t *testing.T) {
+	src := api.LogSourceInfo{Namespace: "default", Name: "app"}
+	exporters := map[string]*fakeExporter{"up": {}, "down": {err: errors.New("unavailable")}}
+	le := newTestClient(src, exporters, "down", "up")
+
+	items := []api.ProcessedDataItem{
+		{ForwardLog: "first", LogSourceInfo: src},
+		{ForwardLog: "second", LogSourceInfo: src},
+		{ForwardLog: "orphan", LogSourceInfo: api.LogSourceInfo{Namespace: "default", Name: "unbound"}},
+	}
+	failed, err := le.ExportLogs(context.Background(), items)
+	if err == nil {
+		t.Fatal("expected an error for the failed exporter")
+	}
+
+	if got := exporters["up"].bodies; len(got) != 2 || got[0] != "first" || got[1] != "second" {
+		t.Errorf("up exported %v, want [first second]", got)
+	}
+	for _, i := range []int{0, 1} {
+		if got := failed[i]; len(got) != 1 || got[0] != "down" {
+			t.Errorf("item %d failed for %v, want [down]", i, got)
+		}
+	}
+	if got, ok := failed[2]; !ok || got != nil {
+		t.Errorf("item of unbound log source failed for %v (%v), want every exporter", got, ok)
+	}
+}
+
+func TestExportLogsOnlyToGivenExporters(t *testing.T) {
+	src := api.LogSourceInfo{Namespace: "default", Name: "app"}
+	exporters := map[string]*fakeExporter{"a": {}, "b": {}}
+	le := newTestClient(src, exporters, "a", "b")
+
+	items := []api.ProcessedDataItem{{ForwardLog: "retried", LogSourceInfo: src, Exporters: []string{"b"}}}
+	failed, err := le.ExportLogs(context.Background(), items)
+	if err != nil || len(failed) != 0 {
+		t.Fatalf("ExportLogs() = %v, %v, want no failures", failed, err)
+	}
+	if len(exporters["a"].bodies) != 0 {
+		t.Errorf("a exported %v, want nothing", exporters["a"].bodies)
+	}
+	if got := exporters["b"].bodies; len(got) != 1 || got[0] != "retried" {
+		t.Errorf("b exported %v, want [retried]", got)
+	}
+}
+
+func TestNewRecord(t *testing.T) {
+	readAt := time.Unix(1700000000, 0)
+	record := newRecord(api.ProcessedDataItem{
+		ForwardLog:        "warn bob 500",
+		ObservedTimestamp: readAt,
+		Severity:          log.SeverityWarn,
+		SeverityText:      "warn",
+		LogAttributes:     []attribute.KeyValue{attribute.String("user", "bob"), attribute.String("status", "500")},
+	})
+	if record.Body().AsString() != "warn bob 500" || record.Severity() != log.SeverityWarn || record.SeverityText() != "warn" {
+		t.Errorf("record = %v, want the item's body and severity", record)
+	}
+	if !record.ObservedTimestamp().Equal(readAt) || record.Timestamp().IsZero() {
+		t.Errorf("timestamps = %v, %v, want the read time and a current timestamp", record.ObservedTimestamp(), record.Timestamp())
+	}
+	attrs := make(map[string]string)
+	record.WalkAttributes(func(kv log.KeyValue) bool {
+		attrs[kv.Key] = kv.Value.AsString()
+		return true
+	})
+	if len(attrs) != 2 || attrs["user"] != "bob" || attrs["status"] != "500" {
+		t.Errorf("attributes = %v, want user and status", attrs)
+	}
+
+	if unset := newRecord(api.ProcessedDataItem{ForwardLog: "plain"}); unset.Severity() != log.SeverityInfo {
+		t.Errorf("severity = %v, want INFO for logs without one", unset.Severity())
+	}
+}
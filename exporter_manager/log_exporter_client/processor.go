@@ -0,0 +1,55 @@
+package log_exporter_client
+
+import (
+	"context"
+	"slices"
+
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// exportProcessor exports the logs of an ExportLogs call to one exporter once the call has emitted
+// them all. Unlike a batch processor, which drops the logs its exporter fails to export, it lets
+// the call know which logs failed so that they can be retried.
+type exportProcessor struct {
+	name     string // of the exporter
+	exporter sdklog.Exporter
+}
+
+// emission is the log an ExportLogs call is emitting. It travels in the context of the emit so
+// that the processors of the logger add it to the logs of the call.
+type emission struct {
+	logs  *pendingLogs
+	index int      // of the log's item in the call
+	only  []string // exporters the log is for, every exporter if empty
+}
+
+type emissionKey struct{}
+
+// pendingLogs are the logs emitted by an ExportLogs call for each processor, along with the
+// indexes of their items.
+type pendingLogs struct {
+	records map[*exportProcessor][]sdklog.Record
+	items   map[*exportProcessor][]int
+}
+
+func (p *exportProcessor) OnEmit(ctx context.Context, record *sdklog.Record) error {
+	e, ok := ctx.Value(emissionKey{}).(*emission)
+	if !ok {
+		return p.exporter.Export(ctx, []sdklog.Record{record.Clone()})
+	}
+	if len(e.only) > 0 && !slices.Contains(e.only, p.name) {
+		return nil
+	}
+	// The record is shared by every processor of the logger
+	e.logs.records[p] = append(e.logs.records[p], record.Clone())
+	e.logs.items[p] = append(e.logs.items[p], e.index)
+	return nil
+}
+
+func (p *exportProcessor) Shutdown(ctx context.Context) error {
+	return p.exporter.Shutdown(ctx)
+}
+
+func (p *exportProcessor) ForceFlush(ctx context.Context) error {
+	return p.exporter.ForceFlush(ctx)
+}
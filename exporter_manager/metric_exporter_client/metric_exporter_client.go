@@ -2,9 +2,12 @@ package metric_exporter_client
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/devon-caron/metrifuge/exporter_manager/circuit_breaker"
 	"github.com/devon-caron/metrifuge/global"
 	"github.com/devon-caron/metrifuge/k8s/api"
 	e "github.com/devon-caron/metrifuge/k8s/api/exporter"
@@ -15,57 +18,120 @@ import (
 )
 
 type MetricExporterClient struct {
-	meterProviders map[string]map[string]*sdkmetric.MeterProvider
-	meters         map[string]map[string]metric.Meter
-	destinations   []sdkmetric.Option
+	bindings map[string]map[string]*meterBinding // Meter providers by log source namespace and name
+	mu       sync.RWMutex
 }
 
-func (me *MetricExporterClient) Initialize(ctx context.Context, exporters []e.Exporter) error {
+// meterBinding is the meter provider of a log source, along with the exports still using it.
+type meterBinding struct {
+	provider *sdkmetric.MeterProvider
+	meter    metric.Meter
+	inflight sync.WaitGroup
+}
+
+// BindLogSource (re)builds the meter provider for a log source so that its metrics are read by
+// every given exporter. Binding a log source to no exporters removes its meter provider.
+func (me *MetricExporterClient) BindLogSource(ctx context.Context, src api.LogSourceInfo, exporters []e.Exporter) error {
+	if len(exporters) == 0 {
+		return me.UnbindLogSource(ctx, src)
+	}
+
+	var destinations []sdkmetric.Option
 	for _, exporter := range exporters {
+		cb, err := circuit_breaker.GetRegistry().ForExporter(exporter)
+		if err != nil {
+			return err
+		}
+		var reader sdkmetric.Option
 		if exporter.GetDestinationType() == "OtelCollector" {
 			// Create OTLP gRPC exporter for OTEL collector
-			if err := me.addOtelCollector(ctx, exporter); err != nil {
+			if reader, err = me.addOtelCollector(ctx, exporter, cb); err != nil {
 				return fmt.Errorf("failed to add OTLP collector: %w", err)
 			}
-		} else if exporter.GetDestinationType() == "honeycomb" {
+		} else if exporter.GetDestinationType() == "Honeycomb" {
 			// Create OTLP HTTP exporter for Honeycomb
-			if err := me.addHoneycombMetricExporter(ctx, exporter); err != nil {
+			if reader, err = me.addHoneycombMetricExporter(ctx, exporter, cb); err != nil {
 				return fmt.Errorf("failed to add Honeycomb exporter: %w", err)
 			}
 			// } else if exporter.GetDestinationType() == "prometheus" {
 			// 	// Create OTLP gRPC exporter for Prometheus collector
-			// 	if err := me.addPrometheusMetricExporter(ctx, exporter); err != nil {
+			// 	if err := me.addPrometheusMetricExporter(ctx, exporter, cb); err != nil {
 			// 		return err
 			// 	}
 		} else {
 			return fmt.Errorf("unknown destination type: %s", exporter.GetDestinationType())
 		}
-		if me.meterProviders == nil {
-			me.meterProviders = make(map[string]map[string]*sdkmetric.MeterProvider)
-		}
-		if me.meters == nil {
-			me.meters = make(map[string]map[string]metric.Meter)
-		}
-		ns := exporter.GetLogSourceInfo().Namespace
-		name := exporter.GetLogSourceInfo().Name
-		if me.meterProviders[ns] == nil {
-			me.meterProviders[ns] = make(map[string]*sdkmetric.MeterProvider)
-		}
-		if me.meters[ns] == nil {
-			me.meters[ns] = make(map[string]metric.Meter)
+		destinations = append(destinations, reader)
+	}
+
+	provider := sdkmetric.NewMeterProvider(destinations...)
+	binding := &meterBinding{provider: provider, meter: provider.Meter("metrifuge")}
+
+	me.mu.Lock()
+	if me.bindings == nil {
+		me.bindings = make(map[string]map[string]*meterBinding)
+	}
+	if me.bindings[src.Namespace] == nil {
+		me.bindings[src.Namespace] = make(map[string]*meterBinding)
+	}
+	old := me.bindings[src.Namespace][src.Name]
+	me.bindings[src.Namespace][src.Name] = binding
+	me.mu.Unlock()
+
+	// New exports already go to the new provider, so the old one can be drained at leisure
+	return retire(ctx, src, old)
+}
+
+// UnbindLogSource shuts down and removes the meter provider for a log source.
+func (me *MetricExporterClient) UnbindLogSource(ctx context.Context, src api.LogSourceInfo) error {
+	me.mu.Lock()
+	old := me.bindings[src.Namespace][src.Name]
+	delete(me.bindings[src.Namespace], src.Name)
+	me.mu.Unlock()
+
+	return retire(ctx, src, old)
+}
+
+// Shutdown flushes and shuts down the meter provider of every log source.
+func (me *MetricExporterClient) Shutdown(ctx context.Context) error {
+	me.mu.Lock()
+	bindings := me.bindings
+	me.bindings = nil
+	me.mu.Unlock()
+
+	var errs []error
+	for ns, byName := range bindings {
+		for name, binding := range byName {
+			binding.inflight.Wait()
+			if err := binding.provider.ForceFlush(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("failed to flush meter provider for %s/%s: %w", ns, name, err))
+			}
+			if err := binding.provider.Shutdown(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("failed to shut down meter provider for %s/%s: %w", ns, name, err))
+			}
 		}
-		me.meterProviders[ns][name] = sdkmetric.NewMeterProvider(me.destinations...)
-		me.meters[ns][name] = me.meterProviders[ns][name].Meter("metrifuge")
 	}
+	return errors.Join(errs...)
+}
 
+// retire waits for the exports still using a replaced meter provider, then shuts it down, which
+// exports whatever it has collected since its last interval.
+func retire(ctx context.Context, src api.LogSourceInfo, binding *meterBinding) error {
+	if binding == nil {
+		return nil
+	}
+	binding.inflight.Wait()
+	if err := binding.provider.Shutdown(ctx); err != nil {
+		return fmt.Errorf("failed to shut down meter provider for %s/%s: %w", src.Namespace, src.Name, err)
+	}
 	return nil
 }
 
-func (me *MetricExporterClient) addOtelCollector(ctx context.Context, exporter e.Exporter) error {
+func (me *MetricExporterClient) addOtelCollector(ctx context.Context, exporter e.Exporter, cb *circuit_breaker.CircuitBreaker) (sdkmetric.Option, error) {
 
 	endpoint := exporter.Spec.Destination.OtelCollector.Endpoint
 	if endpoint == "" {
-		return fmt.Errorf("otel collector endpoint is required")
+		return nil, fmt.Errorf("otel collector endpoint is required")
 	}
 	insecure := exporter.Spec.Destination.OtelCollector.Insecure
 
@@ -76,32 +142,29 @@ func (me *MetricExporterClient) addOtelCollector(ctx context.Context, exporter e
 
 	otlpExporter, err := otlpmetricgrpc.New(ctx, options...)
 	if err != nil {
-		return fmt.Errorf("failed to create OTLP gRPC exporter: %w", err)
+		return nil, fmt.Errorf("failed to create OTLP gRPC exporter: %w", err)
 	}
 	refreshInterval, err := time.ParseDuration(exporter.Spec.RefreshInterval)
 	if err != nil {
-		return fmt.Errorf("failed to parse refresh interval: %w", err)
+		return nil, fmt.Errorf("failed to parse refresh interval: %w", err)
 	}
-	me.destinations = append(me.destinations,
-		sdkmetric.WithReader(
-			sdkmetric.NewPeriodicReader(otlpExporter,
-				sdkmetric.WithInterval(refreshInterval),
-			)),
-	)
-	return nil
+	return sdkmetric.WithReader(
+		sdkmetric.NewPeriodicReader(circuit_breaker.WrapMetricExporter(otlpExporter, cb),
+			sdkmetric.WithInterval(refreshInterval),
+		)), nil
 }
 
-func (me *MetricExporterClient) addHoneycombMetricExporter(ctx context.Context, exporter e.Exporter) error {
+func (me *MetricExporterClient) addHoneycombMetricExporter(ctx context.Context, exporter e.Exporter, cb *circuit_breaker.CircuitBreaker) (sdkmetric.Option, error) {
 	// Validate Honeycomb config
 	honeycombConfig := exporter.Spec.Destination.Honeycomb
 	if honeycombConfig == nil {
-		return fmt.Errorf("honeycomb configuration is required")
+		return nil, fmt.Errorf("honeycomb configuration is required")
 	}
 	if honeycombConfig.APIKey == "" {
-		return fmt.Errorf("honeycomb API key is required")
+		return nil, fmt.Errorf("honeycomb API key is required")
 	}
 	if honeycombConfig.Dataset == "" {
-		return fmt.Errorf("honeycomb dataset is required")
+		return nil, fmt.Errorf("honeycomb dataset is required")
 	}
 
 	// Build headers for Honeycomb
@@ -121,22 +184,19 @@ func (me *MetricExporterClient) addHoneycombMetricExporter(ctx context.Context,
 		otlpmetrichttp.WithHeaders(headers),
 	)
 	if err != nil {
-		return fmt.Errorf("failed to create Honeycomb OTLP HTTP exporter: %w", err)
+		return nil, fmt.Errorf("failed to create Honeycomb OTLP HTTP exporter: %w", err)
 	}
 
 	// Parse refresh interval
 	refreshInterval, err := time.ParseDuration(exporter.Spec.RefreshInterval)
 	if err != nil {
-		return fmt.Errorf("failed to parse refresh interval: %w", err)
+		return nil, fmt.Errorf("failed to parse refresh interval: %w", err)
 	}
 
-	me.destinations = append(me.destinations,
-		sdkmetric.WithReader(
-			sdkmetric.NewPeriodicReader(honeycombExporter,
-				sdkmetric.WithInterval(refreshInterval),
-			)),
-	)
-	return nil
+	return sdkmetric.WithReader(
+		sdkmetric.NewPeriodicReader(circuit_breaker.WrapMetricExporter(honeycombExporter, cb),
+			sdkmetric.WithInterval(refreshInterval),
+		)), nil
 }
 
 func (me *MetricExporterClient) ExportMetric(ctx context.Context, metricData *api.MetricData) error {
@@ -150,10 +210,17 @@ func (me *MetricExporterClient) ExportMetric(ctx context.Context, metricData *ap
 	if expName, ok := ctx.Value(global.SOURCE_NAME_KEY).(string); ok && expName != "" {
 		name = expName
 	}
-	meter := me.meters[namespace][name]
-	if meter == nil {
+	me.mu.RLock()
+	binding := me.bindings[namespace][name]
+	if binding != nil {
+		binding.inflight.Add(1)
+	}
+	me.mu.RUnlock()
+	if binding == nil {
 		return fmt.Errorf("meter not found for namespace %s and name %s", namespace, name)
 	}
+	defer binding.inflight.Done()
+	meter := binding.meter
 
 	// Create and record based on metric kind
 	switch metricData.Kind {
@@ -206,7 +273,7 @@ func (me *MetricExporterClient) ExportMetric(ctx context.Context, metricData *ap
 	return nil
 }
 
-// func (me *MetricExporterClient) addPrometheusMetricExporter(ctx context.Context, exporter e.Exporter) error {
+// func (me *MetricExporterClient) addPrometheusMetricExporter(ctx context.Context, exporter e.Exporter, cb *circuit_breaker.CircuitBreaker) error {
 // 	prometheusExporter, err := prometheus.New()
 // 	if err != nil {
 // 		return err
@@ -0,0 +1,64 @@
+package exporter_manager
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/devon-caron/metrifuge/k8s/api"
+	ls "github.com/devon-caron/metrifuge/k8s/api/log_source"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// Resource attributes identifying the log source that forwarded logs were read from
+const (
+	logSourceNameKey      = attribute.Key("metrifuge.log_source.name")
+	logSourceNamespaceKey = attribute.Key("metrifuge.log_source.namespace")
+	logSourceTypeKey      = attribute.Key("metrifuge.log_source.type")
+	logSourcePVCKey       = attribute.Key("metrifuge.log_source.pvc")
+	logSourceLabelPrefix  = "metrifuge.log_source.label."
+)
+
+// logSourceResource describes a log source as an OpenTelemetry resource: its name and namespace,
+// its labels, and where its logs come from. logSource is nil for log sources that exporters
+// reference by name before they have been listed, which only get their name and namespace.
+func logSourceResource(src api.LogSourceInfo, logSource *ls.LogSource) (*resource.Resource, error) {
+	attrs := []attribute.KeyValue{
+		logSourceNameKey.String(src.Name),
+		logSourceNamespaceKey.String(src.Namespace),
+	}
+	if logSource != nil {
+		if logSource.Spec.Type != "" {
+			attrs = append(attrs, logSourceTypeKey.String(logSource.Spec.Type))
+		}
+		for _, key := range slices.Sorted(maps.Keys(logSource.Metadata.Labels)) {
+			attrs = append(attrs, attribute.String(logSourceLabelPrefix+key, logSource.Metadata.Labels[key]))
+		}
+
+		source := logSource.Spec.Source
+		if pod := source.PodSource; pod != nil {
+			attrs = append(attrs,
+				semconv.K8SPodName(pod.Pod.Name),
+				semconv.K8SNamespaceName(pod.Pod.Namespace),
+				semconv.K8SContainerName(pod.Pod.Container),
+			)
+		}
+		if pvc := source.PVCSource; pvc != nil {
+			attrs = append(attrs, logSourcePVCKey.String(pvc.PVC.Name), semconv.LogFilePath(pvc.LogFilePath))
+		}
+		if local := source.LocalSource; local != nil {
+			attrs = append(attrs, semconv.LogFilePath(local.Path))
+		}
+		if cmd := source.CmdSource; cmd != nil {
+			attrs = append(attrs, semconv.ProcessCommandLine(cmd.Command))
+		}
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, attrs...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build resource of log source %s/%s: %w", src.Namespace, src.Name, err)
+	}
+	return res, nil
+}
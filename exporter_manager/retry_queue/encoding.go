@@ -0,0 +1,148 @@
+package retry_queue
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/devon-caron/metrifuge/k8s/api"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/log"
+)
+
+// queuedItem is the on-disk form of an api.ProcessedDataItem. attribute.KeyValue cannot be
+// unmarshalled from JSON, so metric attributes are stored with an explicit type.
+type queuedItem struct {
+	ForwardLog        string            `json:"forwardLog,omitempty"`
+	Metric            *queuedMetric     `json:"metric,omitempty"`
+	LogSourceInfo     api.LogSourceInfo `json:"logSourceInfo"`
+	Timestamp         time.Time         `json:"timestamp,omitzero"`
+	ObservedTimestamp time.Time         `json:"observedTimestamp,omitzero"`
+	Severity          int               `json:"severity,omitempty"`
+	SeverityText      string            `json:"severityText,omitempty"`
+	LogAttributes     []queuedAttribute `json:"logAttributes,omitempty"`
+	Exporters         []string          `json:"exporters,omitempty"`
+}
+
+type queuedMetric struct {
+	Name       string            `json:"name"`
+	Kind       string            `json:"kind"`
+	ValueInt   int64             `json:"valueInt,omitempty"`
+	ValueFloat float64           `json:"valueFloat,omitempty"`
+	Attributes []queuedAttribute `json:"attributes,omitempty"`
+}
+
+type queuedAttribute struct {
+	Key     string  `json:"key"`
+	Type    string  `json:"type"`
+	String  string  `json:"string,omitempty"`
+	Int64   int64   `json:"int64,omitempty"`
+	Float64 float64 `json:"float64,omitempty"`
+	Bool    bool    `json:"bool,omitempty"`
+}
+
+func toQueuedItems(items []api.ProcessedDataItem) []queuedItem {
+	queued := make([]queuedItem, 0, len(items))
+	for _, item := range items {
+		qi := queuedItem{
+			ForwardLog:        item.ForwardLog,
+			LogSourceInfo:     item.LogSourceInfo,
+			Timestamp:         item.Timestamp,
+			ObservedTimestamp: item.ObservedTimestamp,
+			Severity:          int(item.Severity),
+			SeverityText:      item.SeverityText,
+			LogAttributes:     toQueuedAttributes(item.LogAttributes),
+			Exporters:         item.Exporters,
+		}
+		if item.Metric != nil {
+			qi.Metric = &queuedMetric{
+				Name:       item.Metric.Name,
+				Kind:       item.Metric.Kind,
+				ValueInt:   item.Metric.ValueInt,
+				ValueFloat: item.Metric.ValueFloat,
+				Attributes: toQueuedAttributes(item.Metric.Attributes),
+			}
+		}
+		queued = append(queued, qi)
+	}
+	return queued
+}
+
+func toQueuedAttributes(attrs []attribute.KeyValue) []queuedAttribute {
+	var queued []queuedAttribute
+	for _, kv := range attrs {
+		qa := queuedAttribute{
+			Key:  string(kv.Key),
+			Type: kv.Value.Type().String(),
+		}
+		switch kv.Value.Type() {
+		case attribute.STRING:
+			qa.String = kv.Value.AsString()
+		case attribute.INT64:
+			qa.Int64 = kv.Value.AsInt64()
+		case attribute.FLOAT64:
+			qa.Float64 = kv.Value.AsFloat64()
+		case attribute.BOOL:
+			qa.Bool = kv.Value.AsBool()
+		default:
+			qa.Type = attribute.STRING.String()
+			qa.String = kv.Value.Emit()
+		}
+		queued = append(queued, qa)
+	}
+	return queued
+}
+
+func fromQueuedItems(queued []queuedItem) ([]api.ProcessedDataItem, error) {
+	items := make([]api.ProcessedDataItem, 0, len(queued))
+	for _, qi := range queued {
+		item := api.ProcessedDataItem{
+			ForwardLog:        qi.ForwardLog,
+			LogSourceInfo:     qi.LogSourceInfo,
+			Timestamp:         qi.Timestamp,
+			ObservedTimestamp: qi.ObservedTimestamp,
+			Severity:          log.Severity(qi.Severity),
+			SeverityText:      qi.SeverityText,
+			Exporters:         qi.Exporters,
+		}
+		var err error
+		if len(qi.LogAttributes) > 0 {
+			if item.LogAttributes, err = fromQueuedAttributes(qi.LogAttributes); err != nil {
+				return nil, err
+			}
+		}
+		if qi.Metric != nil {
+			item.Metric = &api.MetricData{
+				Name:       qi.Metric.Name,
+				Kind:       qi.Metric.Kind,
+				ValueInt:   qi.Metric.ValueInt,
+				ValueFloat: qi.Metric.ValueFloat,
+			}
+			if item.Metric.Attributes, err = fromQueuedAttributes(qi.Metric.Attributes); err != nil {
+				return nil, err
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func fromQueuedAttributes(queued []queuedAttribute) ([]attribute.KeyValue, error) {
+	attrs := make([]attribute.KeyValue, 0, len(queued))
+	for _, qa := range queued {
+		var kv attribute.KeyValue
+		switch qa.Type {
+		case attribute.STRING.String():
+			kv = attribute.String(qa.Key, qa.String)
+		case attribute.INT64.String():
+			kv = attribute.Int64(qa.Key, qa.Int64)
+		case attribute.FLOAT64.String():
+			kv = attribute.Float64(qa.Key, qa.Float64)
+		case attribute.BOOL.String():
+			kv = attribute.Bool(qa.Key, qa.Bool)
+		default:
+			return nil, fmt.Errorf("unknown attribute type %s for key %s", qa.Type, qa.Key)
+		}
+		attrs = append(attrs, kv)
+	}
+	return attrs, nil
+}
@@ -0,0 +1,352 @@
+package retry_queue
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/devon-caron/metrifuge/k8s/api"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	batchFileSuffix    = ".batch.json"
+	deadLetterFileName = "dead-letter.jsonl"
+)
+
+// ExportFunc exports a batch of items and returns the items that could not be exported.
+type ExportFunc func(ctx context.Context, items []api.ProcessedDataItem) ([]api.ProcessedDataItem, error)
+
+/**
+ * RetryQueue is a write-ahead disk queue that sits between the log handler and the exporters.
+ * Every batch is persisted before it is exported, failed items are retried with exponential
+ * backoff, and batches that exhaust their attempts (or overflow the queue) are moved to a
+ * dead-letter file. Pending batches are reloaded from disk when the queue is initialized, so a
+ * batch whose export was cut short by a crash is exported again, possibly a second time.
+ */
+type RetryQueue struct {
+	dir         string
+	capacity    int
+	maxAttempts int
+	backoffBase time.Duration
+	backoffMax  time.Duration
+	batches     []*batch
+	nextSeq     uint64
+	log         *logrus.Logger
+	mu          sync.Mutex
+}
+
+type batch struct {
+	Seq         uint64       `json:"seq"`
+	Attempts    int          `json:"attempts"`
+	NextAttempt time.Time    `json:"nextAttempt"`
+	LastError   string       `json:"lastError,omitempty"`
+	Items       []queuedItem `json:"items"`
+	inFlight    bool         // being exported, by the pipeline or Process
+}
+
+type deadLetter struct {
+	Reason string `json:"reason"`
+	batch
+}
+
+func (rq *RetryQueue) Initialize(dir string, capacity, maxAttempts int, backoffBase, backoffMax time.Duration, log *logrus.Logger) error {
+	if capacity <= 0 {
+		return fmt.Errorf("retry queue capacity must be positive, got %d", capacity)
+	}
+	if maxAttempts <= 0 {
+		return fmt.Errorf("retry queue max attempts must be positive, got %d", maxAttempts)
+	}
+	if backoffBase <= 0 || backoffMax < backoffBase {
+		return fmt.Errorf("invalid retry backoff: base %v, max %v", backoffBase, backoffMax)
+	}
+
+	rq.dir = dir
+	rq.capacity = capacity
+	rq.maxAttempts = maxAttempts
+	rq.backoffBase = backoffBase
+	rq.backoffMax = backoffMax
+	rq.log = log
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create retry queue directory %s: %w", dir, err)
+	}
+
+	if err := rq.load(); err != nil {
+		return fmt.Errorf("failed to load retry queue from %s: %w", dir, err)
+	}
+
+	log.Infof("retry queue initialized at %s with %d pending batches", dir, len(rq.batches))
+	return nil
+}
+
+// load restores the batches left on disk by a previous run, oldest first.
+func (rq *RetryQueue) load() error {
+	entries, err := os.ReadDir(rq.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), batchFileSuffix) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(rq.dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to read batch file %s: %w", entry.Name(), err)
+		}
+		var b batch
+		if err := json.Unmarshal(data, &b); err != nil {
+			rq.log.Errorf("skipping corrupt retry queue batch %s: %v", entry.Name(), err)
+			continue
+		}
+		rq.batches = append(rq.batches, &b)
+		if b.Seq >= rq.nextSeq {
+			rq.nextSeq = b.Seq + 1
+		}
+	}
+
+	slices.SortFunc(rq.batches, func(a, b *batch) int {
+		return cmp.Compare(a.Seq, b.Seq)
+	})
+	return nil
+}
+
+// Enqueue persists a batch of items before it is exported, so that it survives a crash until it
+// is. The batch is left alone by Process until Settle reports the outcome of its export with the
+// returned sequence number. If the queue is full, the oldest pending batch is moved to the
+// dead-letter file.
+func (rq *RetryQueue) Enqueue(items []api.ProcessedDataItem) (uint64, error) {
+	rq.mu.Lock()
+	defer rq.mu.Unlock()
+
+	for len(rq.batches) >= rq.capacity {
+		// Batches being exported are bounded by the export workers, so they may exceed the capacity
+		i := slices.IndexFunc(rq.batches, func(b *batch) bool { return !b.inFlight })
+		if i < 0 {
+			break
+		}
+		oldest := rq.batches[i]
+		rq.log.Warnf("retry queue full (%d batches), dead-lettering batch %d", rq.capacity, oldest.Seq)
+		if err := rq.deadLetter(oldest, "queue capacity exceeded"); err != nil {
+			return 0, err
+		}
+		rq.batches = slices.Delete(rq.batches, i, i+1)
+	}
+
+	b := &batch{
+		Seq:         rq.nextSeq,
+		NextAttempt: time.Now(),
+		Items:       toQueuedItems(items),
+		inFlight:    true,
+	}
+	if err := rq.persist(b); err != nil {
+		return 0, fmt.Errorf("failed to persist batch %d: %w", b.Seq, err)
+	}
+	rq.nextSeq++
+	rq.batches = append(rq.batches, b)
+	return b.Seq, nil
+}
+
+// Settle records the outcome of the export of a batch returned by Enqueue. The batch is removed
+// once every item is exported, and its failed items are otherwise retried by Process.
+func (rq *RetryQueue) Settle(seq uint64, failed []api.ProcessedDataItem, err error) error {
+	rq.mu.Lock()
+	defer rq.mu.Unlock()
+
+	i := slices.IndexFunc(rq.batches, func(b *batch) bool { return b.Seq == seq })
+	if i < 0 {
+		return fmt.Errorf("unknown batch %d", seq)
+	}
+	return rq.settle(rq.batches[i], failed, err)
+}
+
+// Process exports every batch that is due for an attempt, in order. Items that fail are kept
+// for the next attempt with an exponential backoff, and batches that run out of attempts are
+// dead-lettered. It returns the number of items exported.
+//
+// The queue is only locked to pick the due batches and to record the outcome of each export, so
+// that the pipeline can keep enqueueing while a destination that is down makes exports time out.
+func (rq *RetryQueue) Process(ctx context.Context, export ExportFunc) (int, error) {
+	type due struct {
+		batch *batch
+		items []api.ProcessedDataItem
+	}
+	var errs []error
+	var pending []due
+
+	rq.mu.Lock()
+	now := time.Now()
+	for _, b := range slices.Clone(rq.batches) {
+		if b.inFlight || b.NextAttempt.After(now) {
+			continue
+		}
+		items, err := fromQueuedItems(b.Items)
+		if err != nil {
+			rq.log.Errorf("failed to decode batch %d: %v", b.Seq, err)
+			if dlErr := rq.deadLetter(b, err.Error()); dlErr != nil {
+				errs = append(errs, dlErr)
+			} else {
+				rq.drop(b)
+			}
+			continue
+		}
+		b.inFlight = true
+		pending = append(pending, due{batch: b, items: items})
+	}
+	rq.mu.Unlock()
+
+	exported := 0
+	for i, d := range pending {
+		if ctx.Err() != nil {
+			// Batches that were not attempted are due again on the next call
+			rq.mu.Lock()
+			for _, skipped := range pending[i:] {
+				skipped.batch.inFlight = false
+			}
+			rq.mu.Unlock()
+			break
+		}
+
+		failed, err := export(ctx, d.items)
+		exported += len(d.items) - len(failed)
+		rq.mu.Lock()
+		if err := rq.settle(d.batch, failed, err); err != nil {
+			errs = append(errs, err)
+		}
+		rq.mu.Unlock()
+	}
+
+	return exported, errors.Join(errs...)
+}
+
+// settle removes an exported batch, or keeps its failed items for another attempt after a backoff
+// and dead-letters them once it has run out of attempts. rq.mu must be held.
+func (rq *RetryQueue) settle(b *batch, failed []api.ProcessedDataItem, err error) error {
+	b.inFlight = false
+	if len(failed) == 0 {
+		rq.drop(b)
+		return rq.remove(b)
+	}
+
+	b.Attempts++
+	b.Items = toQueuedItems(failed)
+	if err != nil {
+		b.LastError = err.Error()
+	}
+	if b.Attempts >= rq.maxAttempts {
+		rq.log.Errorf("batch %d failed after %d attempts, dead-lettering %d items: %v", b.Seq, b.Attempts, len(failed), err)
+		if dlErr := rq.deadLetter(b, "max attempts exceeded"); dlErr != nil {
+			return dlErr
+		}
+		rq.drop(b)
+		return nil
+	}
+
+	b.NextAttempt = time.Now().Add(rq.backoff(b.Attempts))
+	rq.log.Warnf("batch %d failed (attempt %d/%d), retrying %d items at %v: %v",
+		b.Seq, b.Attempts, rq.maxAttempts, len(failed), b.NextAttempt.Format(time.RFC3339), err)
+	if err := rq.persist(b); err != nil {
+		return fmt.Errorf("failed to persist batch %d: %w", b.Seq, err)
+	}
+	return nil
+}
+
+// drop takes a batch out of the queue, leaving its file alone. rq.mu must be held.
+func (rq *RetryQueue) drop(b *batch) {
+	rq.batches = slices.DeleteFunc(rq.batches, func(other *batch) bool { return other == b })
+}
+
+// PollInterval is how often Process should be called for batches to be retried as their backoff
+// expires, which is the shortest backoff.
+func (rq *RetryQueue) PollInterval() time.Duration {
+	return rq.backoffBase
+}
+
+// Len returns the number of batches waiting to be exported.
+func (rq *RetryQueue) Len() int {
+	rq.mu.Lock()
+	defer rq.mu.Unlock()
+	return len(rq.batches)
+}
+
+func (rq *RetryQueue) backoff(attempts int) time.Duration {
+	delay := rq.backoffBase
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= rq.backoffMax {
+			return rq.backoffMax
+		}
+	}
+	return delay
+}
+
+func (rq *RetryQueue) batchPath(b *batch) string {
+	return filepath.Join(rq.dir, strconv.FormatUint(b.Seq, 10)+batchFileSuffix)
+}
+
+// persist atomically writes a batch to disk by writing a temp file and renaming it into place.
+func (rq *RetryQueue) persist(b *batch) error {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(rq.dir, ".batch-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), rq.batchPath(b))
+}
+
+func (rq *RetryQueue) remove(b *batch) error {
+	if err := os.Remove(rq.batchPath(b)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove batch %d: %w", b.Seq, err)
+	}
+	return nil
+}
+
+// deadLetter appends a batch to the dead-letter file and removes it from the queue directory.
+func (rq *RetryQueue) deadLetter(b *batch, reason string) error {
+	data, err := json.Marshal(deadLetter{Reason: reason, batch: *b})
+	if err != nil {
+		return fmt.Errorf("failed to encode dead letter for batch %d: %w", b.Seq, err)
+	}
+
+	f, err := os.OpenFile(filepath.Join(rq.dir, deadLetterFileName), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open dead-letter file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write dead letter for batch %d: %w", b.Seq, err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync dead-letter file: %w", err)
+	}
+
+	return rq.remove(b)
+}
@@ -0,0 +1,199 @@
+package retry_queue
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/devon-caron/metrifuge/k8s/api"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestQueue(t *testing.T, dir string) *RetryQueue {
+	t.Helper()
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	rq := &RetryQueue{}
+	if err := rq.Initialize(dir, 10, 3, time.Millisecond, time.Second, log); err != nil {
+		t.Fatalf("Initialize() = %v", err)
+	}
+	return rq
+}
+
+func testItems(logs ...string) []api.ProcessedDataItem {
+	items := make([]api.ProcessedDataItem, len(logs))
+	for i, log := range logs {
+		items[i] = api.ProcessedDataItem{ForwardLog: log, LogSourceInfo: api.LogSourceInfo{Namespace: "default", Name: "app"}}
+	}
+	return items
+}
+
+func batchFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*"+batchFileSuffix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestEnqueueWritesAhead(t *testing.T) {
+	dir := t.TempDir()
+	rq := newTestQueue(t, dir)
+	if _, err := rq.Enqueue(testItems("first", "second")); err != nil {
+		t.Fatalf("Enqueue() = %v", err)
+	}
+	if files := batchFiles(t, dir); len(files) != 1 {
+		t.Fatalf("batch files = %v, want the batch on disk before its export", files)
+	}
+
+	// The process dies before the export is settled: the batch is exported on restart
+	restarted := newTestQueue(t, dir)
+	var exported []string
+	n, err := restarted.Process(context.Background(), func(_ context.Context, items []api.ProcessedDataItem) ([]api.ProcessedDataItem, error) {
+		for _, item := range items {
+			exported = append(exported, item.ForwardLog)
+		}
+		return nil, nil
+	})
+	if err != nil || n != 2 || len(exported) != 2 || exported[0] != "first" || exported[1] != "second" {
+		t.Errorf("Process() = %d, %v, exported %v, want [first second]", n, err, exported)
+	}
+	if files := batchFiles(t, dir); len(files) != 0 || restarted.Len() != 0 {
+		t.Errorf("batch files = %v, %d batches, want none once exported", files, restarted.Len())
+	}
+}
+
+func TestSettle(t *testing.T) {
+	dir := t.TempDir()
+	rq := newTestQueue(t, dir)
+
+	exported, err := rq.Enqueue(testItems("exported"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rq.Settle(exported, nil, nil); err != nil {
+		t.Fatalf("Settle() = %v", err)
+	}
+	if rq.Len() != 0 || len(batchFiles(t, dir)) != 0 {
+		t.Errorf("exported batch is still queued")
+	}
+
+	partial, err := rq.Enqueue(testItems("ok", "rejected"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rq.Settle(partial, testItems("rejected"), errors.New("unavailable")); err != nil {
+		t.Fatalf("Settle() = %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	var retried []string
+	if _, err := rq.Process(context.Background(), func(_ context.Context, items []api.ProcessedDataItem) ([]api.ProcessedDataItem, error) {
+		for _, item := range items {
+			retried = append(retried, item.ForwardLog)
+		}
+		return nil, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(retried) != 1 || retried[0] != "rejected" {
+		t.Errorf("retried %v, want only [rejected]", retried)
+	}
+
+	if err := rq.Settle(partial, nil, nil); err == nil {
+		t.Error("Settle() of a removed batch succeeded")
+	}
+}
+
+func TestProcessSkipsBatchesBeingExported(t *testing.T) {
+	rq := newTestQueue(t, t.TempDir())
+	if _, err := rq.Enqueue(testItems("in flight")); err != nil {
+		t.Fatal(err)
+	}
+	n, err := rq.Process(context.Background(), func(context.Context, []api.ProcessedDataItem) ([]api.ProcessedDataItem, error) {
+		t.Error("Process() exported a batch the pipeline is exporting")
+		return nil, nil
+	})
+	if err != nil || n != 0 {
+		t.Errorf("Process() = %d, %v, want nothing exported", n, err)
+	}
+}
+
+func TestEnqueueDoesNotBlockWhileProcessing(t *testing.T) {
+	dir := t.TempDir()
+	rq := newTestQueue(t, dir)
+	if _, err := rq.Enqueue(testItems("stuck")); err != nil {
+		t.Fatal(err)
+	}
+	// Reloading the queue makes the batch due, as it is after a restart
+	rq = newTestQueue(t, dir)
+
+	exporting := make(chan struct{})
+	release := make(chan struct{})
+	processed := make(chan error)
+	go func() {
+		_, err := rq.Process(context.Background(), func(context.Context, []api.ProcessedDataItem) ([]api.ProcessedDataItem, error) {
+			close(exporting)
+			<-release // a destination that is down, until the export times out
+			return nil, nil
+		})
+		processed <- err
+	}()
+	<-exporting
+
+	enqueued := make(chan error)
+	go func() {
+		_, err := rq.Enqueue(testItems("live"))
+		enqueued <- err
+	}()
+	select {
+	case err := <-enqueued:
+		if err != nil {
+			t.Errorf("Enqueue() = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("Enqueue() blocked while Process was exporting")
+	}
+
+	close(release)
+	if err := <-processed; err != nil {
+		t.Errorf("Process() = %v", err)
+	}
+	if rq.Len() != 1 {
+		t.Errorf("%d batches queued, want the live one", rq.Len())
+	}
+}
+
+func TestEnqueueDeadLettersOldestPendingBatch(t *testing.T) {
+	dir := t.TempDir()
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	rq := &RetryQueue{}
+	if err := rq.Initialize(dir, 1, 3, time.Millisecond, time.Second, log); err != nil {
+		t.Fatal(err)
+	}
+	first, err := rq.Enqueue(testItems("first"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A batch being exported is never dead-lettered to make room
+	if _, err := rq.Enqueue(testItems("second")); err != nil {
+		t.Fatal(err)
+	}
+	if rq.Len() != 2 {
+		t.Fatalf("%d batches queued, want both in flight", rq.Len())
+	}
+	if err := rq.Settle(first, testItems("first"), errors.New("unavailable")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rq.Enqueue(testItems("third")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, deadLetterFileName))
+	if err != nil || rq.Len() != 2 {
+		t.Errorf("dead letters %q (%v), %d batches queued, want the failed batch dead-lettered", data, err, rq.Len())
+	}
+}
@@ -0,0 +1,56 @@
+package exporter_manager
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/devon-caron/metrifuge/exporter_manager/circuit_breaker"
+	e "github.com/devon-caron/metrifuge/k8s/api/exporter"
+)
+
+// ValidateExporter checks what binding an exporter would check, without connecting to its
+// destination, so that an invalid Exporter can be rejected before it is ever applied.
+func ValidateExporter(exporter e.Exporter) error {
+	switch exporter.Spec.Type {
+	case "Metric", "Log", "Dual":
+	default:
+		return fmt.Errorf("unknown exporter type: %s", exporter.Spec.Type)
+	}
+	if exporter.Spec.LogSource.Name == "" && exporter.Spec.LogSourceSelector == nil {
+		return fmt.Errorf("exporter must specify a logSource or a logSourceSelector")
+	}
+
+	refreshInterval, err := time.ParseDuration(exporter.Spec.RefreshInterval)
+	if err != nil {
+		return fmt.Errorf("failed to parse refresh interval: %w", err)
+	}
+	if refreshInterval <= 0 {
+		return fmt.Errorf("refresh interval must be positive, got %s", exporter.Spec.RefreshInterval)
+	}
+	if _, err := circuit_breaker.ConfigFromSpec(exporter.Spec.CircuitBreaker); err != nil {
+		return err
+	}
+
+	destination := exporter.Spec.Destination
+	switch destination.Type {
+	case "OtelCollector":
+		if destination.OtelCollector == nil || destination.OtelCollector.Endpoint == "" {
+			return fmt.Errorf("otel collector endpoint is required")
+		}
+	case "Honeycomb":
+		if destination.Honeycomb == nil {
+			return fmt.Errorf("honeycomb configuration is required")
+		}
+		if destination.Honeycomb.APIKey == "" {
+			return fmt.Errorf("honeycomb API key is required")
+		}
+		if destination.Honeycomb.Dataset == "" {
+			return fmt.Errorf("honeycomb dataset is required")
+		}
+	case "Prometheus", "Elasticsearch", "Splunk", "Datadog", "Loki":
+		return fmt.Errorf("destination type %s is not supported yet", destination.Type)
+	default:
+		return fmt.Errorf("unknown destination type: %s", destination.Type)
+	}
+	return nil
+}
@@ -14,6 +14,26 @@ var (
 	DEFAULT_REFRESH_INTERVAL        = "60"
 	DEFAULT_LOG_SOURCE_RETRIES      = "5"
 	DEFAULT_LOG_SOURCE_DELAY        = "5"
+	DEFAULT_RETRY_QUEUE_DIR         = "/var/lib/metrifuge/retry-queue"
+	DEFAULT_RETRY_QUEUE_CAPACITY    = "1000"
+	DEFAULT_RETRY_MAX_ATTEMPTS      = "10"
+	DEFAULT_RETRY_BACKOFF_BASE      = "1s"
+	DEFAULT_RETRY_BACKOFF_MAX       = "5m"
+	DEFAULT_API_PORT                = "8080"
+	DEFAULT_SHUTDOWN_TIMEOUT        = "25s"
+	DEFAULT_PIPELINE_BUFFER_SIZE    = "1000"
+	DEFAULT_PIPELINE_PARSE_WORKERS  = "2"
+	DEFAULT_PIPELINE_EVAL_WORKERS   = "2" // at most MF_PIPELINE_PARSE_WORKERS, since each parse lane feeds one evaluation lane
+	DEFAULT_PIPELINE_EXPORT_WORKERS = "1"
+	DEFAULT_PIPELINE_BATCH_SIZE     = "100"
+	DEFAULT_PIPELINE_FLUSH_INTERVAL = "1s"
+	DEFAULT_SOURCE_PARALLELISM      = "1"
+	DEFAULT_METRIC_MAX_LATENESS     = "5m"
+	DEFAULT_WEBHOOK_ENABLED         = "false"
+	DEFAULT_WEBHOOK_PORT            = "8443"
+	DEFAULT_WEBHOOK_CERT_DIR        = "/etc/metrifuge/webhook-certs"
+	DEFAULT_API_VERSION             = "v1beta1"
+	DEFAULT_MIGRATE_STORAGE_VERSION = "false"
 )
 
 var (
@@ -26,6 +46,26 @@ var (
 	REFRESH_INTERVAL        = DEFAULT_REFRESH_INTERVAL
 	LOG_SOURCE_RETRIES      = DEFAULT_LOG_SOURCE_RETRIES
 	LOG_SOURCE_DELAY        = DEFAULT_LOG_SOURCE_DELAY
+	RETRY_QUEUE_DIR         = DEFAULT_RETRY_QUEUE_DIR
+	RETRY_QUEUE_CAPACITY    = DEFAULT_RETRY_QUEUE_CAPACITY
+	RETRY_MAX_ATTEMPTS      = DEFAULT_RETRY_MAX_ATTEMPTS
+	RETRY_BACKOFF_BASE      = DEFAULT_RETRY_BACKOFF_BASE
+	RETRY_BACKOFF_MAX       = DEFAULT_RETRY_BACKOFF_MAX
+	API_PORT                = DEFAULT_API_PORT
+	SHUTDOWN_TIMEOUT        = DEFAULT_SHUTDOWN_TIMEOUT
+	PIPELINE_BUFFER_SIZE    = DEFAULT_PIPELINE_BUFFER_SIZE
+	PIPELINE_PARSE_WORKERS  = DEFAULT_PIPELINE_PARSE_WORKERS
+	PIPELINE_EVAL_WORKERS   = DEFAULT_PIPELINE_EVAL_WORKERS
+	PIPELINE_EXPORT_WORKERS = DEFAULT_PIPELINE_EXPORT_WORKERS
+	PIPELINE_BATCH_SIZE     = DEFAULT_PIPELINE_BATCH_SIZE
+	PIPELINE_FLUSH_INTERVAL = DEFAULT_PIPELINE_FLUSH_INTERVAL
+	SOURCE_PARALLELISM      = DEFAULT_SOURCE_PARALLELISM
+	METRIC_MAX_LATENESS     = DEFAULT_METRIC_MAX_LATENESS
+	WEBHOOK_ENABLED         = DEFAULT_WEBHOOK_ENABLED
+	WEBHOOK_PORT            = DEFAULT_WEBHOOK_PORT
+	WEBHOOK_CERT_DIR        = DEFAULT_WEBHOOK_CERT_DIR
+	API_VERSION             = DEFAULT_API_VERSION
+	MIGRATE_STORAGE_VERSION = DEFAULT_MIGRATE_STORAGE_VERSION
 )
 
 func InitConfig() {
@@ -65,4 +105,84 @@ func InitConfig() {
 	if maybeLogSourceDelay != "" {
 		LOG_SOURCE_DELAY = maybeLogSourceDelay
 	}
+	maybeRetryQueueDir := os.Getenv("MF_RETRY_QUEUE_DIR")
+	if maybeRetryQueueDir != "" {
+		RETRY_QUEUE_DIR = maybeRetryQueueDir
+	}
+	maybeRetryQueueCapacity := os.Getenv("MF_RETRY_QUEUE_CAPACITY")
+	if maybeRetryQueueCapacity != "" {
+		RETRY_QUEUE_CAPACITY = maybeRetryQueueCapacity
+	}
+	maybeRetryMaxAttempts := os.Getenv("MF_RETRY_MAX_ATTEMPTS")
+	if maybeRetryMaxAttempts != "" {
+		RETRY_MAX_ATTEMPTS = maybeRetryMaxAttempts
+	}
+	maybeRetryBackoffBase := os.Getenv("MF_RETRY_BACKOFF_BASE")
+	if maybeRetryBackoffBase != "" {
+		RETRY_BACKOFF_BASE = maybeRetryBackoffBase
+	}
+	maybeRetryBackoffMax := os.Getenv("MF_RETRY_BACKOFF_MAX")
+	if maybeRetryBackoffMax != "" {
+		RETRY_BACKOFF_MAX = maybeRetryBackoffMax
+	}
+	maybeApiPort := os.Getenv("MF_API_PORT")
+	if maybeApiPort != "" {
+		API_PORT = maybeApiPort
+	}
+	maybeShutdownTimeout := os.Getenv("MF_SHUTDOWN_TIMEOUT")
+	if maybeShutdownTimeout != "" {
+		SHUTDOWN_TIMEOUT = maybeShutdownTimeout
+	}
+	maybePipelineBufferSize := os.Getenv("MF_PIPELINE_BUFFER_SIZE")
+	if maybePipelineBufferSize != "" {
+		PIPELINE_BUFFER_SIZE = maybePipelineBufferSize
+	}
+	maybePipelineParseWorkers := os.Getenv("MF_PIPELINE_PARSE_WORKERS")
+	if maybePipelineParseWorkers != "" {
+		PIPELINE_PARSE_WORKERS = maybePipelineParseWorkers
+	}
+	maybePipelineEvalWorkers := os.Getenv("MF_PIPELINE_EVAL_WORKERS")
+	if maybePipelineEvalWorkers != "" {
+		PIPELINE_EVAL_WORKERS = maybePipelineEvalWorkers
+	}
+	maybePipelineExportWorkers := os.Getenv("MF_PIPELINE_EXPORT_WORKERS")
+	if maybePipelineExportWorkers != "" {
+		PIPELINE_EXPORT_WORKERS = maybePipelineExportWorkers
+	}
+	maybePipelineBatchSize := os.Getenv("MF_PIPELINE_BATCH_SIZE")
+	if maybePipelineBatchSize != "" {
+		PIPELINE_BATCH_SIZE = maybePipelineBatchSize
+	}
+	maybePipelineFlushInterval := os.Getenv("MF_PIPELINE_FLUSH_INTERVAL")
+	if maybePipelineFlushInterval != "" {
+		PIPELINE_FLUSH_INTERVAL = maybePipelineFlushInterval
+	}
+	maybeSourceParallelism := os.Getenv("MF_SOURCE_PARALLELISM")
+	if maybeSourceParallelism != "" {
+		SOURCE_PARALLELISM = maybeSourceParallelism
+	}
+	maybeMetricMaxLateness := os.Getenv("MF_METRIC_MAX_LATENESS")
+	if maybeMetricMaxLateness != "" {
+		METRIC_MAX_LATENESS = maybeMetricMaxLateness
+	}
+	maybeWebhookEnabled := os.Getenv("MF_WEBHOOK_ENABLED")
+	if maybeWebhookEnabled != "" {
+		WEBHOOK_ENABLED = maybeWebhookEnabled
+	}
+	maybeWebhookPort := os.Getenv("MF_WEBHOOK_PORT")
+	if maybeWebhookPort != "" {
+		WEBHOOK_PORT = maybeWebhookPort
+	}
+	maybeWebhookCertDir := os.Getenv("MF_WEBHOOK_CERT_DIR")
+	if maybeWebhookCertDir != "" {
+		WEBHOOK_CERT_DIR = maybeWebhookCertDir
+	}
+	maybeApiVersion := os.Getenv("MF_API_VERSION")
+	if maybeApiVersion != "" {
+		API_VERSION = maybeApiVersion
+	}
+	maybeMigrateStorageVersion := os.Getenv("MF_MIGRATE_STORAGE_VERSION")
+	if maybeMigrateStorageVersion != "" {
+		MIGRATE_STORAGE_VERSION = maybeMigrateStorageVersion
+	}
 }
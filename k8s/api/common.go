@@ -1,6 +1,11 @@
 package api
 
-import "go.opentelemetry.io/otel/attribute"
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/log"
+)
 
 type MetrifugeK8sResource interface {
 	GetMetadata() Metadata
@@ -11,19 +16,35 @@ type Metadata struct {
 	Name      string            `json:"name" yaml:"name"`
 	Namespace string            `json:"namespace" yaml:"namespace"`
 	Labels    map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	// Generation is only set for resources read from the cluster, and changes with their spec
+	Generation int64 `json:"generation,omitempty" yaml:"generation,omitempty"`
+}
+
+// ConfigMapRef points at a ConfigMap. An empty namespace means the namespace of the referencing resource.
+type ConfigMapRef struct {
+	Name      string `json:"name" yaml:"name"`
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"` // namespace of the referencing resource if empty
 }
 
 // Selector defines how to select resources
 type Selector struct {
-	MatchLabels map[string]string `json:"matchLabels,omitempty" yaml:"matchLabels,omitempty"`
+	MatchLabels map[string]string `json:"matchLabels,omitempty" yaml:"matchLabels,omitempty"` // labels a resource must have to be selected
 }
 
+// +k8s:deepcopy-gen=false
 type ProcessedDataItem struct {
-	ForwardLog    string
-	Metric        *MetricData
-	LogSourceInfo LogSourceInfo
+	ForwardLog        string
+	Metric            *MetricData
+	LogSourceInfo     LogSourceInfo
+	Timestamp         time.Time // when the event happened, zero if its rule doesn't say
+	ObservedTimestamp time.Time // when the line was read
+	Severity          log.Severity
+	SeverityText      string // level the severity was found from, if any
+	LogAttributes     []attribute.KeyValue
+	Exporters         []string // if set, the only exporters the log is exported to, such as those that failed to export it before
 }
 
+// +k8s:deepcopy-gen=false
 type MetricData struct {
 	Name       string
 	Kind       string
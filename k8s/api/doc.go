@@ -0,0 +1,4 @@
+// Package api contains the types the specs of metrifuge's custom resources are made of.
+//
+// +k8s:deepcopy-gen=package
+package api
@@ -11,12 +11,24 @@ type Exporter struct {
 	Spec       ExporterSpec `json:"spec" yaml:"spec"`
 }
 
+// +k8s:deepcopy-gen=true
+
+// +kubebuilder:validation:XValidation:rule="has(self.logSource) || has(self.logSourceSelector)",message="either logSource or logSourceSelector must be set"
 type ExporterSpec struct {
+	// Type of the items the exporter exports
+	// +kubebuilder:validation:Enum=Metric;Log;Dual
 	Type string `json:"type" yaml:"type"`
 	//Priority        int                     `json:"priority" yaml:"priority"` // Must be a value between 1-20 ( = number of allocated exporter resources)
+
+	// RefreshInterval is how often the exporter exports, as a Go duration
+	// +kubebuilder:validation:Pattern=`^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
 	RefreshInterval string                  `json:"refreshInterval" yaml:"refreshInterval"`
 	Destination     api.ExporterDestination `json:"destination" yaml:"destination"`
-	LogSource       api.LogSourceInfo       `json:"logSource" yaml:"logSource"`
+	LogSource       api.LogSourceInfo       `json:"logSource,omitempty" yaml:"logSource,omitempty"` // log source the exporter receives items from
+	// LogSourceSelector matches log sources by label, in addition to LogSource
+	LogSourceSelector *api.Selector `json:"logSourceSelector,omitempty" yaml:"logSourceSelector,omitempty"`
+	// CircuitBreaker sets the thresholds of the circuit breaker guarding the destination
+	CircuitBreaker *api.CircuitBreakerConfig `json:"circuitBreaker,omitempty" yaml:"circuitBreaker,omitempty"`
 }
 
 func (e Exporter) GetMetadata() api.Metadata {
@@ -34,3 +46,16 @@ func (e *Exporter) GetDestinationConfig() any {
 func (e *Exporter) GetLogSourceInfo() api.LogSourceInfo {
 	return e.Spec.LogSource
 }
+
+// SelectsLogSource reports whether the exporter should receive items from the log source
+// with the given metadata, either through its logSource reference or its logSourceSelector.
+func (e *Exporter) SelectsLogSource(metadata api.Metadata) bool {
+	if e.Spec.LogSource.Name != "" &&
+		e.Spec.LogSource.Name == metadata.Name && e.Spec.LogSource.Namespace == metadata.Namespace {
+		return true
+	}
+	if e.Spec.LogSourceSelector == nil {
+		return false
+	}
+	return api.MatchLabels(e.Spec.LogSourceSelector.MatchLabels, metadata.Labels)
+}
@@ -1,6 +1,9 @@
 package api
 
+// ExporterDestination is where an exporter sends its items, configured by the field of its type
 type ExporterDestination struct {
+	// Type of the destination
+	// +kubebuilder:validation:Enum=Honeycomb;Prometheus;Elasticsearch;Splunk;Datadog;Loki;OtelCollector
 	Type          string               `json:"type" yaml:"type"`
 	Honeycomb     *HoneycombConfig     `json:"honeycomb,omitempty" yaml:"honeycomb,omitempty"`
 	Prometheus    *PrometheusConfig    `json:"prometheus,omitempty" yaml:"prometheus,omitempty"`
@@ -63,6 +66,19 @@ type OtelCollectorConfig struct {
 	Insecure bool   `json:"insecure,omitempty" yaml:"insecure,omitempty"`
 }
 
+// CircuitBreakerConfig contains the thresholds of the circuit breaker guarding an exporter's destination
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the number of consecutive export failures before the breaker opens, 5 if empty
+	// +kubebuilder:validation:Minimum=1
+	FailureThreshold int `json:"failureThreshold,omitempty" yaml:"failureThreshold,omitempty"`
+	// SuccessThreshold is the number of consecutive half-open successes before the breaker closes, 1 if empty
+	// +kubebuilder:validation:Minimum=1
+	SuccessThreshold int `json:"successThreshold,omitempty" yaml:"successThreshold,omitempty"`
+	// OpenDuration is how long the breaker stays open before probing the destination, 30s if empty
+	// +kubebuilder:validation:Pattern=`^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+	OpenDuration string `json:"openDuration,omitempty" yaml:"openDuration,omitempty"`
+}
+
 type LogSourceInfo struct {
 	Name      string `json:"name" yaml:"name"`
 	Namespace string `json:"namespace" yaml:"namespace"`
@@ -0,0 +1,5 @@
+package api
+
+// The spec schemas of the CRDs in k8s/crds are generated from the types of this package and its
+// subpackages, and must be regenerated whenever they change.
+//go:generate go run ../crdgen -root ../..
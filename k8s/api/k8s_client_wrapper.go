@@ -7,6 +7,7 @@ import (
 	"k8s.io/client-go/rest"
 )
 
+// +k8s:deepcopy-gen=false
 type K8sClientWrapper struct {
 	clientset *kubernetes.Clientset
 	config    *rest.Config
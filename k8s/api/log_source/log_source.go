@@ -1,11 +1,13 @@
 package log_source
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/devon-caron/metrifuge/k8s/api"
 )
 
 // LogSource represents a configuration for getting logs from a source
-// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 type LogSource struct {
 	APIVersion string        `json:"apiVersion" yaml:"apiVersion"`
 	Kind       string        `json:"kind" yaml:"kind"`
@@ -13,12 +15,40 @@ type LogSource struct {
 	Spec       LogSourceSpec `json:"spec" yaml:"spec"`
 }
 
+// +k8s:deepcopy-gen=true
+
 // LogSourceSpec contains the log source configuration
 type LogSourceSpec struct {
-	Type   string         `json:"type" yaml:"type"`
+	Type   string         `json:"type,omitempty" yaml:"type,omitempty"` // type of the source, set from Source.Type when the LogSource is read
 	Source api.SourceSpec `json:"source" yaml:"source"`
+	// Parallelism is how many lines of the source may be evaluated at once, up to the number of
+	// pipeline workers. Zero uses MF_SOURCE_PARALLELISM. Sources with ordered rules always use 1.
+	// +kubebuilder:validation:Minimum=1
+	Parallelism int `json:"parallelism,omitempty" yaml:"parallelism,omitempty"`
 }
 
 func (ls LogSource) GetMetadata() api.Metadata {
 	return ls.Metadata
 }
+
+// GetSource returns the source of the spec's type, or an error if it isn't configured.
+func (s LogSourceSpec) GetSource() (api.Source, error) {
+	var source api.Source
+	var configured bool
+	switch s.Type {
+	case "PVCSource":
+		source, configured = s.Source.PVCSource, s.Source.PVCSource != nil
+	case "PodSource":
+		source, configured = s.Source.PodSource, s.Source.PodSource != nil
+	case "LocalSource":
+		source, configured = s.Source.LocalSource, s.Source.LocalSource != nil
+	case "CmdSource":
+		source, configured = s.Source.CmdSource, s.Source.CmdSource != nil
+	default:
+		return nil, fmt.Errorf("unknown log source type: %s", s.Type)
+	}
+	if !configured {
+		return nil, fmt.Errorf("log source type is %s but no %s%s is set", s.Type, strings.ToLower(s.Type[:1]), s.Type[1:])
+	}
+	return source, nil
+}
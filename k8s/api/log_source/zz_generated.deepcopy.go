@@ -0,0 +1,23 @@
+//go:build !ignore_autogenerated
+// +build !ignore_autogenerated
+
+// Code generated by deepcopy-gen. DO NOT EDIT.
+
+package log_source
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LogSourceSpec) DeepCopyInto(out *LogSourceSpec) {
+	*out = *in
+	in.Source.DeepCopyInto(&out.Source)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LogSourceSpec.
+func (in *LogSourceSpec) DeepCopy() *LogSourceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LogSourceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
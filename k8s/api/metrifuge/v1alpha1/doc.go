@@ -0,0 +1,7 @@
+// Package v1alpha1 contains the v1alpha1 API of the metrifuge.com group, in the form the typed
+// clientset, listers and informers in k8s/client are generated from. The specs are the ones
+// metrifuge works with, the statuses are what metrifuge reports about each resource.
+//
+// +k8s:deepcopy-gen=package
+// +groupName=metrifuge.com
+package v1alpha1
@@ -0,0 +1,118 @@
+package v1alpha1
+
+import (
+	e "github.com/devon-caron/metrifuge/k8s/api/exporter"
+	ls "github.com/devon-caron/metrifuge/k8s/api/log_source"
+	rs "github.com/devon-caron/metrifuge/k8s/api/ruleset"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// RuleSet is a set of rules that turn the lines of the log sources it selects into metrics and logs
+type RuleSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   rs.RuleSetSpec `json:"spec"`
+	Status RuleSetStatus  `json:"status,omitempty"`
+}
+
+// RuleSetStatus is what metrifuge observed applying the rules of a RuleSet
+type RuleSetStatus struct {
+	Conditions   []metav1.Condition `json:"conditions,omitempty"`
+	RulesMatched int64              `json:"rulesMatched,omitempty"`
+	LastError    string             `json:"lastError,omitempty"`
+	RuleErrors   map[string]int64   `json:"ruleErrors,omitempty"`   // lines each rule failed on, by rule name or index
+	ErrorSamples []RuleErrorSample  `json:"errorSamples,omitempty"` // most recent lines the rules failed on, oldest first
+}
+
+// RuleErrorSample is a line a rule failed on
+type RuleErrorSample struct {
+	Rule  string      `json:"rule"`
+	Stage string      `json:"stage"` // Parse, Transform or Evaluate
+	Line  string      `json:"line"`  // truncated
+	Error string      `json:"error"`
+	Time  metav1.Time `json:"time"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// RuleSetList is a list of RuleSets
+type RuleSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []RuleSet `json:"items"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// LogSource is a source of log lines, such as the logs of a container
+type LogSource struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ls.LogSourceSpec `json:"spec"`
+	Status LogSourceStatus  `json:"status,omitempty"`
+}
+
+// LogSourceStatus is what metrifuge observed reading a LogSource
+type LogSourceStatus struct {
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	LinesRead  int64              `json:"linesRead,omitempty"`
+	LateEvents int64              `json:"lateEvents,omitempty"` // events whose metrics were dropped for being too old
+	LastError  string             `json:"lastError,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// LogSourceList is a list of LogSources
+type LogSourceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []LogSource `json:"items"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Exporter sends the metrics and logs produced from the log sources it selects to a destination
+type Exporter struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   e.ExporterSpec `json:"spec"`
+	Status ExporterStatus `json:"status,omitempty"`
+}
+
+// ExporterStatus is what metrifuge observed exporting through an Exporter
+type ExporterStatus struct {
+	Conditions     []metav1.Condition    `json:"conditions,omitempty"`
+	CircuitBreaker *CircuitBreakerStatus `json:"circuitBreaker,omitempty"`
+	ItemsExported  int64                 `json:"itemsExported,omitempty"`
+	LastError      string                `json:"lastError,omitempty"`
+}
+
+// CircuitBreakerStatus is the state of the circuit breaker guarding an Exporter's destination
+type CircuitBreakerStatus struct {
+	Name                string      `json:"name"`
+	State               string      `json:"state"` // Closed, Open or HalfOpen
+	ConsecutiveFailures int         `json:"consecutiveFailures"`
+	LastError           string      `json:"lastError,omitempty"`
+	LastTransitionTime  metav1.Time `json:"lastTransitionTime"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ExporterList is a list of Exporters
+type ExporterList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []Exporter `json:"items"`
+}
@@ -0,0 +1,295 @@
+package v1beta1
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/devon-caron/metrifuge/k8s/api"
+	rs "github.com/devon-caron/metrifuge/k8s/api/ruleset"
+)
+
+// ConditionsAnnotation holds the conditions of the conditionals of a RuleSet converted from
+// v1alpha1, so that converting it back gives the conditions it was written with rather than the
+// expressions they were converted to.
+const ConditionsAnnotation = "metrifuge.com/v1alpha1-conditions"
+
+// savedConditions is the condition of a v1alpha1 conditional and those of the conditionals nested in it
+type savedConditions struct {
+	Condition api.Condition    `json:"condition"`
+	True      *savedConditions `json:"true,omitempty"`
+	False     *savedConditions `json:"false,omitempty"`
+}
+
+// FromRuleSetSpec converts the spec of a v1alpha1 RuleSet, which is the one metrifuge works with.
+// The conditions of its conditionals become CEL expressions, see ConditionExpression.
+func FromRuleSetSpec(in *rs.RuleSetSpec) *RuleSetSpec {
+	in = in.DeepCopy()
+	out := &RuleSetSpec{
+		Selector:           in.Selector,
+		Evaluation:         in.Evaluation,
+		PatternDefinitions: in.PatternDefinitions,
+		PatternConfigMap:   in.PatternConfigMap,
+	}
+	for _, rule := range in.Rules {
+		if rule == nil {
+			out.Rules = append(out.Rules, nil)
+			continue
+		}
+		out.Rules = append(out.Rules, &Rule{
+			Name:          rule.Name,
+			Description:   rule.Description,
+			Pattern:       rule.Pattern,
+			Contains:      rule.Contains,
+			Action:        rule.Action,
+			Conditional:   fromConditional(rule.Conditional),
+			CreateMetrics: rule.CreateMetrics,
+			Metrics:       rule.Metrics,
+			Ordered:       rule.Ordered,
+			Transforms:    rule.Transforms,
+			Timestamp:     rule.Timestamp,
+			Severity:      rule.Severity,
+			LogAttributes: rule.LogAttributes,
+			Rewrite:       rule.Rewrite,
+			OnError:       rule.OnError,
+		})
+	}
+	return out
+}
+
+func fromConditional(in *api.Conditional) *Conditional {
+	if in == nil {
+		return nil
+	}
+	return &Conditional{
+		When: ConditionExpression(&in.Condition),
+		Then: Branch{Action: in.ActionTrue, Metrics: in.MetricsTrue, Conditional: fromConditional(in.ConditionalTrue)},
+		Else: Branch{Action: in.ActionFalse, Metrics: in.MetricsFalse, Conditional: fromConditional(in.ConditionalFalse)},
+	}
+}
+
+// ToRuleSetSpec converts the spec of a RuleSet to v1alpha1, which is the one metrifuge works with.
+// The expressions of its conditionals become expression conditions.
+func ToRuleSetSpec(in *RuleSetSpec) *rs.RuleSetSpec {
+	in = in.DeepCopy()
+	out := &rs.RuleSetSpec{
+		Selector:           in.Selector,
+		Evaluation:         in.Evaluation,
+		PatternDefinitions: in.PatternDefinitions,
+		PatternConfigMap:   in.PatternConfigMap,
+	}
+	for _, rule := range in.Rules {
+		if rule == nil {
+			out.Rules = append(out.Rules, nil)
+			continue
+		}
+		out.Rules = append(out.Rules, &api.Rule{
+			Name:          rule.Name,
+			Description:   rule.Description,
+			Pattern:       rule.Pattern,
+			Contains:      rule.Contains,
+			Action:        rule.Action,
+			Conditional:   toConditional(rule.Conditional),
+			CreateMetrics: rule.CreateMetrics,
+			Metrics:       rule.Metrics,
+			Ordered:       rule.Ordered,
+			Transforms:    rule.Transforms,
+			Timestamp:     rule.Timestamp,
+			Severity:      rule.Severity,
+			LogAttributes: rule.LogAttributes,
+			Rewrite:       rule.Rewrite,
+			OnError:       rule.OnError,
+		})
+	}
+	return out
+}
+
+func toConditional(in *Conditional) *api.Conditional {
+	if in == nil {
+		return nil
+	}
+	return &api.Conditional{
+		Condition:        api.Condition{Expression: in.When},
+		ActionTrue:       in.Then.Action,
+		ActionFalse:      in.Else.Action,
+		MetricsTrue:      in.Then.Metrics,
+		MetricsFalse:     in.Else.Metrics,
+		ConditionalTrue:  toConditional(in.Then.Conditional),
+		ConditionalFalse: toConditional(in.Else.Conditional),
+	}
+}
+
+// SaveConditions encodes the conditions of the conditionals of a v1alpha1 RuleSet spec for
+// ConditionsAnnotation. It returns an empty string if every condition is an expression already,
+// since those convert back as they were.
+func SaveConditions(spec *rs.RuleSetSpec) (string, error) {
+	saved := make([]*savedConditions, len(spec.Rules))
+	needed := false
+	for i, rule := range spec.Rules {
+		if rule != nil && rule.Conditional != nil {
+			saved[i] = saveConditions(rule.Conditional)
+			needed = needed || !expressionsOnly(rule.Conditional)
+		}
+	}
+	if !needed {
+		return "", nil
+	}
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode conditions: %v", err)
+	}
+	return string(data), nil
+}
+
+func saveConditions(c *api.Conditional) *savedConditions {
+	if c == nil {
+		return nil
+	}
+	return &savedConditions{Condition: c.Condition, True: saveConditions(c.ConditionalTrue), False: saveConditions(c.ConditionalFalse)}
+}
+
+func expressionsOnly(c *api.Conditional) bool {
+	if c == nil {
+		return true
+	}
+	return reflect.DeepEqual(c.Condition, api.Condition{Expression: c.Expression}) &&
+		expressionsOnly(c.ConditionalTrue) && expressionsOnly(c.ConditionalFalse)
+}
+
+// RestoreConditions puts back the conditions saved by SaveConditions into a v1alpha1 RuleSet spec
+// converted by ToRuleSetSpec. Only the conditions whose expression is still the one they were
+// converted to are restored, those that were changed since are kept as the new expression.
+func RestoreConditions(spec *rs.RuleSetSpec, annotation string) error {
+	var saved []*savedConditions
+	if err := json.Unmarshal([]byte(annotation), &saved); err != nil {
+		return fmt.Errorf("failed to decode %s annotation: %v", ConditionsAnnotation, err)
+	}
+	for i, rule := range spec.Rules {
+		if i < len(saved) && rule != nil {
+			restoreConditions(rule.Conditional, saved[i])
+		}
+	}
+	return nil
+}
+
+func restoreConditions(c *api.Conditional, saved *savedConditions) {
+	if c == nil || saved == nil {
+		return
+	}
+	if c.Expression == ConditionExpression(&saved.Condition) {
+		c.Condition = saved.Condition
+	}
+	restoreConditions(c.ConditionalTrue, saved.True)
+	restoreConditions(c.ConditionalFalse, saved.False)
+}
+
+// Plain decimal numbers, written as CEL double literals
+var decimal = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// ConditionExpression converts a v1alpha1 condition to a CEL expression on the fields of a line.
+// Fields are compared the way expressions see them, numbers as numbers, and comparing a field
+// the line doesn't have is an error rather than a comparison with an empty value. Conditions
+// metrifuge would reject convert to an empty expression, which it rejects as well.
+func ConditionExpression(c *api.Condition) string {
+	switch {
+	case len(c.All) > 0:
+		return joinConditions(c.All, " && ")
+	case len(c.Any) > 0:
+		return joinConditions(c.Any, " || ")
+	case c.Not != nil:
+		return "!(" + ConditionExpression(c.Not) + ")"
+	case c.Expression != "":
+		return c.Expression
+	}
+
+	field1, field2 := stringOperand(c.Field1), stringOperand(c.Field2)
+	switch c.Operator {
+	case "Equals", "DoesNotEqual":
+		if numeric(c.Field1.Type) && numeric(c.Field2.Type) {
+			field1, field2 = numberOperand(c.Field1), numberOperand(c.Field2)
+		}
+		if c.Operator == "Equals" {
+			return field1 + " == " + field2
+		}
+		return field1 + " != " + field2
+	case "Exists", "DoesNotExist":
+		exists := field1 + ` != ""`
+		if c.Field1.GrokKey != "" {
+			key := strconv.Quote(c.Field1.GrokKey)
+			exists = fmt.Sprintf(`%s in raw && raw[%s] != ""`, key, key)
+		}
+		if c.Operator == "Exists" {
+			return exists
+		}
+		return "!(" + exists + ")"
+	case "Contains":
+		return field1 + ".contains(" + field2 + ")"
+	case "StartsWith":
+		return field1 + ".startsWith(" + field2 + ")"
+	case "EndsWith":
+		return field1 + ".endsWith(" + field2 + ")"
+	case "Matches":
+		return field1 + ".matches(" + field2 + ")"
+	case "In", "NotIn":
+		values := make([]string, len(c.Values))
+		for i, value := range c.Values {
+			values[i] = strconv.Quote(value)
+		}
+		in := field1 + " in [" + strings.Join(values, ", ") + "]"
+		if c.Operator == "In" {
+			return in
+		}
+		return "!(" + in + ")"
+	case "LessThan":
+		return numberOperand(c.Field1) + " < " + numberOperand(c.Field2)
+	case "GreaterThan":
+		return numberOperand(c.Field1) + " > " + numberOperand(c.Field2)
+	case "LessThanOrEqualTo":
+		return numberOperand(c.Field1) + " <= " + numberOperand(c.Field2)
+	case "GreaterThanOrEqualTo":
+		return numberOperand(c.Field1) + " >= " + numberOperand(c.Field2)
+	}
+	return ""
+}
+
+func joinConditions(conditions []api.Condition, operator string) string {
+	expressions := make([]string, len(conditions))
+	for i := range conditions {
+		expressions[i] = "(" + ConditionExpression(&conditions[i]) + ")"
+	}
+	return strings.Join(expressions, operator)
+}
+
+func numeric(valueType string) bool {
+	switch strings.ToLower(valueType) {
+	case "int64", "float64":
+		return true
+	}
+	return false
+}
+
+// stringOperand writes a field value of a condition as a CEL string. Fields are taken as they were
+// captured, so that "007" is not compared as "7".
+func stringOperand(fv api.FieldValue) string {
+	if fv.GrokKey != "" {
+		return "raw[" + strconv.Quote(fv.GrokKey) + "]"
+	}
+	return strconv.Quote(fv.ManualValue)
+}
+
+// numberOperand writes a field value of a condition as a CEL double.
+func numberOperand(fv api.FieldValue) string {
+	if fv.GrokKey != "" {
+		return "double(fields[" + strconv.Quote(fv.GrokKey) + "])"
+	}
+	if decimal.MatchString(fv.ManualValue) {
+		if strings.Contains(fv.ManualValue, ".") {
+			return fv.ManualValue
+		}
+		return fv.ManualValue + ".0"
+	}
+	return "double(" + strconv.Quote(fv.ManualValue) + ")"
+}
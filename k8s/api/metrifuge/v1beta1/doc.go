@@ -0,0 +1,8 @@
+// Package v1beta1 contains the v1beta1 API of the metrifuge.com group, which is its storage
+// version. It differs from v1alpha1 in the conditionals of rules only, which decide on a CEL
+// expression instead of a condition, and which RuleSets are converted to and from by the
+// conversion webhook. LogSources and Exporters are the same in both versions.
+//
+// +k8s:deepcopy-gen=package
+// +groupName=metrifuge.com
+package v1beta1
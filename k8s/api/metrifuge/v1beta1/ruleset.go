@@ -0,0 +1,77 @@
+package v1beta1
+
+import (
+	"github.com/devon-caron/metrifuge/k8s/api"
+)
+
+// RuleSetSpec contains the rules of a RuleSet and the log sources they apply to
+type RuleSetSpec struct {
+	// Selector matches the log sources the rules apply to
+	// +required
+	Selector *api.Selector `json:"selector,omitempty"`
+	Rules    []*Rule       `json:"rules"` // applied to every line of the selected log sources
+	// Evaluation is whether every matching rule applies to a line, or only the first one
+	// +kubebuilder:validation:Enum=allMatch;firstMatch
+	// +kubebuilder:default=allMatch
+	Evaluation string `json:"evaluation,omitempty"`
+	// PatternDefinitions are named grok patterns the rules can use, on top of the built-in ones and
+	// those of PatternConfigMap. They take precedence over both.
+	PatternDefinitions map[string]string `json:"patternDefinitions,omitempty"`
+	// PatternConfigMap holds shared grok pattern files. Every entry is a pattern file with one "NAME expression" definition per line. It is read when RuleSets are reloaded, which a change to a RuleSet or LogSource triggers, so edits to it take effect once the RuleSet is touched
+	PatternConfigMap *api.ConfigMapRef `json:"patternConfigMap,omitempty"`
+}
+
+// Rule is a rule of a RuleSet. It is the rule of v1alpha1 but for its conditional.
+type Rule struct {
+	// Name identifies the rule in the RuleSet's status, its index if empty
+	Name string `json:"name,omitempty"`
+	// Description says what the rule is for
+	Description string `json:"description,omitempty"`
+	// Pattern is the grok pattern matched against log lines
+	Pattern string `json:"pattern"`
+	// Contains is a literal that a line must contain for the pattern to be tried, so that most lines skip the regex engine
+	Contains string `json:"contains,omitempty"`
+	// Action is what to do with matching lines
+	// +kubebuilder:validation:Enum=Forward;Discard;Conditional
+	Action string `json:"action"`
+	// Conditional decides the action and metrics of a line from its fields, for the Conditional action
+	Conditional *Conditional `json:"conditional,omitempty"`
+	// CreateMetrics is whether to create metrics for the rule
+	CreateMetrics bool `json:"createMetrics,omitempty"`
+	// Metrics are emitted for every matching line
+	Metrics []api.MetricTemplate `json:"metrics,omitempty"`
+	// Ordered is whether the rule needs lines in the order they were read, such as multiline or stateful rules. Log sources with ordered rules are evaluated one line at a time
+	Ordered bool `json:"ordered,omitempty"`
+	// Transforms are applied in order to the captured fields once the pattern matched. Metrics, attributes and conditionals can use the fields they derive
+	Transforms []api.Transform `json:"transforms,omitempty"`
+	// Timestamp is the field holding the time the event happened. Forwarded logs carry it as their timestamp. Metrics are recorded when the line is exported, so metrics of events older than MF_METRIC_MAX_LATENESS by then are dropped instead of being counted in the wrong interval, except when retried after a failed export
+	Timestamp *api.EventTimestamp `json:"timestamp,omitempty"`
+	// Severity is the severity of the logs the rule forwards. Logs without a severity are forwarded as INFO
+	Severity *api.LogSeverity `json:"severity,omitempty"`
+	// LogAttributes are the captured and derived fields attached to the logs the rule forwards as attributes
+	LogAttributes *api.LogAttributes `json:"logAttributes,omitempty"`
+	// Rewrite rewrites the body of the logs the rule forwards so that sensitive data doesn't leave the cluster
+	Rewrite *api.Rewrite `json:"rewrite,omitempty"`
+	// OnError is what to do with a line the rule fails to parse, transform or evaluate. Skip leaves the rule out for the line, ForwardRaw forwards the line as it was read, with the rule's redaction applied, and DiscardLine drops everything every rule produced for the line, whatever the order of the rules.
+	// +kubebuilder:validation:Enum=Skip;ForwardRaw;DiscardLine
+	// +kubebuilder:default=Skip
+	OnError string `json:"onError,omitempty"`
+}
+
+// Conditional takes one of two branches for a line, depending on whether a CEL expression on its
+// fields holds.
+type Conditional struct {
+	// When is a CEL expression on the fields of the line, such as status >= 500 && path.startsWith("/api"). Fields whose names aren't identifiers are in fields, as in fields["http.status"]. Fields that look like numbers are numbers, raw has every field as a string, as in raw["zip"] == "01234"
+	When string `json:"when"`
+	Then Branch `json:"then"` // taken if When holds
+	Else Branch `json:"else"` // taken if When doesn't hold
+}
+
+// Branch is the action and metrics of the lines that take one branch of a Conditional
+type Branch struct {
+	// Action is what to do with the line
+	// +kubebuilder:validation:Enum=Forward;Discard;Conditional
+	Action      string               `json:"action"`
+	Metrics     []api.MetricTemplate `json:"metrics,omitempty"`     // emitted for the line
+	Conditional *Conditional         `json:"conditional,omitempty"` // next conditional, if Action is Conditional
+}
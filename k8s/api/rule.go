@@ -1,46 +1,163 @@
 package api
 
 type Rule struct {
-	Pattern       string           `json:"pattern" yaml:"pattern"`
-	Action        string           `json:"action" yaml:"action"` // forward, discard, conditional
-	Conditional   *Conditional     `json:"conditional,omitempty" yaml:"conditional,omitempty"`
-	CreateMetrics bool             `json:"create_metrics,omitempty" yaml:"create_metrics,omitempty"`
-	Metrics       []MetricTemplate `json:"metrics,omitempty" yaml:"metrics,omitempty"`
+	// Name identifies the rule in the RuleSet's status, its index if empty
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Description says what the rule is for
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Pattern is the grok pattern matched against log lines
+	Pattern string `json:"pattern" yaml:"pattern"`
+	// Contains is a literal that a line must contain for the pattern to be tried, so that most lines skip the regex engine
+	Contains string `json:"contains,omitempty" yaml:"contains,omitempty"`
+	// Action is what to do with matching lines
+	// +kubebuilder:validation:Enum=Forward;Discard;Conditional
+	Action string `json:"action" yaml:"action"`
+	// Conditional decides the action and metrics of a line from its fields, for the Conditional action
+	Conditional *Conditional `json:"conditional,omitempty" yaml:"conditional,omitempty"`
+	// CreateMetrics is whether to create metrics for the rule
+	CreateMetrics bool `json:"createMetrics,omitempty" yaml:"createMetrics,omitempty"`
+	// Metrics are emitted for every matching line
+	Metrics []MetricTemplate `json:"metrics,omitempty" yaml:"metrics,omitempty"`
+	// Ordered is whether the rule needs lines in the order they were read, such as multiline or stateful rules. Log sources with ordered rules are evaluated one line at a time
+	Ordered bool `json:"ordered,omitempty" yaml:"ordered,omitempty"`
+	// Transforms are applied in order to the captured fields once the pattern matched. Metrics, attributes and conditionals can use the fields they derive
+	Transforms []Transform `json:"transforms,omitempty" yaml:"transforms,omitempty"`
+	// Timestamp is the field holding the time the event happened. Forwarded logs carry it as their timestamp. Metrics are recorded when the line is exported, so metrics of events older than MF_METRIC_MAX_LATENESS by then are dropped instead of being counted in the wrong interval, except when retried after a failed export
+	Timestamp *EventTimestamp `json:"timestamp,omitempty" yaml:"timestamp,omitempty"`
+	// Severity is the severity of the logs the rule forwards. Logs without a severity are forwarded as INFO
+	Severity *LogSeverity `json:"severity,omitempty" yaml:"severity,omitempty"`
+	// LogAttributes are the captured and derived fields attached to the logs the rule forwards as attributes
+	LogAttributes *LogAttributes `json:"logAttributes,omitempty" yaml:"logAttributes,omitempty"`
+	// Rewrite rewrites the body of the logs the rule forwards so that sensitive data doesn't leave the cluster
+	Rewrite *Rewrite `json:"rewrite,omitempty" yaml:"rewrite,omitempty"`
+	// OnError is what to do with a line the rule fails to parse, transform or evaluate. Skip leaves the rule out for the line, ForwardRaw forwards the line as it was read, with the rule's redaction applied, and DiscardLine drops everything every rule produced for the line, whatever the order of the rules.
+	// +kubebuilder:validation:Enum=Skip;ForwardRaw;DiscardLine
+	// +kubebuilder:default=Skip
+	OnError string `json:"onError,omitempty" yaml:"onError,omitempty"`
+}
+
+// Rewrite changes the body of the logs a rule forwards, so that sensitive data doesn't leave the
+// cluster. The body is rebuilt from Template if one is set, then the text captured for RedactFields
+// and whatever the Detectors find in it is replaced with Mask.
+type Rewrite struct {
+	Template     string   `json:"template,omitempty" yaml:"template,omitempty"`         // new body, referring to fields as ${field} or $field, with $$ for a literal $
+	RedactFields []string `json:"redactFields,omitempty" yaml:"redactFields,omitempty"` // fields whose captured text is masked where the pattern captured it, in the body and the log attributes
+	// Detectors are the built-in detectors of sensitive data to mask
+	// +kubebuilder:validation:Enum=Email;CreditCard;Token;IP
+	Detectors []string `json:"detectors,omitempty" yaml:"detectors,omitempty"`
+	// Mask replaces masked text
+	// +kubebuilder:default="[REDACTED]"
+	Mask string `json:"mask,omitempty" yaml:"mask,omitempty"`
+}
+
+// LogAttributes selects the captured and derived fields attached to the logs a rule forwards as
+// attributes. Missing fields are left out.
+type LogAttributes struct {
+	All    bool     `json:"all,omitempty" yaml:"all,omitempty"`       // attach every field
+	Fields []string `json:"fields,omitempty" yaml:"fields,omitempty"` // fields to attach, unless All is set
+}
+
+// LogSeverity sets the severity of the logs a rule forwards. Severities are OpenTelemetry severity
+// names such as WARN or ERROR2, common level names such as warning, or severity numbers from 1 to 24.
+type LogSeverity struct {
+	Field   string            `json:"field,omitempty" yaml:"field,omitempty"`     // field holding the level of the line
+	Mapping map[string]string `json:"mapping,omitempty" yaml:"mapping,omitempty"` // levels to severities, on top of the common level names
+	Value   string            `json:"value,omitempty" yaml:"value,omitempty"`     // severity of lines without a known level
+}
+
+// EventTimestamp names the field holding the time an event happened, so that its metrics and logs
+// are exported at that time instead of the time metrifuge read the line.
+type EventTimestamp struct {
+	Field  string `json:"field" yaml:"field"`   // captured or derived field
+	Layout string `json:"layout" yaml:"layout"` // same layouts as the Timestamp transform
+}
+
+// Transform derives a field from a captured or previously derived field. The transforms of a rule
+// run in order once its pattern matched, and metrics, attributes and conditionals can refer to
+// derived fields by name like to any captured field.
+type Transform struct {
+	// Type of the transform. Duration results are in seconds and ByteSize results in bytes
+	// +kubebuilder:validation:Enum=Lowercase;Replace;Split;Coerce;Duration;ByteSize;Timestamp;Hash;Lookup
+	Type   string `json:"type" yaml:"type"`
+	Field  string `json:"field" yaml:"field"`
+	Target string `json:"target,omitempty" yaml:"target,omitempty"` // field to write, Field itself if empty
+
+	Pattern     string `json:"pattern,omitempty" yaml:"pattern,omitempty"`         // Replace: regular expression to replace
+	Replacement string `json:"replacement,omitempty" yaml:"replacement,omitempty"` // Replace: replacement, may refer to groups as $1
+	Separator   string `json:"separator,omitempty" yaml:"separator,omitempty"`     // Split: separator to split on
+	Index       int    `json:"index,omitempty" yaml:"index,omitempty"`             // Split: element to keep, negative counts from the end
+	// Coerce: type to coerce to
+	// +kubebuilder:validation:Enum=Int64;Float64;Bool
+	To     string `json:"to,omitempty" yaml:"to,omitempty"`
+	Layout string `json:"layout,omitempty" yaml:"layout,omitempty"` // Timestamp: Go layout, a named layout such as RFC3339 or CommonLog, Unix or UnixMilli
+	// Hash: hash algorithm, SHA256 if empty
+	// +kubebuilder:validation:Enum=SHA256;SHA1;MD5;FNV
+	Algorithm string            `json:"algorithm,omitempty" yaml:"algorithm,omitempty"`
+	Table     map[string]string `json:"table,omitempty" yaml:"table,omitempty"`     // Lookup: values to replace the field's value with
+	Default   string            `json:"default,omitempty" yaml:"default,omitempty"` // Lookup: value for values missing from Table, unchanged if empty
 }
 
 // Conditional defines a condition for capturegroup evaluation
 type Conditional struct {
-	Field1           FieldValue       `json:"field1" yaml:"field1"`
-	Operator         string           `json:"operator" yaml:"operator"` // LessThan, Equals, DoesNotEqual, Exists, DoesNotExist, GreaterThan, GreaterThanOrEqualTo, etc.
-	Field2           FieldValue       `json:"field2,omitempty" yaml:"field2,omitempty"`
-	ActionTrue       string           `json:"actionTrue" yaml:"actionTrue"`
+	Condition `json:",inline" yaml:",inline"`
+	// ActionTrue is the action to take if the condition holds
+	// +kubebuilder:validation:Enum=Forward;Discard;Conditional
+	ActionTrue string `json:"actionTrue" yaml:"actionTrue"`
+	// ActionFalse is the action to take if the condition doesn't hold
+	// +kubebuilder:validation:Enum=Forward;Discard;Conditional
 	ActionFalse      string           `json:"actionFalse" yaml:"actionFalse"`
-	MetricsTrue      []MetricTemplate `json:"metricsTrue,omitempty" yaml:"metricsTrue,omitempty"`
-	MetricsFalse     []MetricTemplate `json:"metricsFalse,omitempty" yaml:"metricsFalse,omitempty"`
-	ConditionalTrue  *Conditional     `json:"conditionalTrue" yaml:"conditionalTrue"`
-	ConditionalFalse *Conditional     `json:"conditionalFalse" yaml:"conditionalFalse"`
+	MetricsTrue      []MetricTemplate `json:"metricsTrue,omitempty" yaml:"metricsTrue,omitempty"`           // emitted if the condition holds
+	MetricsFalse     []MetricTemplate `json:"metricsFalse,omitempty" yaml:"metricsFalse,omitempty"`         // emitted if the condition doesn't hold
+	ConditionalTrue  *Conditional     `json:"conditionalTrue,omitempty" yaml:"conditionalTrue,omitempty"`   // next conditional if the condition holds and ActionTrue is Conditional
+	ConditionalFalse *Conditional     `json:"conditionalFalse,omitempty" yaml:"conditionalFalse,omitempty"` // next conditional if the condition doesn't hold and ActionFalse is Conditional
+}
+
+// Condition is a predicate on the fields of a line. It is either a comparison of Field1 with Field2
+// using Operator, a CEL Expression, or a compound of other conditions: All of them, Any of them, or
+// Not the one given.
+type Condition struct {
+	Field1 FieldValue `json:"field1,omitzero" yaml:"field1,omitempty"` // value compared by Operator
+	// Operator compares Field1 with Field2, unless the condition is an expression or a compound. Matches takes a regular expression as the manualValue of Field2, and ordering operators compare numbers
+	// +kubebuilder:validation:Enum=Equals;DoesNotEqual;Exists;DoesNotExist;LessThan;GreaterThan;LessThanOrEqualTo;GreaterThanOrEqualTo;Matches;In;NotIn;Contains;StartsWith;EndsWith
+	Operator string     `json:"operator,omitempty" yaml:"operator,omitempty"`
+	Field2   FieldValue `json:"field2,omitzero" yaml:"field2,omitempty"`  // value Field1 is compared to, the regular expression of Matches as a manualValue
+	Values   []string   `json:"values,omitempty" yaml:"values,omitempty"` // In, NotIn: values Field1 is compared to
+
+	// Expression is a CEL expression on the fields of the line, such as status >= 500 && path.startsWith("/api"). Fields that look like numbers are numbers, raw has every field as a string, as in raw["zip"] == "01234"
+	Expression string `json:"expression,omitempty" yaml:"expression,omitempty"`
+
+	All []Condition `json:"all,omitempty" yaml:"all,omitempty"` // conditions that must all hold
+	Any []Condition `json:"any,omitempty" yaml:"any,omitempty"` // conditions of which one must hold
+	Not *Condition  `json:"not,omitempty" yaml:"not,omitempty"` // condition that must not hold
 }
 
 // FieldValue represents a field value that can come from a grok match or be a manual value
 type FieldValue struct {
-	Type        string `json:"type" yaml:"type"` // Int64, Float64, String
-	GrokKey     string `json:"grokKey,omitempty" yaml:"grokKey,omitempty"`
-	ManualValue string `json:"manualValue,omitempty" yaml:"manualValue,omitempty"`
+	// Type of the value
+	// +kubebuilder:validation:Enum=Int64;Float64;String
+	Type        string `json:"type" yaml:"type"`
+	GrokKey     string `json:"grokKey,omitempty" yaml:"grokKey,omitempty"`         // captured or derived field holding the value
+	ManualValue string `json:"manualValue,omitempty" yaml:"manualValue,omitempty"` // static value, if GrokKey is empty
 }
 
 // MetricTemplate defines a metric to be emitted
 type MetricTemplate struct {
-	Name       string      `json:"name" yaml:"name"`
-	Kind       string      `json:"kind" yaml:"kind"` // Int64Counter, etc.
+	Name string `json:"name" yaml:"name"`
+	// Kind of the metric
+	// +kubebuilder:validation:Enum=Int64Counter;Float64Counter;Int64Gauge;Float64Gauge;Int64Histogram;Float64Histogram
+	Kind       string      `json:"kind" yaml:"kind"`
 	Value      MetricValue `json:"value" yaml:"value"`
 	Attributes []Attribute `json:"attributes,omitempty" yaml:"attributes,omitempty"`
 }
 
 // MetricValue represents the value of a metric
 type MetricValue struct {
-	Type        string `json:"type" yaml:"type"` // Int64, Float64
-	GrokKey     string `json:"grokKey,omitempty" yaml:"grokKey,omitempty"`
-	ManualValue string `json:"manualValue,omitempty" yaml:"manualValue,omitempty"`
+	// Type of the value
+	// +kubebuilder:validation:Enum=Int64;Float64
+	Type        string `json:"type" yaml:"type"`
+	GrokKey     string `json:"grokKey,omitempty" yaml:"grokKey,omitempty"`         // captured or derived field holding the value
+	ManualValue string `json:"manualValue,omitempty" yaml:"manualValue,omitempty"` // static value, if GrokKey is empty
+	Expression  string `json:"expression,omitempty" yaml:"expression,omitempty"`   // CEL expression on the fields of the line, such as bytes_out / 1024
 }
 
 // Attribute represents a key-value pair for metric attributes
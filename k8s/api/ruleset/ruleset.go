@@ -13,10 +13,29 @@ type RuleSet struct {
 	Status     map[string]any `json:"status,omitempty" yaml:"status,omitempty"`
 }
 
-// RuleSpec contains the capturegroup configuration
+// Evaluation modes of a RuleSet
+const (
+	EvaluationAllMatch   = "allMatch"   // every matching rule applies to a line
+	EvaluationFirstMatch = "firstMatch" // only the first matching rule applies to a line
+)
+
+// +k8s:deepcopy-gen=true
+
+// RuleSetSpec contains the rules of a RuleSet and the log sources they apply to
 type RuleSetSpec struct {
+	// Selector matches the log sources the rules apply to
+	// +required
 	Selector *api.Selector `json:"selector,omitempty" yaml:"selector,omitempty"`
-	Rules    []*api.Rule   `json:"rules" yaml:"rules"`
+	Rules    []*api.Rule   `json:"rules" yaml:"rules"` // applied to every line of the selected log sources
+	// Evaluation is whether every matching rule applies to a line, or only the first one
+	// +kubebuilder:validation:Enum=allMatch;firstMatch
+	// +kubebuilder:default=allMatch
+	Evaluation string `json:"evaluation,omitempty" yaml:"evaluation,omitempty"`
+	// PatternDefinitions are named grok patterns the rules can use, on top of the built-in ones and
+	// those of PatternConfigMap. They take precedence over both.
+	PatternDefinitions map[string]string `json:"patternDefinitions,omitempty" yaml:"patternDefinitions,omitempty"`
+	// PatternConfigMap holds shared grok pattern files. Every entry is a pattern file with one "NAME expression" definition per line. It is read when RuleSets are reloaded, which a change to a RuleSet or LogSource triggers, so edits to it take effect once the RuleSet is touched
+	PatternConfigMap *api.ConfigMapRef `json:"patternConfigMap,omitempty" yaml:"patternConfigMap,omitempty"`
 }
 
 func (rs RuleSet) GetMetadata() api.Metadata {
@@ -0,0 +1,54 @@
+//go:build !ignore_autogenerated
+// +build !ignore_autogenerated
+
+// Code generated by deepcopy-gen. DO NOT EDIT.
+
+package ruleset
+
+import (
+	api "github.com/devon-caron/metrifuge/k8s/api"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RuleSetSpec) DeepCopyInto(out *RuleSetSpec) {
+	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(api.Selector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]*api.Rule, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(api.Rule)
+				(*in).DeepCopyInto(*out)
+			}
+		}
+	}
+	if in.PatternDefinitions != nil {
+		in, out := &in.PatternDefinitions, &out.PatternDefinitions
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PatternConfigMap != nil {
+		in, out := &in.PatternConfigMap, &out.PatternConfigMap
+		*out = new(api.ConfigMapRef)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RuleSetSpec.
+func (in *RuleSetSpec) DeepCopy() *RuleSetSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RuleSetSpec)
+	in.DeepCopyInto(out)
+	return out
+}
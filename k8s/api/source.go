@@ -19,13 +19,15 @@ type Source interface {
 	// StartLogStream starts a log stream for the source
 	// kClient is the kubernetes client
 	// nonK8sConfig is the non-kubernetes config
+	// lines is the channel every log line is sent to as soon as it is read; a full channel blocks the stream
 	// stopCh is the channel to signal the end of the log stream
 	// This function assumes k8s is active until the rest config is checked. If the k8s config is not present, it will use the non-k8s config.
-	StartLogStream(kClient *K8sClientWrapper, nonK8sConfig map[string]interface{}, stopCh <-chan struct{}) error
-	GetNewLogs() []string
+	StartLogStream(kClient *K8sClientWrapper, nonK8sConfig map[string]interface{}, lines chan<- string, stopCh <-chan struct{}) error
 }
 
 type SourceSpec struct {
+	// Type of the source
+	// +kubebuilder:validation:Enum=PodSource;PVCSource;LocalSource;CmdSource
 	Type        string       `json:"type" yaml:"type"`
 	PVCSource   *PVCSource   `json:"pvcSource,omitempty" yaml:"pvcSource,omitempty"`
 	PodSource   *PodSource   `json:"podSource,omitempty" yaml:"podSource,omitempty"`
@@ -35,41 +37,35 @@ type SourceSpec struct {
 
 type PVCSource struct {
 	PVC struct {
-		Name string `json:"name" yaml:"name"`
+		Name string `json:"name" yaml:"name"` // name of the PersistentVolumeClaim
 	} `json:"pvc" yaml:"pvc"`
-	LogFilePath string `json:"logFilePath" yaml:"logFilePath"`
+	LogFilePath string `json:"logFilePath" yaml:"logFilePath"` // path to the log file within the PVC
 }
 
 type PodSource struct {
-	Pod    Pod `json:"pod" yaml:"pod"`
-	stream io.ReadCloser
-	buffer []string
+	Pod Pod `json:"pod" yaml:"pod"`
 }
 
 type Pod struct {
-	Name      string `json:"name" yaml:"name"`
-	Namespace string `json:"namespace" yaml:"namespace"`
-	Container string `json:"container" yaml:"container"`
+	Name      string `json:"name" yaml:"name"`           // name of the Pod
+	Namespace string `json:"namespace" yaml:"namespace"` // namespace of the Pod
+	Container string `json:"container" yaml:"container"` // container within the Pod whose logs are read
 }
 
 func (pvc *PVCSource) GetSourceInfo() string {
 	return fmt.Sprintf("PVC: %s, Log File Path: %s", pvc.PVC.Name, pvc.LogFilePath)
 }
 
-func (pvc *PVCSource) StartLogStream(kClient *K8sClientWrapper, nonK8sConfig map[string]interface{}, stopCh <-chan struct{}) error {
+func (pvc *PVCSource) StartLogStream(kClient *K8sClientWrapper, nonK8sConfig map[string]interface{}, lines chan<- string, stopCh <-chan struct{}) error {
 	// may need to implement mount sockets for this to work
 	return nil
 }
 
-func (pvc *PVCSource) GetNewLogs() []string {
-	return nil
-}
-
 func (pod *PodSource) GetSourceInfo() string {
 	return fmt.Sprintf("Pod: %s, Container: %s, Namespace: %s", pod.Pod.Name, pod.Pod.Container, pod.Pod.Namespace)
 }
 
-func (pod *PodSource) StartLogStream(kClient *K8sClientWrapper, nonK8sConfig map[string]interface{}, stopCh <-chan struct{}) error {
+func (pod *PodSource) StartLogStream(kClient *K8sClientWrapper, nonK8sConfig map[string]interface{}, lines chan<- string, stopCh <-chan struct{}) error {
 	if kClient == nil {
 		panic("kClient is nil, nonK8sConfig must be provided")
 	}
@@ -103,7 +99,6 @@ func (pod *PodSource) StartLogStream(kClient *K8sClientWrapper, nonK8sConfig map
 			time.Sleep(time.Duration(delay) * time.Second)
 			continue
 		}
-		pod.stream = stream
 		break
 	}
 	if err != nil {
@@ -120,8 +115,11 @@ func (pod *PodSource) StartLogStream(kClient *K8sClientWrapper, nonK8sConfig map
 	debugCounter := 0
 	numLogs := 100
 	for scanner.Scan() {
-		logLine := scanner.Text()
-		pod.buffer = append(pod.buffer, logLine)
+		select {
+		case lines <- scanner.Text():
+		case <-stopCh:
+			return nil
+		}
 		debugCounter++
 		if debugCounter >= numLogs {
 			logrus.Infof("received %d logs from pod: %v", numLogs, pod.GetSourceInfo())
@@ -138,33 +136,22 @@ func (pod *PodSource) StartLogStream(kClient *K8sClientWrapper, nonK8sConfig map
 	return nil
 }
 
-func (pod *PodSource) GetNewLogs() []string {
-	newLogs := make([]string, len(pod.buffer))
-	copy(newLogs, pod.buffer)
-	pod.buffer = make([]string, 0)
-	return newLogs
-}
-
 // LocalSource contains the configuration for getting logs from a local file
 type LocalSource struct {
-	Path string `json:"path" yaml:"path"`
+	Path string `json:"path" yaml:"path"` // path to the log file
 }
 
 // CmdSource contains the configuration for getting logs from a command
 // TODO: implement for given pod/container
 type CmdSource struct {
-	Command string `json:"command" yaml:"command"`
+	Command string `json:"command" yaml:"command"` // command whose output is read
 }
 
 func (locs *LocalSource) GetSourceInfo() string {
 	return fmt.Sprintf("Local: %s", locs.Path)
 }
 
-func (locs *LocalSource) StartLogStream(kClient *K8sClientWrapper, nonK8sConfig map[string]interface{}, stopCh <-chan struct{}) error {
-	return nil
-}
-
-func (locs *LocalSource) GetNewLogs() []string {
+func (locs *LocalSource) StartLogStream(kClient *K8sClientWrapper, nonK8sConfig map[string]interface{}, lines chan<- string, stopCh <-chan struct{}) error {
 	return nil
 }
 
@@ -172,10 +159,6 @@ func (cs *CmdSource) GetSourceInfo() string {
 	return fmt.Sprintf("Command: %s", cs.Command)
 }
 
-func (cs *CmdSource) StartLogStream(kClient *K8sClientWrapper, nonK8sConfig map[string]interface{}, stopCh <-chan struct{}) error {
-	return nil
-}
-
-func (cs *CmdSource) GetNewLogs() []string {
+func (cs *CmdSource) StartLogStream(kClient *K8sClientWrapper, nonK8sConfig map[string]interface{}, lines chan<- string, stopCh <-chan struct{}) error {
 	return nil
 }
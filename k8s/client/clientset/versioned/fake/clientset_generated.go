@@ -0,0 +1,85 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	clientset "github.com/devon-caron/metrifuge/k8s/client/clientset/versioned"
+	metrifugev1alpha1 "github.com/devon-caron/metrifuge/k8s/client/clientset/versioned/typed/metrifuge/v1alpha1"
+	fakemetrifugev1alpha1 "github.com/devon-caron/metrifuge/k8s/client/clientset/versioned/typed/metrifuge/v1alpha1/fake"
+	metrifugev1beta1 "github.com/devon-caron/metrifuge/k8s/client/clientset/versioned/typed/metrifuge/v1beta1"
+	fakemetrifugev1beta1 "github.com/devon-caron/metrifuge/k8s/client/clientset/versioned/typed/metrifuge/v1beta1/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/discovery"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/testing"
+)
+
+// NewSimpleClientset returns a clientset that will respond with the provided objects.
+// It's backed by a very simple object tracker that processes creates, updates and deletions as-is,
+// without applying any field management, validations and/or defaults. It shouldn't be considered a replacement
+// for a real clientset and is mostly useful in simple unit tests.
+//
+// DEPRECATED: NewClientset replaces this with support for field management, which significantly improves
+// server side apply testing. NewClientset is only available when apply configurations are generated (e.g.
+// via --with-applyconfig).
+func NewSimpleClientset(objects ...runtime.Object) *Clientset {
+	o := testing.NewObjectTracker(scheme, codecs.UniversalDecoder())
+	for _, obj := range objects {
+		if err := o.Add(obj); err != nil {
+			panic(err)
+		}
+	}
+
+	cs := &Clientset{tracker: o}
+	cs.discovery = &fakediscovery.FakeDiscovery{Fake: &cs.Fake}
+	cs.AddReactor("*", "*", testing.ObjectReaction(o))
+	cs.AddWatchReactor("*", func(action testing.Action) (handled bool, ret watch.Interface, err error) {
+		var opts metav1.ListOptions
+		if watchActcion, ok := action.(testing.WatchActionImpl); ok {
+			opts = watchActcion.ListOptions
+		}
+		gvr := action.GetResource()
+		ns := action.GetNamespace()
+		watch, err := o.Watch(gvr, ns, opts)
+		if err != nil {
+			return false, nil, err
+		}
+		return true, watch, nil
+	})
+
+	return cs
+}
+
+// Clientset implements clientset.Interface. Meant to be embedded into a
+// struct to get a default implementation. This makes faking out just the method
+// you want to test easier.
+type Clientset struct {
+	testing.Fake
+	discovery *fakediscovery.FakeDiscovery
+	tracker   testing.ObjectTracker
+}
+
+func (c *Clientset) Discovery() discovery.DiscoveryInterface {
+	return c.discovery
+}
+
+func (c *Clientset) Tracker() testing.ObjectTracker {
+	return c.tracker
+}
+
+var (
+	_ clientset.Interface = &Clientset{}
+	_ testing.FakeClient  = &Clientset{}
+)
+
+// MetrifugeV1alpha1 retrieves the MetrifugeV1alpha1Client
+func (c *Clientset) MetrifugeV1alpha1() metrifugev1alpha1.MetrifugeV1alpha1Interface {
+	return &fakemetrifugev1alpha1.FakeMetrifugeV1alpha1{Fake: &c.Fake}
+}
+
+// MetrifugeV1beta1 retrieves the MetrifugeV1beta1Client
+func (c *Clientset) MetrifugeV1beta1() metrifugev1beta1.MetrifugeV1beta1Interface {
+	return &fakemetrifugev1beta1.FakeMetrifugeV1beta1{Fake: &c.Fake}
+}
@@ -0,0 +1,34 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1alpha1 "github.com/devon-caron/metrifuge/k8s/api/metrifuge/v1alpha1"
+	metrifugev1alpha1 "github.com/devon-caron/metrifuge/k8s/client/clientset/versioned/typed/metrifuge/v1alpha1"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// fakeExporters implements ExporterInterface
+type fakeExporters struct {
+	*gentype.FakeClientWithList[*v1alpha1.Exporter, *v1alpha1.ExporterList]
+	Fake *FakeMetrifugeV1alpha1
+}
+
+func newFakeExporters(fake *FakeMetrifugeV1alpha1) metrifugev1alpha1.ExporterInterface {
+	return &fakeExporters{
+		gentype.NewFakeClientWithList[*v1alpha1.Exporter, *v1alpha1.ExporterList](
+			fake.Fake,
+			"",
+			v1alpha1.SchemeGroupVersion.WithResource("exporters"),
+			v1alpha1.SchemeGroupVersion.WithKind("Exporter"),
+			func() *v1alpha1.Exporter { return &v1alpha1.Exporter{} },
+			func() *v1alpha1.ExporterList { return &v1alpha1.ExporterList{} },
+			func(dst, src *v1alpha1.ExporterList) { dst.ListMeta = src.ListMeta },
+			func(list *v1alpha1.ExporterList) []*v1alpha1.Exporter { return gentype.ToPointerSlice(list.Items) },
+			func(list *v1alpha1.ExporterList, items []*v1alpha1.Exporter) {
+				list.Items = gentype.FromPointerSlice(items)
+			},
+		),
+		fake,
+	}
+}
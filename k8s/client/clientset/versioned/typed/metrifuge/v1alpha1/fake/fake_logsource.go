@@ -0,0 +1,34 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1alpha1 "github.com/devon-caron/metrifuge/k8s/api/metrifuge/v1alpha1"
+	metrifugev1alpha1 "github.com/devon-caron/metrifuge/k8s/client/clientset/versioned/typed/metrifuge/v1alpha1"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// fakeLogSources implements LogSourceInterface
+type fakeLogSources struct {
+	*gentype.FakeClientWithList[*v1alpha1.LogSource, *v1alpha1.LogSourceList]
+	Fake *FakeMetrifugeV1alpha1
+}
+
+func newFakeLogSources(fake *FakeMetrifugeV1alpha1, namespace string) metrifugev1alpha1.LogSourceInterface {
+	return &fakeLogSources{
+		gentype.NewFakeClientWithList[*v1alpha1.LogSource, *v1alpha1.LogSourceList](
+			fake.Fake,
+			namespace,
+			v1alpha1.SchemeGroupVersion.WithResource("logsources"),
+			v1alpha1.SchemeGroupVersion.WithKind("LogSource"),
+			func() *v1alpha1.LogSource { return &v1alpha1.LogSource{} },
+			func() *v1alpha1.LogSourceList { return &v1alpha1.LogSourceList{} },
+			func(dst, src *v1alpha1.LogSourceList) { dst.ListMeta = src.ListMeta },
+			func(list *v1alpha1.LogSourceList) []*v1alpha1.LogSource { return gentype.ToPointerSlice(list.Items) },
+			func(list *v1alpha1.LogSourceList, items []*v1alpha1.LogSource) {
+				list.Items = gentype.FromPointerSlice(items)
+			},
+		),
+		fake,
+	}
+}
@@ -0,0 +1,34 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1alpha1 "github.com/devon-caron/metrifuge/k8s/api/metrifuge/v1alpha1"
+	metrifugev1alpha1 "github.com/devon-caron/metrifuge/k8s/client/clientset/versioned/typed/metrifuge/v1alpha1"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// fakeRuleSets implements RuleSetInterface
+type fakeRuleSets struct {
+	*gentype.FakeClientWithList[*v1alpha1.RuleSet, *v1alpha1.RuleSetList]
+	Fake *FakeMetrifugeV1alpha1
+}
+
+func newFakeRuleSets(fake *FakeMetrifugeV1alpha1, namespace string) metrifugev1alpha1.RuleSetInterface {
+	return &fakeRuleSets{
+		gentype.NewFakeClientWithList[*v1alpha1.RuleSet, *v1alpha1.RuleSetList](
+			fake.Fake,
+			namespace,
+			v1alpha1.SchemeGroupVersion.WithResource("rulesets"),
+			v1alpha1.SchemeGroupVersion.WithKind("RuleSet"),
+			func() *v1alpha1.RuleSet { return &v1alpha1.RuleSet{} },
+			func() *v1alpha1.RuleSetList { return &v1alpha1.RuleSetList{} },
+			func(dst, src *v1alpha1.RuleSetList) { dst.ListMeta = src.ListMeta },
+			func(list *v1alpha1.RuleSetList) []*v1alpha1.RuleSet { return gentype.ToPointerSlice(list.Items) },
+			func(list *v1alpha1.RuleSetList, items []*v1alpha1.RuleSet) {
+				list.Items = gentype.FromPointerSlice(items)
+			},
+		),
+		fake,
+	}
+}
@@ -0,0 +1,54 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	context "context"
+
+	metrifugev1alpha1 "github.com/devon-caron/metrifuge/k8s/api/metrifuge/v1alpha1"
+	scheme "github.com/devon-caron/metrifuge/k8s/client/clientset/versioned/scheme"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// LogSourcesGetter has a method to return a LogSourceInterface.
+// A group's client should implement this interface.
+type LogSourcesGetter interface {
+	LogSources(namespace string) LogSourceInterface
+}
+
+// LogSourceInterface has methods to work with LogSource resources.
+type LogSourceInterface interface {
+	Create(ctx context.Context, logSource *metrifugev1alpha1.LogSource, opts v1.CreateOptions) (*metrifugev1alpha1.LogSource, error)
+	Update(ctx context.Context, logSource *metrifugev1alpha1.LogSource, opts v1.UpdateOptions) (*metrifugev1alpha1.LogSource, error)
+	// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+	UpdateStatus(ctx context.Context, logSource *metrifugev1alpha1.LogSource, opts v1.UpdateOptions) (*metrifugev1alpha1.LogSource, error)
+	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
+	Get(ctx context.Context, name string, opts v1.GetOptions) (*metrifugev1alpha1.LogSource, error)
+	List(ctx context.Context, opts v1.ListOptions) (*metrifugev1alpha1.LogSourceList, error)
+	Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *metrifugev1alpha1.LogSource, err error)
+	LogSourceExpansion
+}
+
+// logSources implements LogSourceInterface
+type logSources struct {
+	*gentype.ClientWithList[*metrifugev1alpha1.LogSource, *metrifugev1alpha1.LogSourceList]
+}
+
+// newLogSources returns a LogSources
+func newLogSources(c *MetrifugeV1alpha1Client, namespace string) *logSources {
+	return &logSources{
+		gentype.NewClientWithList[*metrifugev1alpha1.LogSource, *metrifugev1alpha1.LogSourceList](
+			"logsources",
+			c.RESTClient(),
+			scheme.ParameterCodec,
+			namespace,
+			func() *metrifugev1alpha1.LogSource { return &metrifugev1alpha1.LogSource{} },
+			func() *metrifugev1alpha1.LogSourceList { return &metrifugev1alpha1.LogSourceList{} },
+		),
+	}
+}
@@ -0,0 +1,95 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	http "net/http"
+
+	metrifugev1alpha1 "github.com/devon-caron/metrifuge/k8s/api/metrifuge/v1alpha1"
+	scheme "github.com/devon-caron/metrifuge/k8s/client/clientset/versioned/scheme"
+	rest "k8s.io/client-go/rest"
+)
+
+type MetrifugeV1alpha1Interface interface {
+	RESTClient() rest.Interface
+	ExportersGetter
+	LogSourcesGetter
+	RuleSetsGetter
+}
+
+// MetrifugeV1alpha1Client is used to interact with features provided by the metrifuge.com group.
+type MetrifugeV1alpha1Client struct {
+	restClient rest.Interface
+}
+
+func (c *MetrifugeV1alpha1Client) Exporters() ExporterInterface {
+	return newExporters(c)
+}
+
+func (c *MetrifugeV1alpha1Client) LogSources(namespace string) LogSourceInterface {
+	return newLogSources(c, namespace)
+}
+
+func (c *MetrifugeV1alpha1Client) RuleSets(namespace string) RuleSetInterface {
+	return newRuleSets(c, namespace)
+}
+
+// NewForConfig creates a new MetrifugeV1alpha1Client for the given config.
+// NewForConfig is equivalent to NewForConfigAndClient(c, httpClient),
+// where httpClient was generated with rest.HTTPClientFor(c).
+func NewForConfig(c *rest.Config) (*MetrifugeV1alpha1Client, error) {
+	config := *c
+	setConfigDefaults(&config)
+	httpClient, err := rest.HTTPClientFor(&config)
+	if err != nil {
+		return nil, err
+	}
+	return NewForConfigAndClient(&config, httpClient)
+}
+
+// NewForConfigAndClient creates a new MetrifugeV1alpha1Client for the given config and http client.
+// Note the http client provided takes precedence over the configured transport values.
+func NewForConfigAndClient(c *rest.Config, h *http.Client) (*MetrifugeV1alpha1Client, error) {
+	config := *c
+	setConfigDefaults(&config)
+	client, err := rest.RESTClientForConfigAndClient(&config, h)
+	if err != nil {
+		return nil, err
+	}
+	return &MetrifugeV1alpha1Client{client}, nil
+}
+
+// NewForConfigOrDie creates a new MetrifugeV1alpha1Client for the given config and
+// panics if there is an error in the config.
+func NewForConfigOrDie(c *rest.Config) *MetrifugeV1alpha1Client {
+	client, err := NewForConfig(c)
+	if err != nil {
+		panic(err)
+	}
+	return client
+}
+
+// New creates a new MetrifugeV1alpha1Client for the given RESTClient.
+func New(c rest.Interface) *MetrifugeV1alpha1Client {
+	return &MetrifugeV1alpha1Client{c}
+}
+
+func setConfigDefaults(config *rest.Config) {
+	gv := metrifugev1alpha1.SchemeGroupVersion
+	config.GroupVersion = &gv
+	config.APIPath = "/apis"
+	config.NegotiatedSerializer = rest.CodecFactoryForGeneratedClient(scheme.Scheme, scheme.Codecs).WithoutConversion()
+
+	if config.UserAgent == "" {
+		config.UserAgent = rest.DefaultKubernetesUserAgent()
+	}
+}
+
+// RESTClient returns a RESTClient that is used to communicate
+// with API server by this client implementation.
+func (c *MetrifugeV1alpha1Client) RESTClient() rest.Interface {
+	if c == nil {
+		return nil
+	}
+	return c.restClient
+}
@@ -0,0 +1,34 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1beta1 "github.com/devon-caron/metrifuge/k8s/api/metrifuge/v1beta1"
+	metrifugev1beta1 "github.com/devon-caron/metrifuge/k8s/client/clientset/versioned/typed/metrifuge/v1beta1"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// fakeExporters implements ExporterInterface
+type fakeExporters struct {
+	*gentype.FakeClientWithList[*v1beta1.Exporter, *v1beta1.ExporterList]
+	Fake *FakeMetrifugeV1beta1
+}
+
+func newFakeExporters(fake *FakeMetrifugeV1beta1) metrifugev1beta1.ExporterInterface {
+	return &fakeExporters{
+		gentype.NewFakeClientWithList[*v1beta1.Exporter, *v1beta1.ExporterList](
+			fake.Fake,
+			"",
+			v1beta1.SchemeGroupVersion.WithResource("exporters"),
+			v1beta1.SchemeGroupVersion.WithKind("Exporter"),
+			func() *v1beta1.Exporter { return &v1beta1.Exporter{} },
+			func() *v1beta1.ExporterList { return &v1beta1.ExporterList{} },
+			func(dst, src *v1beta1.ExporterList) { dst.ListMeta = src.ListMeta },
+			func(list *v1beta1.ExporterList) []*v1beta1.Exporter { return gentype.ToPointerSlice(list.Items) },
+			func(list *v1beta1.ExporterList, items []*v1beta1.Exporter) {
+				list.Items = gentype.FromPointerSlice(items)
+			},
+		),
+		fake,
+	}
+}
@@ -0,0 +1,34 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1beta1 "github.com/devon-caron/metrifuge/k8s/api/metrifuge/v1beta1"
+	metrifugev1beta1 "github.com/devon-caron/metrifuge/k8s/client/clientset/versioned/typed/metrifuge/v1beta1"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// fakeLogSources implements LogSourceInterface
+type fakeLogSources struct {
+	*gentype.FakeClientWithList[*v1beta1.LogSource, *v1beta1.LogSourceList]
+	Fake *FakeMetrifugeV1beta1
+}
+
+func newFakeLogSources(fake *FakeMetrifugeV1beta1, namespace string) metrifugev1beta1.LogSourceInterface {
+	return &fakeLogSources{
+		gentype.NewFakeClientWithList[*v1beta1.LogSource, *v1beta1.LogSourceList](
+			fake.Fake,
+			namespace,
+			v1beta1.SchemeGroupVersion.WithResource("logsources"),
+			v1beta1.SchemeGroupVersion.WithKind("LogSource"),
+			func() *v1beta1.LogSource { return &v1beta1.LogSource{} },
+			func() *v1beta1.LogSourceList { return &v1beta1.LogSourceList{} },
+			func(dst, src *v1beta1.LogSourceList) { dst.ListMeta = src.ListMeta },
+			func(list *v1beta1.LogSourceList) []*v1beta1.LogSource { return gentype.ToPointerSlice(list.Items) },
+			func(list *v1beta1.LogSourceList, items []*v1beta1.LogSource) {
+				list.Items = gentype.FromPointerSlice(items)
+			},
+		),
+		fake,
+	}
+}
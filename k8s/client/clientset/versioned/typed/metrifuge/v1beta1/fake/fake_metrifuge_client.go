@@ -0,0 +1,32 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1beta1 "github.com/devon-caron/metrifuge/k8s/client/clientset/versioned/typed/metrifuge/v1beta1"
+	rest "k8s.io/client-go/rest"
+	testing "k8s.io/client-go/testing"
+)
+
+type FakeMetrifugeV1beta1 struct {
+	*testing.Fake
+}
+
+func (c *FakeMetrifugeV1beta1) Exporters() v1beta1.ExporterInterface {
+	return newFakeExporters(c)
+}
+
+func (c *FakeMetrifugeV1beta1) LogSources(namespace string) v1beta1.LogSourceInterface {
+	return newFakeLogSources(c, namespace)
+}
+
+func (c *FakeMetrifugeV1beta1) RuleSets(namespace string) v1beta1.RuleSetInterface {
+	return newFakeRuleSets(c, namespace)
+}
+
+// RESTClient returns a RESTClient that is used to communicate
+// with API server by this client implementation.
+func (c *FakeMetrifugeV1beta1) RESTClient() rest.Interface {
+	var ret *rest.RESTClient
+	return ret
+}
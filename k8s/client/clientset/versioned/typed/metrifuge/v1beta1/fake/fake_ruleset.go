@@ -0,0 +1,34 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1beta1 "github.com/devon-caron/metrifuge/k8s/api/metrifuge/v1beta1"
+	metrifugev1beta1 "github.com/devon-caron/metrifuge/k8s/client/clientset/versioned/typed/metrifuge/v1beta1"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// fakeRuleSets implements RuleSetInterface
+type fakeRuleSets struct {
+	*gentype.FakeClientWithList[*v1beta1.RuleSet, *v1beta1.RuleSetList]
+	Fake *FakeMetrifugeV1beta1
+}
+
+func newFakeRuleSets(fake *FakeMetrifugeV1beta1, namespace string) metrifugev1beta1.RuleSetInterface {
+	return &fakeRuleSets{
+		gentype.NewFakeClientWithList[*v1beta1.RuleSet, *v1beta1.RuleSetList](
+			fake.Fake,
+			namespace,
+			v1beta1.SchemeGroupVersion.WithResource("rulesets"),
+			v1beta1.SchemeGroupVersion.WithKind("RuleSet"),
+			func() *v1beta1.RuleSet { return &v1beta1.RuleSet{} },
+			func() *v1beta1.RuleSetList { return &v1beta1.RuleSetList{} },
+			func(dst, src *v1beta1.RuleSetList) { dst.ListMeta = src.ListMeta },
+			func(list *v1beta1.RuleSetList) []*v1beta1.RuleSet { return gentype.ToPointerSlice(list.Items) },
+			func(list *v1beta1.RuleSetList, items []*v1beta1.RuleSet) {
+				list.Items = gentype.FromPointerSlice(items)
+			},
+		),
+		fake,
+	}
+}
@@ -0,0 +1,95 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1beta1
+
+import (
+	http "net/http"
+
+	metrifugev1beta1 "github.com/devon-caron/metrifuge/k8s/api/metrifuge/v1beta1"
+	scheme "github.com/devon-caron/metrifuge/k8s/client/clientset/versioned/scheme"
+	rest "k8s.io/client-go/rest"
+)
+
+type MetrifugeV1beta1Interface interface {
+	RESTClient() rest.Interface
+	ExportersGetter
+	LogSourcesGetter
+	RuleSetsGetter
+}
+
+// MetrifugeV1beta1Client is used to interact with features provided by the metrifuge.com group.
+type MetrifugeV1beta1Client struct {
+	restClient rest.Interface
+}
+
+func (c *MetrifugeV1beta1Client) Exporters() ExporterInterface {
+	return newExporters(c)
+}
+
+func (c *MetrifugeV1beta1Client) LogSources(namespace string) LogSourceInterface {
+	return newLogSources(c, namespace)
+}
+
+func (c *MetrifugeV1beta1Client) RuleSets(namespace string) RuleSetInterface {
+	return newRuleSets(c, namespace)
+}
+
+// NewForConfig creates a new MetrifugeV1beta1Client for the given config.
+// NewForConfig is equivalent to NewForConfigAndClient(c, httpClient),
+// where httpClient was generated with rest.HTTPClientFor(c).
+func NewForConfig(c *rest.Config) (*MetrifugeV1beta1Client, error) {
+	config := *c
+	setConfigDefaults(&config)
+	httpClient, err := rest.HTTPClientFor(&config)
+	if err != nil {
+		return nil, err
+	}
+	return NewForConfigAndClient(&config, httpClient)
+}
+
+// NewForConfigAndClient creates a new MetrifugeV1beta1Client for the given config and http client.
+// Note the http client provided takes precedence over the configured transport values.
+func NewForConfigAndClient(c *rest.Config, h *http.Client) (*MetrifugeV1beta1Client, error) {
+	config := *c
+	setConfigDefaults(&config)
+	client, err := rest.RESTClientForConfigAndClient(&config, h)
+	if err != nil {
+		return nil, err
+	}
+	return &MetrifugeV1beta1Client{client}, nil
+}
+
+// NewForConfigOrDie creates a new MetrifugeV1beta1Client for the given config and
+// panics if there is an error in the config.
+func NewForConfigOrDie(c *rest.Config) *MetrifugeV1beta1Client {
+	client, err := NewForConfig(c)
+	if err != nil {
+		panic(err)
+	}
+	return client
+}
+
+// New creates a new MetrifugeV1beta1Client for the given RESTClient.
+func New(c rest.Interface) *MetrifugeV1beta1Client {
+	return &MetrifugeV1beta1Client{c}
+}
+
+func setConfigDefaults(config *rest.Config) {
+	gv := metrifugev1beta1.SchemeGroupVersion
+	config.GroupVersion = &gv
+	config.APIPath = "/apis"
+	config.NegotiatedSerializer = rest.CodecFactoryForGeneratedClient(scheme.Scheme, scheme.Codecs).WithoutConversion()
+
+	if config.UserAgent == "" {
+		config.UserAgent = rest.DefaultKubernetesUserAgent()
+	}
+}
+
+// RESTClient returns a RESTClient that is used to communicate
+// with API server by this client implementation.
+func (c *MetrifugeV1beta1Client) RESTClient() rest.Interface {
+	if c == nil {
+		return nil
+	}
+	return c.restClient
+}
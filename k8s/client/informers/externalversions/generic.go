@@ -0,0 +1,59 @@
+// Code generated by informer-gen. DO NOT EDIT.
+
+package externalversions
+
+import (
+	fmt "fmt"
+
+	v1alpha1 "github.com/devon-caron/metrifuge/k8s/api/metrifuge/v1alpha1"
+	v1beta1 "github.com/devon-caron/metrifuge/k8s/api/metrifuge/v1beta1"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// GenericInformer is type of SharedIndexInformer which will locate and delegate to other
+// sharedInformers based on type
+type GenericInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() cache.GenericLister
+}
+
+type genericInformer struct {
+	informer cache.SharedIndexInformer
+	resource schema.GroupResource
+}
+
+// Informer returns the SharedIndexInformer.
+func (f *genericInformer) Informer() cache.SharedIndexInformer {
+	return f.informer
+}
+
+// Lister returns the GenericLister.
+func (f *genericInformer) Lister() cache.GenericLister {
+	return cache.NewGenericLister(f.Informer().GetIndexer(), f.resource)
+}
+
+// ForResource gives generic access to a shared informer of the matching type
+// TODO extend this to unknown resources with a client pool
+func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource) (GenericInformer, error) {
+	switch resource {
+	// Group=metrifuge.com, Version=v1alpha1
+	case v1alpha1.SchemeGroupVersion.WithResource("exporters"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Metrifuge().V1alpha1().Exporters().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("logsources"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Metrifuge().V1alpha1().LogSources().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("rulesets"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Metrifuge().V1alpha1().RuleSets().Informer()}, nil
+
+		// Group=metrifuge.com, Version=v1beta1
+	case v1beta1.SchemeGroupVersion.WithResource("exporters"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Metrifuge().V1beta1().Exporters().Informer()}, nil
+	case v1beta1.SchemeGroupVersion.WithResource("logsources"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Metrifuge().V1beta1().LogSources().Informer()}, nil
+	case v1beta1.SchemeGroupVersion.WithResource("rulesets"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Metrifuge().V1beta1().RuleSets().Informer()}, nil
+
+	}
+
+	return nil, fmt.Errorf("no informer found for %v", resource)
+}
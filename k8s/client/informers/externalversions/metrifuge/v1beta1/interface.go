@@ -0,0 +1,43 @@
+// Code generated by informer-gen. DO NOT EDIT.
+
+package v1beta1
+
+import (
+	internalinterfaces "github.com/devon-caron/metrifuge/k8s/client/informers/externalversions/internalinterfaces"
+)
+
+// Interface provides access to all the informers in this group version.
+type Interface interface {
+	// Exporters returns a ExporterInformer.
+	Exporters() ExporterInformer
+	// LogSources returns a LogSourceInformer.
+	LogSources() LogSourceInformer
+	// RuleSets returns a RuleSetInformer.
+	RuleSets() RuleSetInformer
+}
+
+type version struct {
+	factory          internalinterfaces.SharedInformerFactory
+	namespace        string
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+}
+
+// New returns a new Interface.
+func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakListOptions internalinterfaces.TweakListOptionsFunc) Interface {
+	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
+}
+
+// Exporters returns a ExporterInformer.
+func (v *version) Exporters() ExporterInformer {
+	return &exporterInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
+// LogSources returns a LogSourceInformer.
+func (v *version) LogSources() LogSourceInformer {
+	return &logSourceInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// RuleSets returns a RuleSetInformer.
+func (v *version) RuleSets() RuleSetInformer {
+	return &ruleSetInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
@@ -0,0 +1,86 @@
+// Code generated by informer-gen. DO NOT EDIT.
+
+package v1beta1
+
+import (
+	context "context"
+	time "time"
+
+	apimetrifugev1beta1 "github.com/devon-caron/metrifuge/k8s/api/metrifuge/v1beta1"
+	versioned "github.com/devon-caron/metrifuge/k8s/client/clientset/versioned"
+	internalinterfaces "github.com/devon-caron/metrifuge/k8s/client/informers/externalversions/internalinterfaces"
+	metrifugev1beta1 "github.com/devon-caron/metrifuge/k8s/client/listers/metrifuge/v1beta1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// RuleSetInformer provides access to a shared informer and lister for
+// RuleSets.
+type RuleSetInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() metrifugev1beta1.RuleSetLister
+}
+
+type ruleSetInformer struct {
+	factory          internalinterfaces.SharedInformerFactory
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+	namespace        string
+}
+
+// NewRuleSetInformer constructs a new informer for RuleSet type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewRuleSetInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers) cache.SharedIndexInformer {
+	return NewFilteredRuleSetInformer(client, namespace, resyncPeriod, indexers, nil)
+}
+
+// NewFilteredRuleSetInformer constructs a new informer for RuleSet type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewFilteredRuleSetInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers, tweakListOptions internalinterfaces.TweakListOptionsFunc) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options v1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.MetrifugeV1beta1().RuleSets(namespace).List(context.Background(), options)
+			},
+			WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.MetrifugeV1beta1().RuleSets(namespace).Watch(context.Background(), options)
+			},
+			ListWithContextFunc: func(ctx context.Context, options v1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.MetrifugeV1beta1().RuleSets(namespace).List(ctx, options)
+			},
+			WatchFuncWithContext: func(ctx context.Context, options v1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.MetrifugeV1beta1().RuleSets(namespace).Watch(ctx, options)
+			},
+		},
+		&apimetrifugev1beta1.RuleSet{},
+		resyncPeriod,
+		indexers,
+	)
+}
+
+func (f *ruleSetInformer) defaultInformer(client versioned.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return NewFilteredRuleSetInformer(client, f.namespace, resyncPeriod, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, f.tweakListOptions)
+}
+
+func (f *ruleSetInformer) Informer() cache.SharedIndexInformer {
+	return f.factory.InformerFor(&apimetrifugev1beta1.RuleSet{}, f.defaultInformer)
+}
+
+func (f *ruleSetInformer) Lister() metrifugev1beta1.RuleSetLister {
+	return metrifugev1beta1.NewRuleSetLister(f.Informer().GetIndexer())
+}
@@ -0,0 +1,54 @@
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	metrifugev1alpha1 "github.com/devon-caron/metrifuge/k8s/api/metrifuge/v1alpha1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	listers "k8s.io/client-go/listers"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// LogSourceLister helps list LogSources.
+// All objects returned here must be treated as read-only.
+type LogSourceLister interface {
+	// List lists all LogSources in the indexer.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*metrifugev1alpha1.LogSource, err error)
+	// LogSources returns an object that can list and get LogSources.
+	LogSources(namespace string) LogSourceNamespaceLister
+	LogSourceListerExpansion
+}
+
+// logSourceLister implements the LogSourceLister interface.
+type logSourceLister struct {
+	listers.ResourceIndexer[*metrifugev1alpha1.LogSource]
+}
+
+// NewLogSourceLister returns a new LogSourceLister.
+func NewLogSourceLister(indexer cache.Indexer) LogSourceLister {
+	return &logSourceLister{listers.New[*metrifugev1alpha1.LogSource](indexer, metrifugev1alpha1.Resource("logsource"))}
+}
+
+// LogSources returns an object that can list and get LogSources.
+func (s *logSourceLister) LogSources(namespace string) LogSourceNamespaceLister {
+	return logSourceNamespaceLister{listers.NewNamespaced[*metrifugev1alpha1.LogSource](s.ResourceIndexer, namespace)}
+}
+
+// LogSourceNamespaceLister helps list and get LogSources.
+// All objects returned here must be treated as read-only.
+type LogSourceNamespaceLister interface {
+	// List lists all LogSources in the indexer for a given namespace.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*metrifugev1alpha1.LogSource, err error)
+	// Get retrieves the LogSource from the indexer for a given namespace and name.
+	// Objects returned here must be treated as read-only.
+	Get(name string) (*metrifugev1alpha1.LogSource, error)
+	LogSourceNamespaceListerExpansion
+}
+
+// logSourceNamespaceLister implements the LogSourceNamespaceLister
+// interface.
+type logSourceNamespaceLister struct {
+	listers.ResourceIndexer[*metrifugev1alpha1.LogSource]
+}
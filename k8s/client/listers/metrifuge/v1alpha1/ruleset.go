@@ -0,0 +1,54 @@
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	metrifugev1alpha1 "github.com/devon-caron/metrifuge/k8s/api/metrifuge/v1alpha1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	listers "k8s.io/client-go/listers"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// RuleSetLister helps list RuleSets.
+// All objects returned here must be treated as read-only.
+type RuleSetLister interface {
+	// List lists all RuleSets in the indexer.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*metrifugev1alpha1.RuleSet, err error)
+	// RuleSets returns an object that can list and get RuleSets.
+	RuleSets(namespace string) RuleSetNamespaceLister
+	RuleSetListerExpansion
+}
+
+// ruleSetLister implements the RuleSetLister interface.
+type ruleSetLister struct {
+	listers.ResourceIndexer[*metrifugev1alpha1.RuleSet]
+}
+
+// NewRuleSetLister returns a new RuleSetLister.
+func NewRuleSetLister(indexer cache.Indexer) RuleSetLister {
+	return &ruleSetLister{listers.New[*metrifugev1alpha1.RuleSet](indexer, metrifugev1alpha1.Resource("ruleset"))}
+}
+
+// RuleSets returns an object that can list and get RuleSets.
+func (s *ruleSetLister) RuleSets(namespace string) RuleSetNamespaceLister {
+	return ruleSetNamespaceLister{listers.NewNamespaced[*metrifugev1alpha1.RuleSet](s.ResourceIndexer, namespace)}
+}
+
+// RuleSetNamespaceLister helps list and get RuleSets.
+// All objects returned here must be treated as read-only.
+type RuleSetNamespaceLister interface {
+	// List lists all RuleSets in the indexer for a given namespace.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*metrifugev1alpha1.RuleSet, err error)
+	// Get retrieves the RuleSet from the indexer for a given namespace and name.
+	// Objects returned here must be treated as read-only.
+	Get(name string) (*metrifugev1alpha1.RuleSet, error)
+	RuleSetNamespaceListerExpansion
+}
+
+// ruleSetNamespaceLister implements the RuleSetNamespaceLister
+// interface.
+type ruleSetNamespaceLister struct {
+	listers.ResourceIndexer[*metrifugev1alpha1.RuleSet]
+}
@@ -0,0 +1,23 @@
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1beta1
+
+// ExporterListerExpansion allows custom methods to be added to
+// ExporterLister.
+type ExporterListerExpansion interface{}
+
+// LogSourceListerExpansion allows custom methods to be added to
+// LogSourceLister.
+type LogSourceListerExpansion interface{}
+
+// LogSourceNamespaceListerExpansion allows custom methods to be added to
+// LogSourceNamespaceLister.
+type LogSourceNamespaceListerExpansion interface{}
+
+// RuleSetListerExpansion allows custom methods to be added to
+// RuleSetLister.
+type RuleSetListerExpansion interface{}
+
+// RuleSetNamespaceListerExpansion allows custom methods to be added to
+// RuleSetNamespaceLister.
+type RuleSetNamespaceListerExpansion interface{}
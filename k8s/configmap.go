@@ -0,0 +1,22 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/devon-caron/metrifuge/k8s/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GetConfigMapData returns the data of a ConfigMap.
+func GetConfigMapData(k8sClient *api.K8sClientWrapper, namespace, name string) (map[string]string, error) {
+	if k8sClient == nil {
+		return nil, fmt.Errorf("failed to get configmap %s/%s: not running in kubernetes", namespace, name)
+	}
+
+	configMap, err := k8sClient.Clientset().CoreV1().ConfigMaps(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get configmap %s/%s: %v", namespace, name, err)
+	}
+	return configMap.Data, nil
+}
@@ -0,0 +1,107 @@
+package k8s
+
+import (
+	"fmt"
+
+	"github.com/devon-caron/metrifuge/global"
+	"github.com/devon-caron/metrifuge/k8s/api/metrifuge/v1alpha1"
+	"github.com/devon-caron/metrifuge/k8s/api/metrifuge/v1beta1"
+	rs "github.com/devon-caron/metrifuge/k8s/api/ruleset"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ConvertObject converts a metrifuge resource to another version of the metrifuge.com API. Only
+// the specs of RuleSets differ between versions, the rest of a resource, its status included, is
+// the same in every version.
+func ConvertObject(obj *unstructured.Unstructured, apiVersion string) (*unstructured.Unstructured, error) {
+	from := obj.GetAPIVersion()
+	for _, version := range []string{from, apiVersion} {
+		if version != v1alpha1.SchemeGroupVersion.String() && version != v1beta1.SchemeGroupVersion.String() {
+			return nil, fmt.Errorf("unsupported API version %s", version)
+		}
+	}
+
+	converted := obj.DeepCopy()
+	converted.SetAPIVersion(apiVersion)
+	if from == apiVersion || obj.GetKind() != global.RULESET_CRD_NAME {
+		return converted, nil
+	}
+
+	spec, ok := obj.Object["spec"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("no spec found in %s/%s", obj.GetNamespace(), obj.GetName())
+	}
+	var err error
+	if apiVersion == v1beta1.SchemeGroupVersion.String() {
+		err = ruleSetToV1beta1(spec, converted)
+	} else {
+		err = ruleSetToV1alpha1(spec, converted)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert rule set %s/%s to %s: %w", obj.GetNamespace(), obj.GetName(), apiVersion, err)
+	}
+	return converted, nil
+}
+
+// ruleSetToV1beta1 sets the spec of a RuleSet converted to v1beta1, saving the conditions of its
+// conditionals in an annotation so that converting it back restores them.
+func ruleSetToV1beta1(spec map[string]any, converted *unstructured.Unstructured) error {
+	var in rs.RuleSetSpec
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(spec, &in); err != nil {
+		return fmt.Errorf("failed to decode spec: %v", err)
+	}
+	saved, err := v1beta1.SaveConditions(&in)
+	if err != nil {
+		return err
+	}
+	out, err := runtime.DefaultUnstructuredConverter.ToUnstructured(v1beta1.FromRuleSetSpec(&in))
+	if err != nil {
+		return fmt.Errorf("failed to encode spec: %v", err)
+	}
+
+	converted.Object["spec"] = out
+	annotations := converted.GetAnnotations()
+	delete(annotations, v1beta1.ConditionsAnnotation)
+	if saved != "" {
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		annotations[v1beta1.ConditionsAnnotation] = saved
+	}
+	setAnnotations(converted, annotations)
+	return nil
+}
+
+// ruleSetToV1alpha1 sets the spec of a RuleSet converted to v1alpha1, restoring the conditions
+// saved when it was converted from v1alpha1.
+func ruleSetToV1alpha1(spec map[string]any, converted *unstructured.Unstructured) error {
+	var in v1beta1.RuleSetSpec
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(spec, &in); err != nil {
+		return fmt.Errorf("failed to decode spec: %v", err)
+	}
+	alpha := v1beta1.ToRuleSetSpec(&in)
+	annotations := converted.GetAnnotations()
+	if saved, ok := annotations[v1beta1.ConditionsAnnotation]; ok {
+		if err := v1beta1.RestoreConditions(alpha, saved); err != nil {
+			return err
+		}
+		delete(annotations, v1beta1.ConditionsAnnotation)
+		setAnnotations(converted, annotations)
+	}
+	out, err := runtime.DefaultUnstructuredConverter.ToUnstructured(alpha)
+	if err != nil {
+		return fmt.Errorf("failed to encode spec: %v", err)
+	}
+	converted.Object["spec"] = out
+	return nil
+}
+
+// setAnnotations sets the annotations of a resource, leaving them out rather than empty if there
+// are none, so that converting a resource back and forth gives it back as it was.
+func setAnnotations(obj *unstructured.Unstructured, annotations map[string]string) {
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+	obj.SetAnnotations(annotations)
+}
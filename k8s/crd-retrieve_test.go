@@ -0,0 +1,43 @@
+package k8s
+
+import (
+	"testing"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func TestCheckConversion(t *testing.T) {
+	crd := func(strategy apiextensionsv1.ConversionStrategyType, storedVersions ...string) apiextensionsv1.CustomResourceDefinition {
+		return apiextensionsv1.CustomResourceDefinition{
+			Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+				Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+					{Name: "v1alpha1", Served: true},
+					{Name: "v1beta1", Served: true, Storage: true},
+				},
+				Conversion: &apiextensionsv1.CustomResourceConversion{Strategy: strategy},
+			},
+			Status: apiextensionsv1.CustomResourceDefinitionStatus{StoredVersions: storedVersions},
+		}
+	}
+	tests := []struct {
+		name           string
+		crd            apiextensionsv1.CustomResourceDefinition
+		apiVersion     string
+		webhookEnabled bool
+		wantErr        bool
+	}{
+		{"stored in the API version", crd(apiextensionsv1.WebhookConverter, "v1beta1"), "v1beta1", false, false},
+		{"stored in an older version", crd(apiextensionsv1.WebhookConverter, "v1alpha1", "v1beta1"), "v1beta1", false, true},
+		{"storage version differs", crd(apiextensionsv1.WebhookConverter, "v1beta1"), "v1alpha1", false, true},
+		{"webhook enabled", crd(apiextensionsv1.WebhookConverter, "v1alpha1", "v1beta1"), "v1beta1", true, false},
+		{"no conversion", crd(apiextensionsv1.NoneConverter, "v1alpha1", "v1beta1"), "v1beta1", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setAPIVersion(t, tt.apiVersion)
+			if err := checkConversion(tt.crd, tt.webhookEnabled); (err != nil) != tt.wantErr {
+				t.Errorf("checkConversion() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
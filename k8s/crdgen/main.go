@@ -0,0 +1,551 @@
+// crdgen generates the spec schemas of the metrifuge CRDs in k8s/crds from the Go types in k8s/api,
+// so that the two can't drift apart. Run it from the root of the module after changing the types,
+// or with -check to fail if the CRDs are stale:
+//
+//	go run ./k8s/crdgen
+//	go run ./k8s/crdgen -check
+//
+// Fields are described by their doc comments, or their line comments if they have none, leaving
+// out TODOs, and are required unless their json tag has omitempty or omitzero. Markers in doc
+// comments add validation the way they do for controller-gen:
+//
+//	+kubebuilder:validation:Enum=A;B               allowed values, of the items of a list
+//	+kubebuilder:validation:Minimum=1              minimum of an integer
+//	+kubebuilder:validation:Pattern=`^[a-z]+$`     pattern of a string, of the items of a list
+//	+kubebuilder:validation:XValidation:rule="has(self.a)",message="a is required"   CEL rule of a type
+//	+kubebuilder:default=value                     default value
+//	+optional, +required                           override omitempty and omitzero
+//
+// Types that refer to themselves, like compound conditions, are described down to their first
+// repetition, below which unknown fields are preserved and left for metrifuge to validate. The
+// rest of the CRDs, statuses included, is written by hand and left as it is.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const modulePath = "github.com/devon-caron/metrifuge"
+
+// Go type of the spec of a CRD version
+type specType struct {
+	pkg      string
+	typeName string
+}
+
+// CRDs and the Go types of the specs of their versions
+var crds = []struct {
+	file     string
+	versions map[string]specType
+}{
+	{"k8s/crds/ruleset.crd.yaml", map[string]specType{
+		"v1alpha1": {"k8s/api/ruleset", "RuleSetSpec"},
+		"v1beta1":  {"k8s/api/metrifuge/v1beta1", "RuleSetSpec"},
+	}},
+	{"k8s/crds/logsource.crd.yaml", map[string]specType{
+		"v1alpha1": {"k8s/api/log_source", "LogSourceSpec"},
+		"v1beta1":  {"k8s/api/log_source", "LogSourceSpec"},
+	}},
+	{"k8s/crds/exporter.crd.yaml", map[string]specType{
+		"v1alpha1": {"k8s/api/exporter", "ExporterSpec"},
+		"v1beta1":  {"k8s/api/exporter", "ExporterSpec"},
+	}},
+}
+
+func main() {
+	root := flag.String("root", ".", "root of the metrifuge module")
+	check := flag.Bool("check", false, "fail if a CRD is not up to date instead of writing it")
+	flag.Parse()
+
+	g := &generator{root: *root, packages: make(map[string]map[string]*typeDecl)}
+	stale := false
+	for _, crd := range crds {
+		specs := make(map[string]*schema)
+		for version, t := range crd.versions {
+			spec, err := g.describeNamed(t.pkg, t.typeName)
+			if err != nil {
+				fail("failed to describe %s.%s: %v", t.pkg, t.typeName, err)
+			}
+			specs[version] = spec
+		}
+		path := filepath.Join(*root, crd.file)
+		current, err := os.ReadFile(path)
+		if err != nil {
+			fail("failed to read %s: %v", crd.file, err)
+		}
+		generated, err := replaceSpecs(current, specs)
+		if err != nil {
+			fail("failed to update %s: %v", crd.file, err)
+		}
+		if bytes.Equal(current, generated) {
+			continue
+		}
+		if *check {
+			fmt.Fprintf(os.Stderr, "%s is stale, run go run ./k8s/crdgen\n", crd.file)
+			stale = true
+			continue
+		}
+		if err := os.WriteFile(path, generated, 0o644); err != nil {
+			fail("failed to write %s: %v", crd.file, err)
+		}
+		fmt.Printf("generated %s\n", crd.file)
+	}
+	if stale {
+		os.Exit(1)
+	}
+}
+
+func fail(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
+
+// schema is the part of an OpenAPI v3 schema that CRDs use, in the order its keys are written
+type schema struct {
+	Type                  string
+	Format                string
+	Description           string
+	Enum                  []string
+	Default               string
+	Minimum               *int64
+	Pattern               string
+	Required              []string
+	Validations           []validation
+	Properties            []property
+	Items                 *schema
+	AdditionalProperties  *schema
+	PreserveUnknownFields bool
+}
+
+type property struct {
+	name   string
+	schema *schema
+}
+
+type validation struct {
+	rule    string
+	message string
+}
+
+type typeDecl struct {
+	spec    *ast.TypeSpec
+	doc     *ast.CommentGroup
+	imports map[string]string // directories of the packages the declaring file imports, by name
+}
+
+type generator struct {
+	root     string
+	packages map[string]map[string]*typeDecl // declarations of each package, by directory and name
+	stack    []string                        // named types being described, to stop at their repetition
+}
+
+// load parses the type declarations of the package in dir.
+func (g *generator) load(dir string) (map[string]*typeDecl, error) {
+	if decls, ok := g.packages[dir]; ok {
+		return decls, nil
+	}
+	pkgs, err := parser.ParseDir(token.NewFileSet(), filepath.Join(g.root, dir), func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	decls := make(map[string]*typeDecl)
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			imports := make(map[string]string)
+			for _, imp := range file.Imports {
+				path, _ := strconv.Unquote(imp.Path.Value)
+				name := filepath.Base(path)
+				if imp.Name != nil {
+					name = imp.Name.Name
+				}
+				if rel, ok := strings.CutPrefix(path, modulePath+"/"); ok {
+					imports[name] = rel
+				}
+			}
+			for _, decl := range file.Decls {
+				gen, ok := decl.(*ast.GenDecl)
+				if !ok || gen.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range gen.Specs {
+					ts := spec.(*ast.TypeSpec)
+					doc := ts.Doc
+					if doc == nil {
+						doc = gen.Doc
+					}
+					decls[ts.Name.Name] = &typeDecl{spec: ts, doc: doc, imports: imports}
+				}
+			}
+		}
+	}
+	g.packages[dir] = decls
+	return decls, nil
+}
+
+// describeNamed describes a named type of the package in dir.
+func (g *generator) describeNamed(dir, name string) (*schema, error) {
+	decls, err := g.load(dir)
+	if err != nil {
+		return nil, err
+	}
+	decl, ok := decls[name]
+	if !ok {
+		return nil, fmt.Errorf("type %s not found in %s", name, dir)
+	}
+
+	key := dir + "." + name
+	for _, described := range g.stack {
+		if described == key {
+			return &schema{Type: "object", PreserveUnknownFields: true}, nil
+		}
+	}
+	g.stack = append(g.stack, key)
+	defer func() { g.stack = g.stack[:len(g.stack)-1] }()
+
+	s, err := g.describe(decl.spec.Type, dir, decl.imports)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if _, err := applyMarkers(s, decl.doc, nil); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return s, nil
+}
+
+// describe describes a type expression of a file of the package in dir.
+func (g *generator) describe(expr ast.Expr, dir string, imports map[string]string) (*schema, error) {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		switch t.Name {
+		case "string":
+			return &schema{Type: "string"}, nil
+		case "bool":
+			return &schema{Type: "boolean"}, nil
+		case "int", "uint", "int8", "int16", "uint8", "uint16":
+			return &schema{Type: "integer"}, nil
+		case "int32", "uint32":
+			return &schema{Type: "integer", Format: "int32"}, nil
+		case "int64", "uint64":
+			return &schema{Type: "integer", Format: "int64"}, nil
+		case "float32", "float64":
+			return &schema{Type: "number"}, nil
+		case "any":
+			return &schema{PreserveUnknownFields: true}, nil
+		}
+		return g.describeNamed(dir, t.Name)
+	case *ast.SelectorExpr:
+		pkg, ok := t.X.(*ast.Ident)
+		if !ok || imports[pkg.Name] == "" {
+			return nil, fmt.Errorf("unsupported type %s.%s", pkg, t.Sel.Name)
+		}
+		return g.describeNamed(imports[pkg.Name], t.Sel.Name)
+	case *ast.StarExpr:
+		return g.describe(t.X, dir, imports)
+	case *ast.ArrayType:
+		if t.Len != nil {
+			return nil, fmt.Errorf("arrays are not supported")
+		}
+		items, err := g.describe(t.Elt, dir, imports)
+		if err != nil {
+			return nil, err
+		}
+		return &schema{Type: "array", Items: items}, nil
+	case *ast.MapType:
+		if key, ok := t.Key.(*ast.Ident); !ok || key.Name != "string" {
+			return nil, fmt.Errorf("only maps with string keys are supported")
+		}
+		values, err := g.describe(t.Value, dir, imports)
+		if err != nil {
+			return nil, err
+		}
+		return &schema{Type: "object", AdditionalProperties: values}, nil
+	case *ast.InterfaceType:
+		return &schema{PreserveUnknownFields: true}, nil
+	case *ast.StructType:
+		return g.describeStruct(t, dir, imports)
+	}
+	return nil, fmt.Errorf("unsupported type %T", expr)
+}
+
+func (g *generator) describeStruct(st *ast.StructType, dir string, imports map[string]string) (*schema, error) {
+	s := &schema{Type: "object"}
+	for _, field := range st.Fields.List {
+		tag := reflect.StructTag("")
+		if field.Tag != nil {
+			value, _ := strconv.Unquote(field.Tag.Value)
+			tag = reflect.StructTag(value)
+		}
+		name, options, _ := strings.Cut(tag.Get("json"), ",")
+		if name == "-" || len(field.Names) > 0 && !field.Names[0].IsExported() {
+			continue
+		}
+
+		fieldSchema, err := g.describe(field.Type, dir, imports)
+		if err != nil {
+			return nil, err
+		}
+		// Embedded structs without a name of their own are inlined, as encoding/json does
+		if len(field.Names) == 0 && name == "" {
+			s.Properties = append(s.Properties, fieldSchema.Properties...)
+			s.Required = append(s.Required, fieldSchema.Required...)
+			continue
+		}
+
+		for _, ident := range field.Names {
+			if name == "" {
+				name = ident.Name
+			}
+			// Named types are shared, so a field's own description and markers go on a copy
+			copied := *fieldSchema
+			required, err := applyMarkers(&copied, field.Doc, field.Comment)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", ident.Name, err)
+			}
+			if required == nil {
+				optional := strings.Contains(","+options+",", ",omitempty,") || strings.Contains(","+options+",", ",omitzero,")
+				required = &[]bool{!optional}[0]
+			}
+			if *required {
+				s.Required = append(s.Required, name)
+			}
+			s.Properties = append(s.Properties, property{name: name, schema: &copied})
+		}
+	}
+	return s, nil
+}
+
+var xValidation = regexp.MustCompile(`^rule=("(?:[^"\\]|\\.)*"),message=("(?:[^"\\]|\\.)*")$`)
+
+// applyMarkers sets the description and validation of a schema from the doc and line comments of
+// a type or field, and returns whether +optional or +required says the field is required.
+func applyMarkers(s *schema, doc, line *ast.CommentGroup) (*bool, error) {
+	var required *bool
+	var description []string
+	if doc != nil {
+		for _, c := range doc.List {
+			text := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+			if strings.HasPrefix(text, "TODO") {
+				continue
+			}
+			if !strings.HasPrefix(text, "+") {
+				description = append(description, text)
+				continue
+			}
+
+			// Enums and patterns of lists restrict their items
+			target := s
+			if s.Items != nil {
+				target = &[]schema{*s.Items}[0]
+				s.Items = target
+			}
+			marker, value, _ := strings.Cut(text, "=")
+			switch marker {
+			case "+optional":
+				required = &[]bool{false}[0]
+			case "+required":
+				required = &[]bool{true}[0]
+			case "+kubebuilder:validation:Enum":
+				target.Enum = strings.Split(value, ";")
+			case "+kubebuilder:validation:Pattern":
+				target.Pattern = strings.Trim(value, "`")
+			case "+kubebuilder:validation:Minimum":
+				minimum, err := strconv.ParseInt(value, 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid minimum %q: %v", value, err)
+				}
+				s.Minimum = &minimum
+			case "+kubebuilder:default":
+				if unquoted, err := strconv.Unquote(value); err == nil {
+					value = unquoted
+				}
+				s.Default = value
+			case "+kubebuilder:validation:XValidation:rule":
+				match := xValidation.FindStringSubmatch("rule=" + value)
+				if match == nil {
+					return nil, fmt.Errorf("invalid XValidation %q", text)
+				}
+				rule, _ := strconv.Unquote(match[1])
+				message, _ := strconv.Unquote(match[2])
+				s.Validations = append(s.Validations, validation{rule: rule, message: message})
+			case "+kubebuilder:pruning:PreserveUnknownFields":
+				s.PreserveUnknownFields = true
+			default:
+				if strings.HasPrefix(marker, "+kubebuilder:") {
+					return nil, fmt.Errorf("unknown marker %q", text)
+				}
+			}
+		}
+	}
+	if len(description) == 0 && line != nil {
+		description = append(description, strings.TrimSpace(line.Text()))
+	}
+	if text := strings.Join(description, " "); text != "" {
+		s.Description = strings.ToUpper(text[:1]) + text[1:]
+	}
+	return required, nil
+}
+
+// node writes a schema as YAML.
+func (s *schema) node() *yaml.Node {
+	n := &yaml.Node{Kind: yaml.MappingNode}
+	add := func(key string, value *yaml.Node) {
+		n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+	}
+	scalar := func(tag, value string) *yaml.Node {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+	}
+	strings := func(values []string) *yaml.Node {
+		seq := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		for _, v := range values {
+			seq.Content = append(seq.Content, scalar("!!str", v))
+		}
+		return seq
+	}
+
+	if s.Type != "" {
+		add("type", scalar("!!str", s.Type))
+	}
+	if s.Format != "" {
+		add("format", scalar("!!str", s.Format))
+	}
+	if s.Description != "" {
+		add("description", scalar("!!str", s.Description))
+	}
+	if len(s.Enum) > 0 {
+		add("enum", strings(s.Enum))
+	}
+	if s.Default != "" {
+		tag := "!!str"
+		switch s.Type {
+		case "integer":
+			tag = "!!int"
+		case "boolean":
+			tag = "!!bool"
+		}
+		add("default", scalar(tag, s.Default))
+	}
+	if s.Minimum != nil {
+		add("minimum", scalar("!!int", strconv.FormatInt(*s.Minimum, 10)))
+	}
+	if s.Pattern != "" {
+		add("pattern", scalar("!!str", s.Pattern))
+	}
+	if len(s.Required) > 0 {
+		required := strings(s.Required)
+		required.Style = 0
+		add("required", required)
+	}
+	if len(s.Validations) > 0 {
+		seq := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, v := range s.Validations {
+			seq.Content = append(seq.Content, &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+				scalar("!!str", "rule"), scalar("!!str", v.rule),
+				scalar("!!str", "message"), scalar("!!str", v.message),
+			}})
+		}
+		add("x-kubernetes-validations", seq)
+	}
+	if len(s.Properties) > 0 {
+		props := &yaml.Node{Kind: yaml.MappingNode}
+		for _, p := range s.Properties {
+			props.Content = append(props.Content, scalar("!!str", p.name), p.schema.node())
+		}
+		add("properties", props)
+	}
+	if s.Items != nil {
+		add("items", s.Items.node())
+	}
+	if s.AdditionalProperties != nil {
+		add("additionalProperties", s.AdditionalProperties.node())
+	}
+	if s.PreserveUnknownFields {
+		add("x-kubernetes-preserve-unknown-fields", scalar("!!bool", "true"))
+	}
+	return n
+}
+
+// replaceSpecs replaces the spec schema of every version of a CRD with the one generated for it.
+func replaceSpecs(crd []byte, specs map[string]*schema) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(crd, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, fmt.Errorf("empty document")
+	}
+	versions := lookup(doc.Content[0], "spec", "versions")
+	if versions == nil || versions.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("no versions found")
+	}
+	for _, version := range versions.Content {
+		name := lookup(version, "name")
+		if name == nil {
+			return nil, fmt.Errorf("version without a name")
+		}
+		spec, ok := specs[name.Value]
+		if !ok {
+			return nil, fmt.Errorf("no spec type for version %s", name.Value)
+		}
+		properties := lookup(version, "schema", "openAPIV3Schema", "properties")
+		if properties == nil {
+			return nil, fmt.Errorf("no openAPIV3Schema properties found in version %s", name.Value)
+		}
+		replaced := false
+		for i := 0; i+1 < len(properties.Content); i += 2 {
+			if properties.Content[i].Value == "spec" {
+				properties.Content[i+1] = spec.node()
+				replaced = true
+			}
+		}
+		if !replaced {
+			return nil, fmt.Errorf("no spec schema found in version %s", name.Value)
+		}
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// lookup follows keys down nested mappings.
+func lookup(n *yaml.Node, keys ...string) *yaml.Node {
+	for _, key := range keys {
+		if n.Kind != yaml.MappingNode {
+			return nil
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == key {
+				next = n.Content[i+1]
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		n = next
+	}
+	return n
+}
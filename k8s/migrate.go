@@ -0,0 +1,101 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+// Resources of the metrifuge CRDs, whose stored objects MigrateStorageVersion rewrites
+var metrifugeResources = []string{"rulesets", "logsources", "exporters"}
+
+// MigrateStorageVersion stores every RuleSet, LogSource and Exporter in the storage version of its
+// CRD, so that the versions they were stored in before can be removed from the CRD. The resources
+// of a CRD whose status lists other stored versions are updated as they are, which the API server
+// stores in the storage version, and the CRD's storedVersions is then set to the storage version
+// alone. RuleSets are read through the conversion webhook, which must be serving.
+func MigrateStorageVersion(restConfig *rest.Config) error {
+	crdClient, err := apiextensionsclientset.NewForConfig(restConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create clientset: %v", err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create dynamic client: %v", err)
+	}
+
+	for _, resource := range metrifugeResources {
+		if err := migrateResource(context.TODO(), crdClient, dynamicClient, resource); err != nil {
+			return fmt.Errorf("failed to migrate %s: %w", resource, err)
+		}
+	}
+	return nil
+}
+
+func migrateResource(ctx context.Context, crdClient apiextensionsclientset.Interface, dynamicClient dynamic.Interface, resource string) error {
+	crds := crdClient.ApiextensionsV1().CustomResourceDefinitions()
+	crd, err := crds.Get(ctx, resource+".metrifuge.com", metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get CRD: %v", err)
+	}
+	storage := ""
+	for _, version := range crd.Spec.Versions {
+		if version.Storage {
+			storage = version.Name
+		}
+	}
+	if storage == "" {
+		return fmt.Errorf("CRD %s has no storage version", crd.Name)
+	}
+	if slices.Equal(crd.Status.StoredVersions, []string{storage}) {
+		log.Debugf("%s are all stored as %s", resource, storage)
+		return nil
+	}
+
+	log.Infof("migrating %s stored as %v to %s", resource, crd.Status.StoredVersions, storage)
+	client := dynamicClient.Resource(schema.GroupVersionResource{Group: "metrifuge.com", Version: storage, Resource: resource})
+	migrated, failed := 0, 0
+	options := metav1.ListOptions{Limit: 500}
+	for {
+		list, err := client.List(ctx, options)
+		if err != nil {
+			return fmt.Errorf("failed to list %s: %v", resource, err)
+		}
+		for i := range list.Items {
+			item := &list.Items[i]
+			_, err := client.Namespace(item.GetNamespace()).Update(ctx, item, metav1.UpdateOptions{})
+			// Resources changed or deleted since they were listed are stored in the storage version already
+			if err != nil && !apierrors.IsConflict(err) && !apierrors.IsNotFound(err) {
+				log.Warnf("failed to migrate %s %s/%s: %v", resource, item.GetNamespace(), item.GetName(), err)
+				failed++
+				continue
+			}
+			migrated++
+		}
+		if list.GetContinue() == "" {
+			break
+		}
+		options.Continue = list.GetContinue()
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d %s could not be migrated to %s", failed, failed+migrated, resource, storage)
+	}
+
+	crd, err = crds.Get(ctx, crd.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get CRD: %v", err)
+	}
+	crd.Status.StoredVersions = []string{storage}
+	if _, err := crds.UpdateStatus(ctx, crd, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update stored versions of CRD %s: %v", crd.Name, err)
+	}
+	log.Infof("migrated %d %s to %s", migrated, resource, storage)
+	return nil
+}
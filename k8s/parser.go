@@ -2,12 +2,16 @@ package k8s
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 
+	"github.com/devon-caron/metrifuge/global"
 	e "github.com/devon-caron/metrifuge/k8s/api/exporter"
 	ls "github.com/devon-caron/metrifuge/k8s/api/log_source"
+	"github.com/devon-caron/metrifuge/k8s/api/metrifuge/v1beta1"
 	"github.com/devon-caron/metrifuge/k8s/api/ruleset"
 	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
 	"gopkg.in/yaml.v3"
 )
@@ -56,12 +60,39 @@ func ParseRules(data []byte) ([]ruleset.RuleSet, error) {
 		if err := yaml.Unmarshal(doc, &rule); err != nil {
 			return nil, fmt.Errorf("failed to parse capturegroup document %d: %w", i+1, err)
 		}
+		if rule.APIVersion == v1beta1.SchemeGroupVersion.String() {
+			if rule, err = parseV1beta1RuleSet(doc); err != nil {
+				return nil, fmt.Errorf("failed to parse capturegroup document %d: %w", i+1, err)
+			}
+		}
 		rules = append(rules, rule)
 	}
 
 	return rules, nil
 }
 
+// parseV1beta1RuleSet parses a RuleSet of v1beta1, whose conditionals don't fit the v1alpha1 types
+// other RuleSets are parsed into, by converting it to v1alpha1 the way the API server would.
+func parseV1beta1RuleSet(doc []byte) (ruleset.RuleSet, error) {
+	var object map[string]any
+	if err := yaml.Unmarshal(doc, &object); err != nil {
+		return ruleset.RuleSet{}, err
+	}
+	data, err := json.Marshal(object)
+	if err != nil {
+		return ruleset.RuleSet{}, err
+	}
+	crdResource := &unstructured.Unstructured{}
+	if err := crdResource.UnmarshalJSON(data); err != nil {
+		return ruleset.RuleSet{}, err
+	}
+	resource, err := ConvertResource(crdResource, global.RULESET_CRD_NAME)
+	if err != nil {
+		return ruleset.RuleSet{}, err
+	}
+	return resource.(ruleset.RuleSet), nil
+}
+
 func ParseLogSources(data []byte) ([]ls.LogSource, error) {
 	documents, err := parseDocuments(data)
 	if err != nil {
@@ -0,0 +1,55 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/devon-caron/metrifuge/global"
+	"github.com/devon-caron/metrifuge/k8s/api/metrifuge/v1alpha1"
+	"github.com/devon-caron/metrifuge/k8s/client/clientset/versioned"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// PatchResourceStatus merge-patches the status subresource of a metrifuge resource, in the version
+// of MF_API_VERSION. The namespace of Exporters, which are cluster-scoped, is ignored.
+func PatchResourceStatus(client versioned.Interface, kindPlural, namespace, name string, status map[string]any) error {
+	patch, err := json.Marshal(map[string]any{"status": status})
+	if err != nil {
+		return fmt.Errorf("failed to encode status patch: %v", err)
+	}
+
+	log.Debugf("patching status of %s %s/%s: %s", kindPlural, namespace, name, patch)
+	ctx := context.TODO()
+	options := metav1.PatchOptions{}
+	if global.API_VERSION == v1alpha1.SchemeGroupVersion.Version {
+		metrifuge := client.MetrifugeV1alpha1()
+		switch kindPlural {
+		case "rulesets":
+			_, err = metrifuge.RuleSets(namespace).Patch(ctx, name, types.MergePatchType, patch, options, "status")
+		case "logsources":
+			_, err = metrifuge.LogSources(namespace).Patch(ctx, name, types.MergePatchType, patch, options, "status")
+		case "exporters":
+			_, err = metrifuge.Exporters().Patch(ctx, name, types.MergePatchType, patch, options, "status")
+		default:
+			return fmt.Errorf("unknown kind: %s", kindPlural)
+		}
+	} else {
+		metrifuge := client.MetrifugeV1beta1()
+		switch kindPlural {
+		case "rulesets":
+			_, err = metrifuge.RuleSets(namespace).Patch(ctx, name, types.MergePatchType, patch, options, "status")
+		case "logsources":
+			_, err = metrifuge.LogSources(namespace).Patch(ctx, name, types.MergePatchType, patch, options, "status")
+		case "exporters":
+			_, err = metrifuge.Exporters().Patch(ctx, name, types.MergePatchType, patch, options, "status")
+		default:
+			return fmt.Errorf("unknown kind: %s", kindPlural)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to patch status of %s %s/%s: %v", kindPlural, namespace, name, err)
+	}
+	return nil
+}
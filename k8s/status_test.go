@@ -0,0 +1,78 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	"github.com/devon-caron/metrifuge/global"
+	"github.com/devon-caron/metrifuge/k8s/api"
+	"github.com/devon-caron/metrifuge/k8s/api/metrifuge/v1alpha1"
+	"github.com/devon-caron/metrifuge/k8s/api/metrifuge/v1beta1"
+	"github.com/devon-caron/metrifuge/k8s/api/ruleset"
+	"github.com/devon-caron/metrifuge/k8s/client/clientset/versioned/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func setAPIVersion(t *testing.T, version string) {
+	t.Helper()
+	previous := global.API_VERSION
+	global.API_VERSION = version
+	t.Cleanup(func() { global.API_VERSION = previous })
+}
+
+func TestPatchResourceStatusRuleSet(t *testing.T) {
+	setAPIVersion(t, v1alpha1.SchemeGroupVersion.Version)
+	selector := &api.Selector{MatchLabels: map[string]string{"app": "test"}}
+	client := fake.NewSimpleClientset(&v1alpha1.RuleSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "rules"},
+		Spec:       ruleset.RuleSetSpec{Selector: selector},
+		Status:     v1alpha1.RuleSetStatus{LastError: "stale"},
+	})
+
+	status := map[string]any{"rulesMatched": 3, "ruleErrors": map[string]any{"0": 1}}
+	if err := PatchResourceStatus(client, "rulesets", "default", "rules", status); err != nil {
+		t.Fatalf("PatchResourceStatus() = %v", err)
+	}
+
+	got, err := client.MetrifugeV1alpha1().RuleSets("default").Get(context.TODO(), "rules", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.RulesMatched != 3 || got.Status.RuleErrors["0"] != 1 {
+		t.Errorf("status = %+v, want the patched counts", got.Status)
+	}
+	// A merge patch leaves the fields it doesn't set alone
+	if got.Status.LastError != "stale" || got.Spec.Selector == nil {
+		t.Errorf("patch dropped fields it didn't set: %+v", got)
+	}
+}
+
+func TestPatchResourceStatusExporterV1beta1(t *testing.T) {
+	setAPIVersion(t, v1beta1.SchemeGroupVersion.Version)
+	client := fake.NewSimpleClientset(&v1beta1.Exporter{ObjectMeta: metav1.ObjectMeta{Name: "otlp"}})
+
+	status := map[string]any{"circuitBreaker": map[string]any{"state": "Open"}}
+	// Exporters are cluster-scoped, so the namespace is ignored
+	if err := PatchResourceStatus(client, "exporters", "default", "otlp", status); err != nil {
+		t.Fatalf("PatchResourceStatus() = %v", err)
+	}
+
+	got, err := client.MetrifugeV1beta1().Exporters().Get(context.TODO(), "otlp", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.CircuitBreaker == nil || got.Status.CircuitBreaker.State != "Open" {
+		t.Errorf("status = %+v, want an open circuit breaker", got.Status)
+	}
+}
+
+func TestPatchResourceStatusErrors(t *testing.T) {
+	setAPIVersion(t, v1alpha1.SchemeGroupVersion.Version)
+	client := fake.NewSimpleClientset()
+	if err := PatchResourceStatus(client, "logsources", "default", "missing", map[string]any{}); err == nil {
+		t.Error("PatchResourceStatus() of a missing LogSource succeeded")
+	}
+	if err := PatchResourceStatus(client, "pipelines", "default", "app", map[string]any{}); err == nil {
+		t.Error("PatchResourceStatus() of an unknown kind succeeded")
+	}
+}
@@ -0,0 +1,177 @@
+package k8s
+
+import (
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/devon-caron/metrifuge/global"
+	"github.com/devon-caron/metrifuge/k8s/api"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+type EventType string
+
+const (
+	ResourceAdded   EventType = "Added"
+	ResourceUpdated EventType = "Updated"
+	ResourceDeleted EventType = "Deleted"
+)
+
+// ResourceEvent is a change to a single metrifuge resource. Resource is nil for deleted resources.
+type ResourceEvent struct {
+	Type      EventType
+	Kind      string
+	Namespace string
+	Name      string
+	Resource  api.MetrifugeK8sResource
+}
+
+/**
+ * ResourceWatcher keeps a shared informer for each metrifuge CRD, so that resources are listed
+ * once and then kept up to date through watches instead of being re-listed periodically. Changes
+ * are delivered in order through Events.
+ */
+type ResourceWatcher struct {
+	factory   dynamicinformer.DynamicSharedInformerFactory
+	informers map[string]cache.SharedIndexInformer // Informers by CRD kind
+	events    chan ResourceEvent
+	stopCh    <-chan struct{} // closed once the informers stop, after which events are no longer delivered
+}
+
+func (rw *ResourceWatcher) Initialize(k8sClient *api.K8sClientWrapper, version string, resync time.Duration) error {
+	dynamicClient, err := dynamic.NewForConfig(k8sClient.Config())
+	if err != nil {
+		return fmt.Errorf("failed to create dynamic client: %v", err)
+	}
+
+	rw.factory = dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, resync)
+	rw.informers = make(map[string]cache.SharedIndexInformer)
+	rw.events = make(chan ResourceEvent, 100)
+
+	kinds := map[string]string{
+		global.LOGSOURCE_CRD_NAME: "logsources",
+		global.RULESET_CRD_NAME:   "rulesets",
+		global.EXPORTER_CRD_NAME:  "exporters",
+	}
+	for kind, kindPlural := range kinds {
+		gvr := schema.GroupVersionResource{
+			Group:    "metrifuge.com",
+			Version:  version,
+			Resource: kindPlural,
+		}
+		informer := rw.factory.ForResource(gvr).Informer()
+		if _, err := informer.AddEventHandler(rw.eventHandler(kind)); err != nil {
+			return fmt.Errorf("failed to add event handler for %s: %v", kindPlural, err)
+		}
+		rw.informers[kind] = informer
+	}
+
+	return nil
+}
+
+// Start runs the informers until stopCh is closed, and waits for their caches to fill.
+func (rw *ResourceWatcher) Start(stopCh <-chan struct{}) error {
+	rw.stopCh = stopCh
+	rw.factory.Start(stopCh)
+	for kind, synced := range rw.factory.WaitForCacheSync(stopCh) {
+		if !synced {
+			return fmt.Errorf("failed to sync informer cache for %s", kind.Resource)
+		}
+	}
+	return nil
+}
+
+// Events returns the channel that resource changes are delivered on. The initial list of each
+// CRD is delivered as ResourceAdded events.
+func (rw *ResourceWatcher) Events() <-chan ResourceEvent {
+	return rw.events
+}
+
+// List returns the resources of a kind currently in the informer cache.
+func (rw *ResourceWatcher) List(kind string) ([]api.MetrifugeK8sResource, error) {
+	informer, ok := rw.informers[kind]
+	if !ok {
+		return nil, fmt.Errorf("no informer for kind %s", kind)
+	}
+
+	var resources []api.MetrifugeK8sResource
+	for _, obj := range informer.GetStore().List() {
+		crdResource, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			log.Warnf("unexpected object of type %T in %s informer cache", obj, kind)
+			continue
+		}
+		resource, err := ConvertResource(crdResource, kind)
+		if err != nil {
+			log.Warnf("failed to get resource: %v", err)
+			continue
+		}
+		resources = append(resources, resource)
+	}
+	return resources, nil
+}
+
+func (rw *ResourceWatcher) eventHandler(kind string) cache.ResourceEventHandlerFuncs {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj any) {
+			rw.send(ResourceAdded, kind, obj)
+		},
+		UpdateFunc: func(oldObj, newObj any) {
+			oldResource, ok := oldObj.(*unstructured.Unstructured)
+			if !ok {
+				return
+			}
+			newResource, ok := newObj.(*unstructured.Unstructured)
+			if !ok {
+				return
+			}
+			// Status writes and resyncs leave the generation alone; only spec and label changes matter
+			if oldResource.GetGeneration() == newResource.GetGeneration() &&
+				maps.Equal(oldResource.GetLabels(), newResource.GetLabels()) {
+				return
+			}
+			rw.send(ResourceUpdated, kind, newObj)
+		},
+		DeleteFunc: func(obj any) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			rw.send(ResourceDeleted, kind, obj)
+		},
+	}
+}
+
+func (rw *ResourceWatcher) send(eventType EventType, kind string, obj any) {
+	crdResource, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		log.Warnf("ignoring %s event for object of type %T", eventType, obj)
+		return
+	}
+
+	event := ResourceEvent{
+		Type:      eventType,
+		Kind:      kind,
+		Namespace: crdResource.GetNamespace(),
+		Name:      crdResource.GetName(),
+	}
+	if eventType != ResourceDeleted {
+		resource, err := ConvertResource(crdResource, kind)
+		if err != nil {
+			log.Warnf("ignoring %s event for %s %s/%s: %v", eventType, kind, event.Namespace, event.Name, err)
+			return
+		}
+		event.Resource = resource
+	}
+
+	log.Debugf("%s %s %s/%s", eventType, kind, event.Namespace, event.Name)
+	// Nothing reads events once shutdown started, and the informers wait for their handlers to return
+	select {
+	case rw.events <- event:
+	case <-rw.stopCh:
+	}
+}
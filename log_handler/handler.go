@@ -8,6 +8,7 @@ import (
 	ls "github.com/devon-caron/metrifuge/k8s/api/log_source"
 	"github.com/devon-caron/metrifuge/k8s/api/ruleset"
 	"github.com/devon-caron/metrifuge/log_handler/log_processor"
+	"github.com/devon-caron/metrifuge/status_tracker"
 	"github.com/sirupsen/logrus"
 	"k8s.io/client-go/rest"
 )
@@ -21,22 +22,21 @@ type LogHandler struct {
 	log             *logrus.Logger
 	wg              sync.WaitGroup
 	once            sync.Once
-	sourceStopChans map[string]chan struct{} // Map of source names to their stop channels
-	mu              sync.RWMutex             // Protects the source maps
-	itemBucket      []api.ProcessedDataItem  // Current batch of processed items
+	sourceStopChans map[string]chan struct{}     // Stop channels of the sources, by namespace/name
+	mu              sync.RWMutex                 // Protects the source maps
+	lines           chan<- log_processor.LogLine // Where every line read from a source is sent
 }
 
-func (lh *LogHandler) Initialize(initialSources []ls.LogSource, initialRuleSets []ruleset.RuleSet, log *logrus.Logger,
-	kubeConfig *rest.Config, k8sClient *api.K8sClientWrapper) error {
+func (lh *LogHandler) Initialize(initialSources []ls.LogSource, initialRuleSets []ruleset.RuleSet, lp *log_processor.LogProcessor,
+	lines chan<- log_processor.LogLine, log *logrus.Logger, kubeConfig *rest.Config, k8sClient *api.K8sClientWrapper) error {
 	lh.once.Do(func() {
 		lh.log = log
 		log.Info("initialized log handler")
 		lh.sourceStopChans = make(map[string]chan struct{})
-		log.Info("initialized log handler sources and buckets")
-		lh.lp = &log_processor.LogProcessor{}
-		lh.lp.Initialize(initialSources, initialRuleSets, log)
-		lh.itemBucket = make([]api.ProcessedDataItem, 0)
-		lh.Update(initialSources, k8sClient)
+		log.Info("initialized log handler sources")
+		lh.lp = lp
+		lh.lines = lines
+		lh.Update(initialSources, initialRuleSets, k8sClient)
 
 		log.Info("log handler updated successfully")
 	})
@@ -44,21 +44,26 @@ func (lh *LogHandler) Initialize(initialSources []ls.LogSource, initialRuleSets
 	return nil
 }
 
-func (lh *LogHandler) Update(sources []ls.LogSource, k8sClient *api.K8sClientWrapper) error {
+// Update applies the current log sources and rule sets. Rules are swapped under running sources,
+// new sources are started and removed sources are stopped.
+func (lh *LogHandler) Update(sources []ls.LogSource, ruleSets []ruleset.RuleSet, k8sClient *api.K8sClientWrapper) error {
 	lh.log.Debug("loghandler update func called")
 
-	// Create a set of current source names
+	// Swap rules first, so that new sources start with their rules in place
+	lh.lp.Update(sources, ruleSets, k8sClient)
+
+	// Create a set of current source keys, since sources in different namespaces can share a name
 	currentSources := make(map[string]bool)
 	for _, source := range sources {
-		currentSources[source.Metadata.Name] = true
+		currentSources[status_tracker.Key(source.Metadata.Namespace, source.Metadata.Name)] = true
 	}
 
 	// Stop and remove any sources that are no longer present
 	lh.mu.Lock()
-	for name, stopCh := range lh.sourceStopChans {
-		if !currentSources[name] {
+	for key, stopCh := range lh.sourceStopChans {
+		if !currentSources[key] {
 			close(stopCh)
-			delete(lh.sourceStopChans, name)
+			delete(lh.sourceStopChans, key)
 		}
 	}
 	lh.mu.Unlock()
@@ -68,27 +73,28 @@ func (lh *LogHandler) Update(sources []ls.LogSource, k8sClient *api.K8sClientWra
 
 		lh.log.Debugf("checking source: %v", source)
 
+		key := status_tracker.Key(source.Metadata.Namespace, source.Metadata.Name)
 		lh.mu.Lock()
 		// If source already exists, skip or restart it
-		_, stopChanExists := lh.sourceStopChans[source.Metadata.Name]
+		_, stopChanExists := lh.sourceStopChans[key]
 		if stopChanExists {
-			lh.log.Debugf("source %s already exists, skipping", source.Metadata.Name)
+			lh.log.Debugf("source %s already exists, skipping", key)
 			lh.mu.Unlock()
 			continue
 		}
 
 		// Create a new stop channel for this source
 		stopCh := make(chan struct{})
-		lh.sourceStopChans[source.Metadata.Name] = stopCh
+		lh.sourceStopChans[key] = stopCh
 
 		lh.mu.Unlock()
 
 		lh.wg.Add(1)
-		go func(name string, src ls.LogSource, ch chan struct{}) {
+		go func(key string, src ls.LogSource, ch chan struct{}) {
 			defer lh.wg.Done()
-			lh.log.Debugf("beginning receipt of logs for source with name %v", name)
+			lh.log.Debugf("beginning receipt of logs for source %v", key)
 			lh.receiveLogs(src, k8sClient, ch)
-		}(source.Metadata.Name, source, stopCh)
+		}(key, source, stopCh)
 	}
 
 	return nil
@@ -97,7 +103,6 @@ func (lh *LogHandler) Update(sources []ls.LogSource, k8sClient *api.K8sClientWra
 // ShutDown signals all goroutines to stop and waits for them to complete
 func (lh *LogHandler) ShutDown() {
 	lh.mu.Lock()
-	defer lh.mu.Unlock()
 
 	// Close all stop channels
 	for _, stopCh := range lh.sourceStopChans {
@@ -107,94 +112,84 @@ func (lh *LogHandler) ShutDown() {
 	// Clear the source maps
 	lh.sourceStopChans = make(map[string]chan struct{})
 
+	// The lock only guards the stop channels, so it is released before waiting for the sources to stop
+	lh.mu.Unlock()
+
 	// Wait for all goroutines to complete
 	lh.wg.Wait()
 }
 
-// StopSource stops a specific source by name
-func (lh *LogHandler) StopSource(name string) bool {
+// StopSource stops a specific source by namespace and name
+func (lh *LogHandler) StopSource(namespace, name string) bool {
 	lh.mu.Lock()
 	defer lh.mu.Unlock()
 
-	if stopCh, exists := lh.sourceStopChans[name]; exists {
+	key := status_tracker.Key(namespace, name)
+	if stopCh, exists := lh.sourceStopChans[key]; exists {
 		close(stopCh)
-		delete(lh.sourceStopChans, name)
+		delete(lh.sourceStopChans, key)
 		return true
 	}
 	return false
 }
 
 func (lh *LogHandler) receiveLogs(sourceObj ls.LogSource, kClient *api.K8sClientWrapper, stopCh <-chan struct{}) {
-	// Create a ticker for periodic processing
+	// Create a ticker for periodic readiness checks
 	ticker := time.NewTicker(10 * time.Second)
 	defer ticker.Stop()
 
-	var source api.Source
-
-	switch sourceObj.Spec.Type {
-	case "PVCSource":
-		source = sourceObj.Spec.Source.PVCSource
-	case "PodSource":
-		source = sourceObj.Spec.Source.PodSource
-	case "LocalSource":
-		source = sourceObj.Spec.Source.LocalSource
-	case "CmdSource":
-		source = sourceObj.Spec.Source.CmdSource
-	default:
-		lh.log.Errorf("unknown log source type: %s", sourceObj.Spec.Type)
+	tracker := status_tracker.GetInstance()
+	key := status_tracker.Key(sourceObj.Metadata.Namespace, sourceObj.Metadata.Name)
+
+	source, err := sourceObj.Spec.GetSource()
+	if err != nil {
+		lh.log.Errorf("failed to get source of log source %s: %v", key, err)
+		tracker.SetLogSourceReady(key, false, "UnknownSourceType")
 		return
 	}
 
-	go source.StartLogStream(kClient, nil, stopCh)
-
-	sru, err := lh.lp.FindSRU(source)
+	sru, err := lh.lp.FindSRU(sourceObj)
 	if err != nil {
 		lh.log.Errorf("failed to find log set for source: %v", err)
+		tracker.SetLogSourceReady(key, false, "NoRules")
 		return
 	}
 
+	lines := make(chan string)
+	go func() {
+		tracker.SetStreaming(key, true, nil)
+		err := source.StartLogStream(kClient, nil, lines, stopCh)
+		if err != nil {
+			lh.log.Errorf("log stream for source %s ended: %v", sourceObj.Metadata.Name, err)
+		}
+		tracker.SetStreaming(key, false, err)
+	}()
+
+	checkReady := func() {
+		// Rule sets can come and go while the source is running
+		if sru.RuleCount() == 0 {
+			tracker.SetLogSourceReady(key, false, "NoMatchingRuleSet")
+		} else {
+			tracker.SetLogSourceReady(key, true, "")
+		}
+	}
+	checkReady()
+
+	srcInfo := api.LogSourceInfo{Name: sourceObj.Metadata.Name, Namespace: sourceObj.Metadata.Namespace}
 	for {
 		select {
 		case <-stopCh:
 			return
 		case <-ticker.C:
-			logs := source.GetNewLogs()
-			lh.log.Infof("Processing %v logs from source: %s", len(logs), source.GetSourceInfo())
-			data := lh.lp.ProcessLogsWithSRU(sru, logs, sourceObj.Metadata.Name, sourceObj.Metadata.Namespace)
-			lh.log.Infof("Processed %d items with SRU", len(data))
-
-			// Store the processed data in the bucket
-			lh.mu.Lock()
-			lh.itemBucket = append(lh.itemBucket, data...)
-			lh.mu.Unlock()
-
-			lh.log.Debugf("Stored %d items in bucket for source %s, total now: %d",
-				len(data), sourceObj.Metadata.Name, len(lh.itemBucket))
+			checkReady()
+		case line := <-lines:
+			tracker.AddLinesRead(key, 1)
+			// Blocks while the pipeline is full, which in turn blocks the stream
+			select {
+			case lh.lines <- log_processor.LogLine{SRU: sru, Source: srcInfo, Line: line, ReadAt: time.Now()}:
+			case <-stopCh:
+				return
+			}
 		}
 	}
 }
-
-func (lh *LogHandler) ReceiveBucketContents() []api.ProcessedDataItem {
-	lh.mu.Lock()
-	defer lh.mu.Unlock()
-
-	items := make([]api.ProcessedDataItem, len(lh.itemBucket))
-	copy(items, lh.itemBucket)
-
-	// Clear the bucket
-	lh.itemBucket = make([]api.ProcessedDataItem, 0)
-
-	return items
-}
-
-func (lh *LogHandler) AppendToItemBucket(items []api.ProcessedDataItem) {
-	lh.mu.Lock()
-	defer lh.mu.Unlock()
-	lh.itemBucket = append(lh.itemBucket, items...)
-}
-
-func (lh *LogHandler) ClearItemBucket() {
-	lh.mu.Lock()
-	defer lh.mu.Unlock()
-	lh.itemBucket = make([]api.ProcessedDataItem, 0)
-}
@@ -0,0 +1,259 @@
+package log_processor
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/devon-caron/metrifuge/k8s/api"
+)
+
+// compileConditional checks a conditional and the conditionals nested in it, and compiles the
+// patterns of their Matches conditions into matchers and their expressions into exprs.
+func compileConditional(conditional *api.Conditional, matchers map[string]*regexp.Regexp, exprs *expressions) error {
+	if conditional == nil {
+		return nil
+	}
+	if err := compileCondition(&conditional.Condition, matchers, exprs); err != nil {
+		return err
+	}
+	if err := compileMetrics(conditional.MetricsTrue, exprs); err != nil {
+		return fmt.Errorf("metricsTrue: %w", err)
+	}
+	if err := compileMetrics(conditional.MetricsFalse, exprs); err != nil {
+		return fmt.Errorf("metricsFalse: %w", err)
+	}
+	if err := compileConditional(conditional.ConditionalTrue, matchers, exprs); err != nil {
+		return fmt.Errorf("conditionalTrue: %w", err)
+	}
+	if err := compileConditional(conditional.ConditionalFalse, matchers, exprs); err != nil {
+		return fmt.Errorf("conditionalFalse: %w", err)
+	}
+	return nil
+}
+
+// Instruments that metrics can be recorded with
+var metricKinds = []string{"Int64Counter", "Float64Counter", "Int64Gauge", "Float64Gauge", "Int64Histogram", "Float64Histogram"}
+
+// compileMetrics checks the kinds and types of metrics and their attributes, and that their manual
+// values are of their type, and compiles the expressions computing their values.
+func compileMetrics(metrics []api.MetricTemplate, exprs *expressions) error {
+	for _, metric := range metrics {
+		if !slices.Contains(metricKinds, metric.Kind) {
+			return fmt.Errorf("unsupported kind of metric %s: %s", metric.Name, metric.Kind)
+		}
+		value := metric.Value
+		switch strings.ToLower(value.Type) {
+		case "int64", "float64":
+		default:
+			return fmt.Errorf("unknown value type of metric %s: %s", metric.Name, value.Type)
+		}
+		if value.Expression != "" {
+			if err := exprs.compile(value.Expression, valueTypes); err != nil {
+				return fmt.Errorf("value of metric %s: %w", metric.Name, err)
+			}
+		} else if value.GrokKey == "" {
+			if err := checkManualValue(value.Type, value.ManualValue); err != nil {
+				return fmt.Errorf("value of metric %s: %w", metric.Name, err)
+			}
+		}
+
+		for _, attr := range metric.Attributes {
+			switch strings.ToLower(attr.Value.Type) {
+			case "int64", "float64", "string":
+			default:
+				return fmt.Errorf("unknown type of attribute %s of metric %s: %s", attr.Key, metric.Name, attr.Value.Type)
+			}
+			if attr.Value.GrokKey != "" {
+				continue
+			}
+			if err := checkManualValue(attr.Value.Type, attr.Value.ManualValue); err != nil {
+				return fmt.Errorf("attribute %s of metric %s: %w", attr.Key, metric.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkManualValue checks that a manual value parses as its numeric type, as it would for every line.
+func checkManualValue(valueType, manualValue string) error {
+	var err error
+	switch strings.ToLower(valueType) {
+	case "int64":
+		_, err = strconv.ParseInt(manualValue, 10, 64)
+	case "float64":
+		_, err = strconv.ParseFloat(manualValue, 64)
+	}
+	if err != nil {
+		return fmt.Errorf("manual value %q is not a valid %s", manualValue, valueType)
+	}
+	return nil
+}
+
+// compileCondition checks that a condition is either a comparison, an expression or a single
+// compound, and compiles the pattern of Matches comparisons and expressions.
+func compileCondition(c *api.Condition, matchers map[string]*regexp.Regexp, exprs *expressions) error {
+	compounds := 0
+	for _, set := range []bool{len(c.All) > 0, len(c.Any) > 0, c.Not != nil, c.Operator != "", c.Expression != ""} {
+		if set {
+			compounds++
+		}
+	}
+	if compounds != 1 {
+		return fmt.Errorf("a condition needs exactly one of an operator, an expression, all, any or not")
+	}
+
+	for i := range c.All {
+		if err := compileCondition(&c.All[i], matchers, exprs); err != nil {
+			return fmt.Errorf("all[%d]: %w", i, err)
+		}
+	}
+	for i := range c.Any {
+		if err := compileCondition(&c.Any[i], matchers, exprs); err != nil {
+			return fmt.Errorf("any[%d]: %w", i, err)
+		}
+	}
+	if c.Not != nil {
+		if err := compileCondition(c.Not, matchers, exprs); err != nil {
+			return fmt.Errorf("not: %w", err)
+		}
+	}
+	if c.Expression != "" {
+		return exprs.compile(c.Expression, conditionTypes)
+	}
+
+	switch c.Operator {
+	case "", "Equals", "DoesNotEqual", "Exists", "DoesNotExist", "Contains", "StartsWith", "EndsWith",
+		"LessThan", "GreaterThan", "GreaterThanOrEqualTo", "LessThanOrEqualTo":
+	case "Matches":
+		if c.Field2.GrokKey != "" {
+			return fmt.Errorf("the pattern of a Matches condition must be a manualValue of field2")
+		}
+		pattern, err := regexp.Compile(c.Field2.ManualValue)
+		if err != nil {
+			return fmt.Errorf("invalid pattern of Matches condition: %v", err)
+		}
+		matchers[c.Field2.ManualValue] = pattern
+	case "In", "NotIn":
+		if len(c.Values) == 0 {
+			return fmt.Errorf("%s condition has no values", c.Operator)
+		}
+	default:
+		return fmt.Errorf("unsupported operator: %s", c.Operator)
+	}
+	return nil
+}
+
+// evaluateCondition evaluates a condition of a rule against the fields captured from a line.
+func evaluateCondition(c *api.Condition, values map[string]string, ref ruleRef) (bool, error) {
+	switch {
+	case len(c.All) > 0:
+		for i := range c.All {
+			if ok, err := evaluateCondition(&c.All[i], values, ref); err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	case len(c.Any) > 0:
+		for i := range c.Any {
+			if ok, err := evaluateCondition(&c.Any[i], values, ref); err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	case c.Not != nil:
+		ok, err := evaluateCondition(c.Not, values, ref)
+		return !ok, err
+	case c.Expression != "":
+		return ref.expressions.evalBool(c.Expression, values)
+	}
+
+	f1 := fieldValue(c.Field1, values)
+	f2 := fieldValue(c.Field2, values)
+	if err := validateFields(f1, f2, c.Operator); err != nil {
+		return false, err
+	}
+	return evaluateConditional(f1, f2, c, ref.matchers)
+}
+
+// fieldValue returns the value of a field of a line, or the manual value if it refers to none.
+func fieldValue(fv api.FieldValue, values map[string]string) string {
+	if fv.GrokKey != "" {
+		return values[fv.GrokKey]
+	}
+	return fv.ManualValue
+}
+
+func validateFields(field1, field2, op string) error {
+	// Check that field1 and field2 are valid based on the operator
+	switch op {
+	case "Equals", "DoesNotEqual":
+		// These operators require both fields to be present and comparable
+		if field1 == "" {
+			return fmt.Errorf("field1 is required for operator %s", op)
+		}
+		if field2 == "" {
+			return fmt.Errorf("field2 is required for operator %s", op)
+		}
+	case "LessThan", "GreaterThan", "GreaterThanOrEqualTo", "LessThanOrEqualTo":
+		// These operators require both fields to be present and parseable as numbers
+		if field1 == "" {
+			return fmt.Errorf("field1 is required for operator %s", op)
+		}
+		if field2 == "" {
+			return fmt.Errorf("field2 is required for operator %s", op)
+		}
+		_, err1 := strconv.ParseFloat(field1, 64)
+		_, err2 := strconv.ParseFloat(field2, 64)
+		if err1 != nil || err2 != nil {
+			return fmt.Errorf("field1 and field2 must be numbers for operator %s, got %q and %q", op, field1, field2)
+		}
+	}
+	return nil
+}
+
+func evaluateConditional(f1Str, f2Str string, c *api.Condition, matchers map[string]*regexp.Regexp) (bool, error) {
+	switch c.Operator {
+	case "Equals":
+		return f1Str == f2Str, nil
+	case "DoesNotEqual":
+		return f1Str != f2Str, nil
+	case "Exists":
+		return f1Str != "", nil
+	case "DoesNotExist":
+		return f1Str == "", nil
+	case "Contains":
+		return strings.Contains(f1Str, f2Str), nil
+	case "StartsWith":
+		return strings.HasPrefix(f1Str, f2Str), nil
+	case "EndsWith":
+		return strings.HasSuffix(f1Str, f2Str), nil
+	case "In":
+		return slices.Contains(c.Values, f1Str), nil
+	case "NotIn":
+		return !slices.Contains(c.Values, f1Str), nil
+	case "Matches":
+		pattern, ok := matchers[f2Str]
+		if !ok {
+			return false, fmt.Errorf("pattern %q of Matches condition was not compiled", f2Str)
+		}
+		return pattern.MatchString(f1Str), nil
+	case "LessThan", "GreaterThan", "GreaterThanOrEqualTo", "LessThanOrEqualTo":
+		// Both fields were checked to be numbers
+		n1, _ := strconv.ParseFloat(f1Str, 64)
+		n2, _ := strconv.ParseFloat(f2Str, 64)
+		switch c.Operator {
+		case "LessThan":
+			return n1 < n2, nil
+		case "GreaterThan":
+			return n1 > n2, nil
+		case "GreaterThanOrEqualTo":
+			return n1 >= n2, nil
+		default:
+			return n1 <= n2, nil
+		}
+	}
+	return false, fmt.Errorf("unsupported operator: %s", c.Operator)
+}
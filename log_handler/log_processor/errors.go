@@ -0,0 +1,70 @@
+package log_processor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/devon-caron/metrifuge/k8s/api"
+)
+
+// What a rule was doing with a line when it failed
+const (
+	StageParse     = "Parse"
+	StageTransform = "Transform"
+	StageEvaluate  = "Evaluate"
+)
+
+// What becomes of a line that a rule fails on
+const (
+	OnErrorSkip        = "skip"        // the rule produces nothing for the line, other rules still apply
+	OnErrorForwardRaw  = "forwardraw"  // the line is forwarded as it was read, with the rule's redaction
+	OnErrorDiscardLine = "discardline" // nothing is produced for the line, not even by the rules before it
+)
+
+// RuleError is a failure of a rule on a single line. It is recorded against the rule, and never
+// stops the line's other rules or the lines after it from being evaluated, although a DiscardLine
+// policy drops what the other rules produced for the line.
+type RuleError struct {
+	RuleSet string // status_tracker key of the RuleSet
+	Rule    string // name of the rule, or its index in the RuleSet
+	Stage   string
+	Line    string
+	Err     error
+}
+
+func (e *RuleError) Error() string {
+	return fmt.Sprintf("rule %s of %s failed to %s line: %v", e.Rule, e.RuleSet, strings.ToLower(e.Stage), e.Err)
+}
+
+func (e *RuleError) Unwrap() error {
+	return e.Err
+}
+
+// ruleName identifies a rule in the status of its RuleSet.
+func ruleName(rule *api.Rule, index int) string {
+	if rule.Name != "" {
+		return rule.Name
+	}
+	return strconv.Itoa(index)
+}
+
+// compileOnError checks the error policy of a rule and returns it lowercased.
+func compileOnError(onError string) (string, error) {
+	switch policy := strings.ToLower(onError); policy {
+	case "":
+		return OnErrorSkip, nil
+	case OnErrorSkip, OnErrorForwardRaw, OnErrorDiscardLine:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown onError policy: %v", onError)
+	}
+}
+
+// recoverRule turns a panic of a rule into an error, so that a rule that trips over a line can't
+// take the pipeline down with it.
+func recoverRule(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("panic: %v", r)
+	}
+}
@@ -0,0 +1,178 @@
+package log_processor
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/devon-caron/metrifuge/k8s/api"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// fieldsVariable holds every field of a line in expressions, including those whose names aren't
+// CEL identifiers, as in fields["http.status"]. rawVariable holds them as they were captured, for
+// fields that look like numbers but are compared as strings, as in raw["zip"] == "01234".
+const (
+	fieldsVariable = "fields"
+	rawVariable    = "raw"
+)
+
+// expressions compiles and runs the CEL expressions of a rule. The fields the rule can capture or
+// derive are declared as variables, so that an expression referring to anything else is rejected
+// when its RuleSet loads.
+type expressions struct {
+	fields   []string
+	env      *cel.Env // created with the first expression, so that rules without any don't pay for it
+	programs map[string]cel.Program
+}
+
+var (
+	celIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	// CEL's type names and reserved words, which fields can't be declared as, such as the bytes of COMMONAPACHELOG
+	celReserved = []string{"bool", "bytes", "double", "duration", "dyn", "int", "list", "map", "null_type", "string",
+		"timestamp", "type", "uint", "true", "false", "null", "in", "as", "break", "const", "continue", "else", "for",
+		"function", "if", "import", "let", "loop", "package", "namespace", "return", "var", "void", "while"}
+
+	conditionTypes = []*cel.Type{cel.BoolType, cel.DynType}
+	valueTypes     = []*cel.Type{cel.IntType, cel.UintType, cel.DoubleType, cel.DynType}
+)
+
+// ruleFields lists the fields a rule may capture or derive that can be referred to by name: the
+// named captures of its compiled pattern and the targets of its transforms. The others are only in fields.
+func ruleFields(rule *api.Rule, captures []string) []string {
+	found := make(map[string]bool)
+	for _, capture := range captures {
+		found[capture] = true
+	}
+	for _, t := range rule.Transforms {
+		found[t.Field] = true
+		found[t.Target] = true
+	}
+
+	var fields []string
+	for field := range found {
+		if celIdentifier.MatchString(field) && field != fieldsVariable && field != rawVariable && !slices.Contains(celReserved, field) {
+			fields = append(fields, field)
+		}
+	}
+	slices.Sort(fields)
+	return fields
+}
+
+func newExpressions(fields []string) *expressions {
+	return &expressions{fields: fields, programs: make(map[string]cel.Program)}
+}
+
+// compile type-checks an expression and prepares it to run. Its result must have one of the given
+// types, or be dynamic, which is checked when it runs instead.
+func (e *expressions) compile(expression string, want []*cel.Type) error {
+	if _, ok := e.programs[expression]; ok {
+		return nil
+	}
+	if e.env == nil {
+		options := []cel.EnvOption{
+			cel.Variable(fieldsVariable, cel.MapType(cel.StringType, cel.DynType)),
+			cel.Variable(rawVariable, cel.MapType(cel.StringType, cel.StringType)),
+		}
+		for _, field := range e.fields {
+			options = append(options, cel.Variable(field, cel.DynType))
+		}
+		env, err := cel.NewEnv(options...)
+		if err != nil {
+			return fmt.Errorf("failed to create expression environment: %v", err)
+		}
+		e.env = env
+	}
+
+	ast, issues := e.env.Compile(expression)
+	if issues.Err() != nil {
+		return fmt.Errorf("invalid expression %q: %v", expression, issues.Err())
+	}
+	if !slices.ContainsFunc(want, ast.OutputType().IsExactType) {
+		return fmt.Errorf("expression %q is of type %s, expected one of %v", expression, ast.OutputType(), want)
+	}
+	program, err := e.env.Program(ast)
+	if err != nil {
+		return fmt.Errorf("failed to prepare expression %q: %v", expression, err)
+	}
+	e.programs[expression] = program
+	return nil
+}
+
+// eval runs a compiled expression on the fields of a line. Fields that look like numbers are
+// numbers in expressions, so that status >= 500 works on a captured status, but also so that "007"
+// is 7 and user == "123" fails on a numeric user; raw has every field as the string it was.
+func (e *expressions) eval(expression string, values map[string]string) (ref.Val, error) {
+	program, ok := e.programs[expression]
+	if !ok {
+		return nil, fmt.Errorf("expression %q was not compiled", expression)
+	}
+
+	fields := make(map[string]any, len(values))
+	for field, value := range values {
+		fields[field] = typedValue(value)
+	}
+	activation := map[string]any{fieldsVariable: fields, rawVariable: values}
+	for _, field := range e.fields {
+		if value, ok := fields[field]; ok {
+			activation[field] = value
+		}
+	}
+
+	out, _, err := program.Eval(activation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate expression %q: %w", expression, err)
+	}
+	return out, nil
+}
+
+func (e *expressions) evalBool(expression string, values map[string]string) (bool, error) {
+	out, err := e.eval(expression, values)
+	if err != nil {
+		return false, err
+	}
+	result, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("expression %q returned %s, not a bool", expression, out.Type().TypeName())
+	}
+	return result, nil
+}
+
+func (e *expressions) evalInt(expression string, values map[string]string) (int64, error) {
+	out, err := e.eval(expression, values)
+	if err != nil {
+		return 0, err
+	}
+	converted := out.ConvertToType(types.IntType)
+	if types.IsError(converted) {
+		return 0, fmt.Errorf("expression %q returned %s, not a number: %v", expression, out.Type().TypeName(), converted)
+	}
+	return converted.Value().(int64), nil
+}
+
+func (e *expressions) evalFloat(expression string, values map[string]string) (float64, error) {
+	out, err := e.eval(expression, values)
+	if err != nil {
+		return 0, err
+	}
+	converted := out.ConvertToType(types.DoubleType)
+	if types.IsError(converted) {
+		return 0, fmt.Errorf("expression %q returned %s, not a number: %v", expression, out.Type().TypeName(), converted)
+	}
+	return converted.Value().(float64), nil
+}
+
+// typedValue converts a field to an integer or a float if it is one, and leaves it a string otherwise.
+func typedValue(value string) any {
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+		return f
+	}
+	return value
+}
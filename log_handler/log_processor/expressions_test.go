@@ -0,0 +1,66 @@
+package log_processor
+
+import (
+	"testing"
+
+	"github.com/devon-caron/metrifuge/k8s/api"
+	"github.com/devon-caron/metrifuge/k8s/api/metrifuge/v1beta1"
+	"github.com/devon-caron/metrifuge/k8s/api/ruleset"
+)
+
+func TestExpressionsDeclareLibraryCaptures(t *testing.T) {
+	rule := &api.Rule{
+		Pattern: "%{COMMONAPACHELOG}",
+		Action:  "Conditional",
+		Conditional: &api.Conditional{
+			Condition:   api.Condition{Expression: `response >= 200 && fields["bytes"] == 2326 && raw["bytes"] == "2326"`},
+			ActionTrue:  "Forward",
+			ActionFalse: "Discard",
+		},
+	}
+	crs, err := compileRuleSet(ruleset.RuleSet{Spec: ruleset.RuleSetSpec{Rules: []*api.Rule{rule}}}, nil)
+	if err != nil {
+		t.Fatalf("compileRuleSet() = %v", err)
+	}
+
+	values, err := crs.g.Parse(rule.Pattern, apacheLine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	holds, err := crs.expressions[0].evalBool(rule.Conditional.Expression, values)
+	if err != nil || !holds {
+		t.Errorf("evalBool() = %v, %v, want true", holds, err)
+	}
+}
+
+func TestRawKeepsNumericLookingStrings(t *testing.T) {
+	e := newExpressions([]string{"zip"})
+	for _, expression := range []string{`raw["zip"] == "01234"`, `zip == 1234`} {
+		if err := e.compile(expression, conditionTypes); err != nil {
+			t.Fatalf("compile(%s) = %v", expression, err)
+		}
+		holds, err := e.evalBool(expression, map[string]string{"zip": "01234"})
+		if err != nil || !holds {
+			t.Errorf("evalBool(%s) = %v, %v, want true", expression, holds, err)
+		}
+	}
+}
+
+func TestConvertedConditionsKeepStrings(t *testing.T) {
+	condition := &api.Condition{
+		Field1:   api.FieldValue{Type: "String", GrokKey: "zip"},
+		Operator: "Equals",
+		Field2:   api.FieldValue{Type: "String", ManualValue: "007"},
+	}
+	expression := v1beta1.ConditionExpression(condition)
+	e := newExpressions([]string{"zip"})
+	if err := e.compile(expression, conditionTypes); err != nil {
+		t.Fatalf("compile(%s) = %v", expression, err)
+	}
+	for zip, want := range map[string]bool{"007": true, "7": false} {
+		holds, err := e.evalBool(expression, map[string]string{"zip": zip})
+		if err != nil || holds != want {
+			t.Errorf("evalBool(%s) with zip %s = %v, %v, want %v", expression, zip, holds, err, want)
+		}
+	}
+}
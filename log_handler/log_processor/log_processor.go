@@ -3,14 +3,23 @@ package log_processor
 import (
 	"context"
 	"fmt"
+	"hash/fnv"
+	"maps"
 	"math/rand/v2"
+	"reflect"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/devon-caron/metrifuge/global"
 	"github.com/devon-caron/metrifuge/k8s/api"
 	logsource "github.com/devon-caron/metrifuge/k8s/api/log_source"
 	"github.com/devon-caron/metrifuge/k8s/api/ruleset"
+	"github.com/devon-caron/metrifuge/status_tracker"
 	"github.com/sirupsen/logrus"
 	"github.com/vjeantet/grok"
 	"go.opentelemetry.io/otel/attribute"
@@ -18,17 +27,51 @@ import (
 )
 
 type LogProcessor struct {
-	sourceSets []*SourceRuleUnion
-	log        *logrus.Logger
-	g          *grok.Grok
+	sourceSets         map[string]*SourceRuleUnion // SRUs by status_tracker key of their log source
+	ruleSets           map[string]*compiledRuleSet // last good version of every RuleSet, by status_tracker key
+	mu                 sync.RWMutex                // Protects sourceSets and ruleSets
+	defaultParallelism int                         // parallelism of log sources that don't set one
+	parsedLines        sync.Pool                   // ParsedLines released after evaluation, reused with their capture maps
+	log                *logrus.Logger
 }
 
+/**
+ * SourceRuleUnion pairs a log source with the rules of every RuleSet that selects it. The rules
+ * are swapped atomically when RuleSets change, so a running source picks up the new rules with
+ * its next batch of logs and never stops consuming.
+ */
 type SourceRuleUnion struct {
-	source api.Source
-	rules  []*api.Rule
+	sourceKey string
+	home      uint32        // hash of sourceKey, the first lane the source's lines are sent to
+	seq       atomic.Uint64 // lines assigned a lane so far, to spread them over the source's lanes
+	rules     atomic.Pointer[ruleVersion]
 }
 
-func (lp *LogProcessor) Initialize(logSources []logsource.LogSource, ruleSets []ruleset.RuleSet, log *logrus.Logger) {
+// ruleVersion is one immutable generation of the rules applied to a log source.
+type ruleVersion struct {
+	generation  int64     // incremented on every reload of the SRU
+	ruleSets    string    // namespace/name@generation of every RuleSet the rules came from
+	rules       []ruleRef // rules in RuleSet order
+	parallelism int       // lanes the source's lines may be spread over, 1 if any rule is ordered
+}
+
+type ruleRef struct {
+	rule        *api.Rule
+	name        string     // name or index of the rule, identifying it in the RuleSet's status
+	ruleSetKey  string     // status_tracker key of the RuleSet the rule came from
+	g           *grok.Grok // grok instance of the RuleSet, with the rule's pattern already compiled
+	named       bool       // whether the pattern has named captures, which are only empty if it doesn't match
+	firstMatch  bool       // whether the RuleSet stops at its first matching rule
+	transforms  []transform
+	severity    *severityMap
+	rewrite     *rewriter
+	matchers    map[string]*regexp.Regexp // compiled patterns of the RuleSet's Matches conditions
+	expressions *expressions
+	onError     string // what becomes of a line the rule fails on
+}
+
+func (lp *LogProcessor) Initialize(logSources []logsource.LogSource, ruleSets []ruleset.RuleSet, defaultParallelism int,
+	k8sClient *api.K8sClientWrapper, log *logrus.Logger) {
 	if logSources == nil {
 		logrus.Fatalf("log processor initialization failed, logSources triggered nil: logSources: %v, ruleSets: %v, log: %v", logSources, ruleSets, log)
 	}
@@ -41,122 +84,339 @@ func (lp *LogProcessor) Initialize(logSources []logsource.LogSource, ruleSets []
 		logrus.Fatalf("log processor initialization failed, log triggered nil: logSources: %v, ruleSets: %v, log: %v", logSources, ruleSets, log)
 	}
 
-	lp.log = log
+	if defaultParallelism <= 0 {
+		logrus.Fatalf("log processor initialization failed, default parallelism must be positive, got %d", defaultParallelism)
+	}
 
-	lp.sourceSets = make([]*SourceRuleUnion, 0)
+	lp.log = log
+	lp.defaultParallelism = defaultParallelism
+	lp.parsedLines.New = func() any {
+		return &ParsedLine{}
+	}
 
-	lp.Update(logSources, ruleSets)
+	lp.sourceSets = make(map[string]*SourceRuleUnion)
+	lp.ruleSets = make(map[string]*compiledRuleSet)
 
-	if g, err := grok.NewWithConfig(&grok.Config{NamedCapturesOnly: true}); err != nil {
-		logrus.Fatalf("failed to initialize grok: %v", err)
-	} else {
-		lp.g = g
-	}
+	lp.Update(logSources, ruleSets, k8sClient)
 }
 
-func (lp *LogProcessor) Update(logSources []logsource.LogSource, ruleSets []ruleset.RuleSet) {
+// Update re-resolves the rules of every log source from the full set of log sources and RuleSets.
+// SRUs of existing sources keep their identity and only have their rules swapped, SRUs of new
+// sources are created, and SRUs of sources that are gone are dropped. The patterns of changed
+// RuleSets are compiled first, and RuleSets that fail to compile keep their last good version.
+func (lp *LogProcessor) Update(logSources []logsource.LogSource, ruleSets []ruleset.RuleSet, k8sClient *api.K8sClientWrapper) {
+	// Pattern ConfigMaps are fetched before taking the lock, which starting log sources wait on
+	definitions := loadDefinitions(ruleSets, k8sClient)
+
+	lp.mu.Lock()
+	defer lp.mu.Unlock()
+
+	loaded := make(map[string]*compiledRuleSet)
 	for _, rs := range ruleSets {
-		lp.log.Infof("processing rule set: %v", rs)
-
-		lp.log.Debugf("spec: %v", rs.Spec)
-		lp.log.Debugf("selector: %v", rs.Spec.Selector)
-		lp.log.Debugf("matchlabels: %v", rs.Spec.Selector.MatchLabels)
-
-		selectorLabels := rs.Spec.Selector.MatchLabels
-
-		selector := labels.Set(selectorLabels).AsSelector()
-		for _, ls := range logSources {
-			lp.log.Infof("processing log source: %+v", ls)
-			sourceLabels := ls.Metadata.Labels
-			lp.log.Infof("source labels: %v", sourceLabels)
-			lp.log.Infof("type: %v", ls.Spec.Type)
-			if selector.Matches(labels.Set(sourceLabels)) {
-				set := &SourceRuleUnion{
-					rules: rs.Spec.Rules,
-				}
+		key := status_tracker.Key(rs.Metadata.Namespace, rs.Metadata.Name)
+		if crs := lp.loadRuleSet(key, rs, definitions[key]); crs != nil {
+			loaded[key] = crs
+		}
+	}
+	lp.ruleSets = loaded
+	sorted := slices.Sorted(maps.Keys(loaded))
 
-				switch ls.Spec.Type {
-				case "PodSource":
-					set.source = ls.Spec.Source.PodSource
-				case "PVCSource":
-					set.source = ls.Spec.Source.PVCSource
-				case "LocalSource":
-					set.source = ls.Spec.Source.LocalSource
-				case "CmdSource":
-					set.source = ls.Spec.Source.CmdSource
-				default:
-					lp.log.Errorf("unknown log source type: %s", ls.Spec.Source.Type)
-					return
-				}
+	current := make(map[string]bool)
+	for _, ls := range logSources {
+		key := status_tracker.Key(ls.Metadata.Namespace, ls.Metadata.Name)
+		current[key] = true
+		lp.log.Debugf("resolving rules for log source %s, labels: %v", key, ls.Metadata.Labels)
 
-				lp.sourceSets = append(lp.sourceSets, set)
-				lp.log.Infof("added source set: %v", set)
+		next := &ruleVersion{parallelism: ls.Spec.Parallelism}
+		if next.parallelism <= 0 {
+			next.parallelism = lp.defaultParallelism
+		}
+		var versions []string
+		for _, ruleSetKey := range sorted {
+			crs := loaded[ruleSetKey]
+			selector := labels.Set(crs.ruleSet.Spec.Selector.MatchLabels).AsSelector()
+			if !selector.Matches(labels.Set(ls.Metadata.Labels)) {
+				continue
+			}
+			versions = append(versions, fmt.Sprintf("%s@%d", ruleSetKey, crs.ruleSet.Metadata.Generation))
+			for i, rule := range crs.ruleSet.Spec.Rules {
+				next.rules = append(next.rules, ruleRef{rule: rule, name: ruleName(rule, i), ruleSetKey: ruleSetKey, g: crs.g,
+					named: crs.named[i], firstMatch: crs.ruleSet.Spec.Evaluation == ruleset.EvaluationFirstMatch,
+					transforms: crs.transforms[i], severity: crs.severities[i], rewrite: crs.rewriters[i],
+					matchers: crs.matchers, expressions: crs.expressions[i], onError: crs.onErrors[i]})
+				if rule.Ordered {
+					next.parallelism = 1
+				}
 			}
 		}
+		next.ruleSets = strings.Join(versions, ",")
+
+		sru, ok := lp.sourceSets[key]
+		if !ok {
+			hash := fnv.New32a()
+			hash.Write([]byte(key))
+			sru = &SourceRuleUnion{sourceKey: key, home: hash.Sum32()}
+			sru.rules.Store(next)
+			lp.sourceSets[key] = sru
+			lp.log.Infof("loaded %d rules for log source %s from rule sets [%s], parallelism %d",
+				len(next.rules), key, next.ruleSets, next.parallelism)
+			continue
+		}
+
+		prev := sru.rules.Load()
+		if prev.ruleSets == next.ruleSets && prev.parallelism == next.parallelism && slices.EqualFunc(prev.rules, next.rules, func(a, b ruleRef) bool {
+			return a.ruleSetKey == b.ruleSetKey && a.g == b.g && reflect.DeepEqual(a.rule, b.rule)
+		}) {
+			// Nothing changed, keep the current generation
+			continue
+		}
+		next.generation = prev.generation + 1
+		sru.rules.Store(next)
+		lp.log.Infof("reloaded rules for log source %s, generation %d: %d rules from rule sets [%s] (previously [%s]), parallelism %d",
+			key, next.generation, len(next.rules), next.ruleSets, prev.ruleSets, next.parallelism)
+	}
+
+	for key := range lp.sourceSets {
+		if !current[key] {
+			delete(lp.sourceSets, key)
+			lp.log.Infof("dropped rules for removed log source %s", key)
+		}
+	}
+}
+
+// FindSRU returns the SRU of a log source.
+func (lp *LogProcessor) FindSRU(logSource logsource.LogSource) (*SourceRuleUnion, error) {
+	lp.mu.RLock()
+	defer lp.mu.RUnlock()
+
+	key := status_tracker.Key(logSource.Metadata.Namespace, logSource.Metadata.Name)
+	sru, ok := lp.sourceSets[key]
+	if !ok {
+		return nil, fmt.Errorf("log set not found for source: %v", key)
 	}
+	return sru, nil
 }
 
-func (lp *LogProcessor) FindSRU(source api.Source) (*SourceRuleUnion, error) {
-	for i, set := range lp.sourceSets {
-		// TODO this is a costly operation, needs improvement
-		lp.log.Infof("checking source #%v: %v", i+1, set.source.GetSourceInfo())
-		lp.log.Infof("against desired source: %v", source.GetSourceInfo())
-		if set.source.GetSourceInfo() == source.GetSourceInfo() {
-			return set, nil
+// RuleCount returns the number of rules currently applied to the SRU's log source.
+func (sru *SourceRuleUnion) RuleCount() int {
+	return len(sru.rules.Load().rules)
+}
+
+// Lane picks which of lanes the next line of the SRU's source is evaluated on. Lanes are processed
+// in order, so a source that must keep its lines in order always gets the same lane, while the
+// lines of other sources are spread round-robin over as many lanes as their parallelism allows.
+func (sru *SourceRuleUnion) Lane(lanes int) int {
+	parallelism := min(sru.rules.Load().parallelism, lanes)
+	offset := 0
+	if parallelism > 1 {
+		offset = int(sru.seq.Add(1) % uint64(parallelism))
+	}
+	return (int(sru.home%uint32(lanes)) + offset) % lanes
+}
+
+// LogLine is a single line read from a log source, along with the rules to apply to it.
+type LogLine struct {
+	SRU    *SourceRuleUnion
+	Source api.LogSourceInfo
+	Line   string
+	ReadAt time.Time
+}
+
+// ParsedLine is a LogLine with the fields captured by the pattern of each of its rules. ParsedLines
+// come from a pool along with their capture maps, which are cleared and refilled with the captures
+// of each line, so they must be given back with ReleaseLine once evaluated.
+type ParsedLine struct {
+	LogLine
+	version  *ruleVersion        // rules the line was parsed with, so evaluation uses the same generation
+	matched  []bool              // whether each rule applies to the line
+	captures []map[string]string // captures of each rule
+	errs     []error             // parsing error of each rule
+}
+
+// ParseLine runs the pattern of every rule of the line's source against it. Rules whose contains
+// prefilter rules the line out are not run at all, and rules of a firstMatch RuleSet are not run
+// once an earlier rule of the same RuleSet matched.
+func (lp *LogProcessor) ParseLine(line LogLine) *ParsedLine {
+	version := line.SRU.rules.Load()
+	parsed := lp.parsedLines.Get().(*ParsedLine)
+	parsed.LogLine = line
+	parsed.version = version
+	for len(parsed.captures) < len(version.rules) {
+		parsed.matched = append(parsed.matched, false)
+		parsed.captures = append(parsed.captures, make(map[string]string))
+		parsed.errs = append(parsed.errs, nil)
+	}
+
+	matchedRuleSet := "" // firstMatch RuleSet that already has a matching rule for the line
+	for i, ref := range version.rules {
+		clear(parsed.captures[i])
+		parsed.matched[i], parsed.errs[i] = false, nil
+		if ref.firstMatch && ref.ruleSetKey == matchedRuleSet {
+			continue
+		}
+		if ref.rule.Contains != "" && !strings.Contains(line.Line, ref.rule.Contains) {
+			continue
+		}
+		parsed.matched[i], parsed.errs[i] = lp.parseLog(line.Line, ref, parsed.captures[i])
+		if parsed.matched[i] && ref.firstMatch {
+			matchedRuleSet = ref.ruleSetKey
 		}
 	}
-	return nil, fmt.Errorf("log set not found for source: %v", source.GetSourceInfo())
+	return parsed
 }
 
-func (lp *LogProcessor) ProcessLogsWithSRU(sru *SourceRuleUnion, logs []string, lsName string, lsNamespace string) []api.ProcessedDataItem {
-	totalProcessedDataItems := make([]api.ProcessedDataItem, 0)
-	baseCtx := context.WithValue(context.TODO(), global.SOURCE_NAME_KEY, lsName)
-	baseCtx = context.WithValue(baseCtx, global.SOURCE_NAMESPACE_KEY, lsNamespace)
-	for _, log := range logs {
-		for _, rule := range sru.rules {
-			processedDataItems, err := lp.processLog(baseCtx, log, rule)
-			if err != nil {
-				lp.log.Errorf("failed to process log: %v", err)
+// ReleaseLine returns an evaluated line to the pool. Nothing may use it afterwards.
+func (lp *LogProcessor) ReleaseLine(parsed *ParsedLine) {
+	parsed.LogLine = LogLine{}
+	parsed.version = nil
+	lp.parsedLines.Put(parsed)
+}
+
+// EvaluateLine applies every rule of a parsed line to the fields captured for it. A rule that fails
+// on the line is recorded with a sample of the line, and its onError policy decides what becomes
+// of the line; the other rules and the lines after it carry on either way. If any failing rule
+// discards the line, nothing is returned for it, wherever that rule sits in the rule order.
+func (lp *LogProcessor) EvaluateLine(parsed *ParsedLine) []api.ProcessedDataItem {
+	ctx := context.WithValue(context.TODO(), global.SOURCE_NAME_KEY, parsed.Source.Name)
+	ctx = context.WithValue(ctx, global.SOURCE_NAMESPACE_KEY, parsed.Source.Namespace)
+	tracker := status_tracker.GetInstance()
+
+	processedDataItems := make([]api.ProcessedDataItem, 0)
+	discard := false
+	for i, ref := range parsed.version.rules {
+		var match []int
+		stage, err := StageParse, parsed.errs[i]
+		if err == nil {
+			if !parsed.matched[i] {
+				// The rule does not apply to the line
+				continue
+			}
+			values := parsed.captures[i]
+			match = ref.rewrite.locate(parsed.Line)
+			tracker.AddRulesMatched(ref.ruleSetKey, 1)
+			var items []api.ProcessedDataItem
+			if items, stage, err = lp.applyRule(ctx, parsed, ref, values, match); err == nil {
+				processedDataItems = append(processedDataItems, items...)
 				continue
 			}
-			totalProcessedDataItems = append(totalProcessedDataItems, processedDataItems...)
 		}
-	}
 
-	lp.log.Debugf("processed %d logs into %d data items", len(logs), len(totalProcessedDataItems))
-	if len(totalProcessedDataItems) > 0 {
-		lp.log.Debugf("first data item: %+v", totalProcessedDataItems[0])
+		// The sample of the line ends up in the RuleSet's status, so it is redacted like forwarded logs
+		redacted := ref.rewrite.redact(parsed.Line, ref.rewrite.spans(match))
+		ruleErr := &RuleError{RuleSet: ref.ruleSetKey, Rule: ref.name, Stage: stage, Line: redacted, Err: err}
+		lp.log.Error(ruleErr)
+		tracker.RecordRuleFailure(ref.ruleSetKey, ref.name, stage, redacted, ruleErr)
+		switch ref.onError {
+		case OnErrorForwardRaw:
+			processedDataItems = append(processedDataItems, api.ProcessedDataItem{
+				ForwardLog:        redacted,
+				LogSourceInfo:     parsed.Source,
+				ObservedTimestamp: parsed.ReadAt,
+			})
+		case OnErrorDiscardLine:
+			// The remaining rules are still evaluated, so that their failures and matches are recorded
+			discard = true
+		}
+	}
+	if discard {
+		return nil
 	}
-	return totalProcessedDataItems
+	return processedDataItems
 }
 
-// TODO needs implementation
-func (lp *LogProcessor) processLog(ctx context.Context, logMsg string, rule *api.Rule) ([]api.ProcessedDataItem, error) {
-
-	var srcInfo = api.LogSourceInfo{}
+// applyRule transforms the fields a rule captured from a line and builds the items the rule
+// produces from them. It returns the stage it failed at along with any error, including a panic.
+func (lp *LogProcessor) applyRule(ctx context.Context, parsed *ParsedLine, ref ruleRef, values map[string]string,
+	match []int) (items []api.ProcessedDataItem, stage string, err error) {
+	stage = StageTransform
+	defer recoverRule(&err)
+	if err = applyTransforms(ref.transforms, values); err != nil {
+		return nil, stage, err
+	}
+
+	stage = StageEvaluate
+	if items, err = lp.evaluateRule(ctx, parsed.Line, values, ref); err != nil {
+		return nil, stage, err
+	}
+	timestamp := lp.eventTimestamp(ref, values)
+	severity, severityText := ref.severity.resolve(values)
+	logAttributes := logAttributes(ref.rule.LogAttributes, parsed.Line, values, ref.rewrite, match)
+	body := ""
+	for j := range items {
+		items[j].Timestamp = timestamp
+		items[j].ObservedTimestamp = parsed.ReadAt
+		items[j].Severity = severity
+		items[j].SeverityText = severityText
+		items[j].LogAttributes = logAttributes
+		if items[j].ForwardLog != "" && ref.rewrite != nil {
+			// Every forwarded body of a rule is the line itself, so it is rewritten once
+			if body == "" {
+				body = ref.rewrite.rewrite(parsed.Line, values, match)
+			}
+			items[j].ForwardLog = body
+		}
+	}
+	return items, stage, nil
+}
 
-	lsName, ok := ctx.Value(global.SOURCE_NAME_KEY).(string)
-	if !ok {
-		return []api.ProcessedDataItem{}, fmt.Errorf("missing name in context")
+// eventTimestamp returns when the event on a line happened, according to the rule's timestamp
+// field. It is zero if the rule has none or the field is missing. A timestamp that does not parse
+// is recorded as an error of the RuleSet, and the line is exported as if it had none.
+func (lp *LogProcessor) eventTimestamp(ref ruleRef, values map[string]string) time.Time {
+	spec := ref.rule.Timestamp
+	if spec == nil {
+		return time.Time{}
 	}
-	lsNamespace, ok := ctx.Value(global.SOURCE_NAMESPACE_KEY).(string)
+	value, ok := values[spec.Field]
 	if !ok {
-		return []api.ProcessedDataItem{}, fmt.Errorf("missing namespace in context")
+		return time.Time{}
 	}
-	srcInfo.Name = lsName
-	srcInfo.Namespace = lsNamespace
-
-	values, err := lp.g.Parse(rule.Pattern, logMsg)
+	timestamp, err := parseTimestamp(value, spec.Layout)
 	if err != nil {
-		return []api.ProcessedDataItem{}, err
+		status_tracker.GetInstance().RecordRuleError(ref.ruleSetKey, fmt.Errorf("failed to parse timestamp field %s: %w", spec.Field, err))
+		return time.Time{}
+	}
+	return timestamp
+}
+
+// logAttributes copies the fields a rule attaches to its forwarded logs, since the captures of a
+// line are reused once it has been evaluated. Every field is attached in name order if All is set,
+// and fields are redacted like the body, given the match of the rule's rewriter.
+func logAttributes(spec *api.LogAttributes, line string, values map[string]string, rw *rewriter, match []int) []attribute.KeyValue {
+	if spec == nil {
+		return nil
+	}
+	fields := spec.Fields
+	if spec.All {
+		fields = slices.Sorted(maps.Keys(values))
+	}
+	attrs := make([]attribute.KeyValue, 0, len(fields))
+	for _, field := range fields {
+		if _, ok := values[field]; ok {
+			attrs = append(attrs, attribute.String(field, rw.attribute(field, line, values, match)))
+		}
 	}
+	return attrs
+}
 
-	// Check if grok actually parsed anything
-	if len(values) == 0 {
-		lp.log.Warnf("grok pattern matched 0 fields - pattern may not match log")
-		lp.log.Warnf("  Log message: %s", logMsg)
-		lp.log.Warnf("  Pattern: %s", rule.Pattern)
+// parseLog captures the fields of a log line with the pattern of a rule into values, and reports
+// whether the pattern matched.
+func (lp *LogProcessor) parseLog(logMsg string, ref ruleRef, values map[string]string) (matched bool, err error) {
+	defer recoverRule(&err)
+	rule := ref.rule
+	// The pattern was compiled when its RuleSet was loaded, so these only look it up
+	if !ref.named {
+		return ref.g.Match(rule.Pattern, logMsg)
+	}
+	captured, err := ref.g.Parse(rule.Pattern, logMsg)
+	if err != nil {
+		return false, err
+	}
+	// Named captures are always present when the pattern matches, even if empty
+	if len(captured) == 0 {
+		return false, nil
 	}
+	maps.Copy(values, captured)
 
 	// debug
 	if rand.IntN(100) == 0 {
@@ -168,7 +428,27 @@ func (lp *LogProcessor) processLog(ctx context.Context, logMsg string, rule *api
 		}
 	}
 
-	metricData, err := lp.createMetricData(values, rule.Metrics)
+	return true, nil
+}
+
+// evaluateRule builds the metrics and forwarded log of a rule from the fields captured from a log line.
+func (lp *LogProcessor) evaluateRule(ctx context.Context, logMsg string, values map[string]string, ref ruleRef) ([]api.ProcessedDataItem, error) {
+	rule := ref.rule
+
+	var srcInfo = api.LogSourceInfo{}
+
+	lsName, ok := ctx.Value(global.SOURCE_NAME_KEY).(string)
+	if !ok {
+		return []api.ProcessedDataItem{}, fmt.Errorf("missing name in context")
+	}
+	lsNamespace, ok := ctx.Value(global.SOURCE_NAMESPACE_KEY).(string)
+	if !ok {
+		return []api.ProcessedDataItem{}, fmt.Errorf("missing namespace in context")
+	}
+	srcInfo.Name = lsName
+	srcInfo.Namespace = lsNamespace
+
+	metricData, err := lp.createMetricData(values, rule.Metrics, ref.expressions)
 	if err != nil {
 		return []api.ProcessedDataItem{}, err
 	}
@@ -187,7 +467,7 @@ func (lp *LogProcessor) processLog(ctx context.Context, logMsg string, rule *api
 		if rule.Conditional == nil {
 			return []api.ProcessedDataItem{}, fmt.Errorf("conditional action requires a conditional block, but none was provided")
 		}
-		processedLogMsg, processedDataItems, err = lp.processConditional(ctx, logMsg, values, rule, rule.Conditional)
+		processedLogMsg, processedDataItems, err = lp.processConditional(ctx, logMsg, values, ref, rule.Conditional)
 		if err != nil {
 			return []api.ProcessedDataItem{}, fmt.Errorf("failed to process conditional: %w", err)
 		}
@@ -206,7 +486,7 @@ func (lp *LogProcessor) processLog(ctx context.Context, logMsg string, rule *api
 	return processedDataItems, nil
 }
 
-func (lp *LogProcessor) createMetricData(values map[string]string, metrics []api.MetricTemplate) ([]*api.MetricData, error) {
+func (lp *LogProcessor) createMetricData(values map[string]string, metrics []api.MetricTemplate, exprs *expressions) ([]*api.MetricData, error) {
 
 	myMetricDataList := make([]*api.MetricData, 0)
 
@@ -229,7 +509,9 @@ func (lp *LogProcessor) createMetricData(values map[string]string, metrics []api
 			if random == 0 {
 				lp.log.Debugf("processing int64 metric: %s", metricTemplate.Name)
 			}
-			if metricTemplate.Value.GrokKey == "" {
+			if metricTemplate.Value.Expression != "" {
+				metricData.ValueInt, err = exprs.evalInt(metricTemplate.Value.Expression, values)
+			} else if metricTemplate.Value.GrokKey == "" {
 				metricData.ValueInt, err = strconv.ParseInt(metricTemplate.Value.ManualValue, 10, 64)
 			} else {
 				metricData.ValueInt, err = strconv.ParseInt(values[metricTemplate.Value.GrokKey], 10, 64)
@@ -241,7 +523,9 @@ func (lp *LogProcessor) createMetricData(values map[string]string, metrics []api
 			if random == 0 {
 				lp.log.Debugf("processing float64 metric: %s", metricTemplate.Name)
 			}
-			if metricTemplate.Value.GrokKey == "" {
+			if metricTemplate.Value.Expression != "" {
+				metricData.ValueFloat, err = exprs.evalFloat(metricTemplate.Value.Expression, values)
+			} else if metricTemplate.Value.GrokKey == "" {
 				metricData.ValueFloat, err = strconv.ParseFloat(metricTemplate.Value.ManualValue, 64)
 			} else {
 				metricData.ValueFloat, err = strconv.ParseFloat(values[metricTemplate.Value.GrokKey], 64)
@@ -319,12 +603,12 @@ func (lp *LogProcessor) createMetricData(values map[string]string, metrics []api
 	return myMetricDataList, nil
 }
 
-func (lp *LogProcessor) processConditional(ctx context.Context, logMsg string, values map[string]string, rule *api.Rule, conditional *api.Conditional) (string, []api.ProcessedDataItem, error) {
+func (lp *LogProcessor) processConditional(ctx context.Context, logMsg string, values map[string]string, ref ruleRef, conditional *api.Conditional) (string, []api.ProcessedDataItem, error) {
 
 	random := rand.IntN(100)
 
 	if random == 0 {
-		lp.log.Debugf("Evaluating conditional rule with pattern %s with field1: %v, operator: %s", rule.Pattern, conditional.Field1, conditional.Operator)
+		lp.log.Debugf("Evaluating conditional rule with pattern %s with field1: %v, operator: %s", ref.rule.Pattern, conditional.Field1, conditional.Operator)
 		lp.log.Debugf("conditional: %+v", conditional)
 	}
 
@@ -341,39 +625,8 @@ func (lp *LogProcessor) processConditional(ctx context.Context, logMsg string, v
 	srcInfo.Name = lsName
 	srcInfo.Namespace = lsNamespace
 
-	var f1Str, f2Str string
-
-	// Extract string values from FieldValue structs
-	if conditional.Field1.GrokKey != "" {
-		f1Str = values[conditional.Field1.GrokKey]
-	} else {
-		f1Str = conditional.Field1.ManualValue
-	}
-
-	if conditional.Field2.GrokKey != "" {
-		f2Str = values[conditional.Field2.GrokKey]
-	} else {
-		f2Str = conditional.Field2.ManualValue
-	}
-
-	op := conditional.Operator
-
-	if random == 0 {
-		lp.log.Debugf("validating conditional: field1='%s', field2='%s', operator='%s'", f1Str, f2Str, op)
-	}
-
-	var err error
-	if err = lp.validateFields(f1Str, f2Str, op); err != nil {
-		return "", nil, fmt.Errorf("conditional validation failed: %w", err)
-	}
-
-	if random == 0 {
-		lp.log.Debugf("fields validation passed")
-		lp.log.Debugf("evaluating conditional result")
-	}
-
-	var result bool
-	if result, err = evaluateConditional(f1Str, f2Str, op); err != nil {
+	result, err := evaluateCondition(&conditional.Condition, values, ref)
+	if err != nil {
 		return "", nil, fmt.Errorf("conditional evaluation failed: %w", err)
 	}
 
@@ -405,7 +658,7 @@ func (lp *LogProcessor) processConditional(ctx context.Context, logMsg string, v
 		if resultConditional == nil {
 			return "", nil, fmt.Errorf("nested conditional action specified but no conditional block provided for result=%t", result)
 		}
-		fwdLog, extraDataItems, err = lp.processConditional(ctx, logMsg, values, rule, resultConditional)
+		fwdLog, extraDataItems, err = lp.processConditional(ctx, logMsg, values, ref, resultConditional)
 		if err != nil {
 			return "", nil, fmt.Errorf("nested conditional processing failed: %w", err)
 		}
@@ -427,7 +680,7 @@ func (lp *LogProcessor) processConditional(ctx context.Context, logMsg string, v
 	if random == 0 {
 		lp.log.Debugf("selected metrics for result %t: %v", result, resultMetrics)
 	}
-	metricData, err := lp.createMetricData(values, resultMetrics)
+	metricData, err := lp.createMetricData(values, resultMetrics, ref.expressions)
 	if err != nil {
 		return "", nil, fmt.Errorf("metric data creation failed: %w", err)
 	}
@@ -449,80 +702,3 @@ func (lp *LogProcessor) processConditional(ctx context.Context, logMsg string, v
 
 	return fwdLog, processedDataItems, nil
 }
-
-func (lp *LogProcessor) validateFields(field1, field2, op string) error {
-	// Check that field1 and field2 are valid based on the operator
-	switch op {
-	case "Equals", "DoesNotEqual":
-		// These operators require both fields to be present and comparable
-		if field1 == "" {
-			return fmt.Errorf("field1 is required for operator %s", op)
-		}
-		if field2 == "" {
-			return fmt.Errorf("field2 is required for operator %s", op)
-		}
-	case "Exists", "DoesNotExist":
-		lp.log.Debugf("Exists/DoesNotExist operators don't require field validation")
-	case "LessThan", "GreaterThan", "GreaterThanOrEqualTo", "LessThanOrEqualTo":
-		// These operators require both fields to be present, parseable as integers, and comparable
-		if field1 == "" {
-			return fmt.Errorf("field1 is required for operator %s", op)
-		}
-		if field2 == "" {
-			return fmt.Errorf("field2 is required for operator %s", op)
-		}
-		// Try to parse as integers for comparison
-		_, err1 := strconv.Atoi(field1)
-		_, err2 := strconv.Atoi(field2)
-		if err1 != nil || err2 != nil {
-			return fmt.Errorf("field1 and field2 must be parseable as integers for operator %s", op)
-		}
-	default:
-		return fmt.Errorf("unsupported operator: %s", op)
-	}
-
-	return nil
-}
-
-func evaluateConditional(f1Str, f2Str, op string) (bool, error) {
-	switch op {
-	case "Equals":
-		return f1Str == f2Str, nil
-	case "DoesNotEqual":
-		return f1Str != f2Str, nil
-	case "Exists":
-		return f1Str != "", nil
-	case "DoesNotExist":
-		return f1Str == "", nil
-	case "LessThan":
-		i1, err1 := strconv.Atoi(f1Str)
-		i2, err2 := strconv.Atoi(f2Str)
-		if err1 != nil || err2 != nil {
-			panic(fmt.Errorf("cannot compare non-numeric values: %s and %s", f1Str, f2Str))
-		}
-		return i1 < i2, nil
-	case "GreaterThan":
-		i1, err1 := strconv.Atoi(f1Str)
-		i2, err2 := strconv.Atoi(f2Str)
-		if err1 != nil || err2 != nil {
-			panic(fmt.Errorf("cannot compare non-numeric values: %s and %s", f1Str, f2Str))
-		}
-		return i1 > i2, nil
-	case "GreaterThanOrEqualTo":
-		i1, err1 := strconv.Atoi(f1Str)
-		i2, err2 := strconv.Atoi(f2Str)
-		if err1 != nil || err2 != nil {
-			panic(fmt.Errorf("cannot compare non-numeric values: %s and %s", f1Str, f2Str))
-		}
-		return i1 >= i2, nil
-	case "LessThanOrEqualTo":
-		i1, err1 := strconv.Atoi(f1Str)
-		i2, err2 := strconv.Atoi(f2Str)
-		if err1 != nil || err2 != nil {
-			panic(fmt.Errorf("cannot compare non-numeric values: %s and %s", f1Str, f2Str))
-		}
-		return i1 <= i2, nil
-	default:
-		panic(fmt.Errorf("unsupported operator: %s", op))
-	}
-}
@@ -0,0 +1,232 @@
+package log_processor
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/devon-caron/metrifuge/k8s/api"
+	logsource "github.com/devon-caron/metrifuge/k8s/api/log_source"
+	"github.com/devon-caron/metrifuge/k8s/api/ruleset"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	otellog "go.opentelemetry.io/otel/log"
+)
+
+func TestOnErrorPolicies(t *testing.T) {
+	counted := &api.Rule{Name: "counted", Pattern: "%{WORD:user}", Action: "Forward", Metrics: []api.MetricTemplate{
+		{Name: "lines", Kind: "Int64Counter", Value: api.MetricValue{Type: "Int64", ManualValue: "1"}},
+	}}
+	failing := func(onError string) *api.Rule {
+		// user is not a number, so the condition fails to evaluate on every line
+		return &api.Rule{Name: "failing", Pattern: "%{WORD:user}", Action: "Conditional", OnError: onError,
+			Conditional: &api.Conditional{
+				Condition:   api.Condition{Expression: `int(raw["user"]) > 0`},
+				ActionTrue:  "Forward",
+				ActionFalse: "Discard",
+			}}
+	}
+	tests := []struct {
+		name  string
+		rules []*api.Rule
+		want  int
+	}{
+		{"skip", []*api.Rule{counted, failing("Skip")}, 1},
+		{"forward raw", []*api.Rule{counted, failing("ForwardRaw")}, 2},
+		{"discard line after other rules", []*api.Rule{counted, failing("DiscardLine")}, 0},
+		{"discard line before other rules", []*api.Rule{failing("DiscardLine"), counted}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := evaluate(t, "bob", tt.rules...); len(got) != tt.want {
+				t.Errorf("EvaluateLine() = %v, want %d items", got, tt.want)
+			}
+		})
+	}
+}
+
+// evaluate runs a line of a log source through a RuleSet of the given rules.
+func evaluate(t *testing.T, line string, rules ...*api.Rule) []api.ProcessedDataItem {
+	t.Helper()
+	selector := &api.Selector{MatchLabels: map[string]string{"app": "test"}}
+	ls := logsource.LogSource{Metadata: api.Metadata{Namespace: "default", Name: "app", Labels: selector.MatchLabels}}
+	rs := ruleset.RuleSet{Metadata: api.Metadata{Namespace: "default", Name: "rules"},
+		Spec: ruleset.RuleSetSpec{Selector: selector, Rules: rules}}
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	lp := &LogProcessor{}
+	lp.Initialize([]logsource.LogSource{ls}, []ruleset.RuleSet{rs}, 1, nil, log)
+	sru, err := lp.FindSRU(ls)
+	if err != nil || sru.RuleCount() != len(rules) {
+		t.Fatalf("FindSRU() = %v, want every rule loaded", err)
+	}
+
+	parsed := lp.ParseLine(LogLine{SRU: sru, Source: api.LogSourceInfo{Namespace: "default", Name: "app"}, Line: line, ReadAt: time.Now()})
+	defer lp.ReleaseLine(parsed)
+	return lp.EvaluateLine(parsed)
+}
+
+func TestTransforms(t *testing.T) {
+	tests := []struct {
+		name      string
+		transform api.Transform
+		value     string
+		want      string
+		wantErr   bool
+	}{
+		{"lowercase", api.Transform{Type: "Lowercase"}, "HeLLo", "hello", false},
+		{"replace", api.Transform{Type: "Replace", Pattern: `(\d+)ms`, Replacement: "${1}"}, "250ms", "250", false},
+		{"split", api.Transform{Type: "Split", Separator: "/", Index: 1}, "a/b/c", "b", false},
+		{"split from the end", api.Transform{Type: "Split", Separator: "/", Index: -1}, "a/b/c", "c", false},
+		{"split out of range", api.Transform{Type: "Split", Separator: "/", Index: 3}, "a/b/c", "", true},
+		{"coerce int64", api.Transform{Type: "Coerce", To: "Int64"}, " 3.0 ", "3", false},
+		{"coerce float64", api.Transform{Type: "Coerce", To: "Float64"}, "1.50", "1.5", false},
+		{"coerce bool", api.Transform{Type: "Coerce", To: "Bool"}, "yes", "true", false},
+		{"coerce invalid", api.Transform{Type: "Coerce", To: "Int64"}, "abc", "", true},
+		{"duration", api.Transform{Type: "Duration"}, "1m30s", "90", false},
+		{"binary byte size", api.Transform{Type: "ByteSize"}, "1.5KiB", "1536", false},
+		{"decimal byte size", api.Transform{Type: "ByteSize"}, "2 MB", "2000000", false},
+		{"unknown byte size unit", api.Transform{Type: "ByteSize"}, "3XB", "", true},
+		{"named timestamp layout", api.Transform{Type: "Timestamp", Layout: "RFC3339"}, "2023-11-14T22:13:20Z", "1700000000", false},
+		{"common log timestamp", api.Transform{Type: "Timestamp", Layout: "CommonLog"}, "14/Nov/2023:22:13:20 +0000", "1700000000", false},
+		{"unix milliseconds", api.Transform{Type: "Timestamp", Layout: "UnixMilli"}, "1700000000500", "1700000000.5", false},
+		{"invalid timestamp", api.Transform{Type: "Timestamp", Layout: "Unix"}, "yesterday", "", true},
+		{"default hash", api.Transform{Type: "Hash"}, "abc", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad", false},
+		{"md5 hash", api.Transform{Type: "Hash", Algorithm: "MD5"}, "abc", "900150983cd24fb0d6963f7d28e17f72", false},
+		{"lookup", api.Transform{Type: "Lookup", Table: map[string]string{"404": "not found"}}, "404", "not found", false},
+		{"lookup default", api.Transform{Type: "Lookup", Table: map[string]string{"404": "not found"}, Default: "other"}, "500", "other", false},
+		{"lookup miss", api.Transform{Type: "Lookup", Table: map[string]string{"404": "not found"}}, "500", "500", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.transform.Field = "f"
+			transforms, err := compileTransforms([]api.Transform{tt.transform})
+			if err != nil {
+				t.Fatalf("compileTransforms() = %v", err)
+			}
+			values := map[string]string{"f": tt.value}
+			err = applyTransforms(transforms, values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyTransforms() = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && values["f"] != tt.want {
+				t.Errorf("f = %q, want %q", values["f"], tt.want)
+			}
+		})
+	}
+}
+
+func TestTransformsChain(t *testing.T) {
+	transforms, err := compileTransforms([]api.Transform{
+		{Type: "Lowercase", Field: "unit", Target: "normalized"},
+		{Type: "Lookup", Field: "normalized", Table: map[string]string{"kb": "KiB"}},
+		{Type: "Coerce", Field: "missing", To: "Int64"},
+	})
+	if err != nil {
+		t.Fatalf("compileTransforms() = %v", err)
+	}
+	values := map[string]string{"unit": "KB"}
+	if err := applyTransforms(transforms, values); err != nil {
+		t.Fatalf("applyTransforms() = %v, want a missing field skipped", err)
+	}
+	if values["unit"] != "KB" || values["normalized"] != "KiB" {
+		t.Errorf("values = %v, want the target written and the field kept", values)
+	}
+}
+
+func TestCompileTransformsRejects(t *testing.T) {
+	for _, transform := range []api.Transform{
+		{Type: "Lowercase"},
+		{Type: "Upcase", Field: "f"},
+		{Type: "Replace", Field: "f", Pattern: "("},
+		{Type: "Split", Field: "f"},
+		{Type: "Coerce", Field: "f", To: "Duration"},
+		{Type: "Timestamp", Field: "f"},
+		{Type: "Hash", Field: "f", Algorithm: "CRC32"},
+	} {
+		if _, err := compileTransforms([]api.Transform{transform}); err == nil {
+			t.Errorf("compileTransforms(%+v) accepted an invalid transform", transform)
+		}
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     *api.LogSeverity
+		values   map[string]string
+		want     otellog.Severity
+		wantText string
+	}{
+		{"no severity", nil, map[string]string{"level": "warn"}, otellog.SeverityUndefined, ""},
+		{"default mapping", &api.LogSeverity{Field: "level"}, map[string]string{"level": " WARNING "}, otellog.SeverityWarn, " WARNING "},
+		{"mapping override", &api.LogSeverity{Field: "level", Mapping: map[string]string{"Warning": "ERROR"}},
+			map[string]string{"level": "warning"}, otellog.SeverityError, "warning"},
+		{"severity number", &api.LogSeverity{Field: "level", Mapping: map[string]string{"audit": "10"}},
+			map[string]string{"level": "audit"}, otellog.SeverityInfo2, "audit"},
+		{"unmapped level falls back to the value", &api.LogSeverity{Field: "level", Value: "INFO3"},
+			map[string]string{"level": "verbose"}, otellog.SeverityInfo3, "verbose"},
+		{"unmapped level without a value", &api.LogSeverity{Field: "level"},
+			map[string]string{"level": "verbose"}, otellog.SeverityUndefined, "verbose"},
+		{"missing field falls back to the value", &api.LogSeverity{Field: "level", Value: "warning"},
+			map[string]string{}, otellog.SeverityWarn, ""},
+		{"value only", &api.LogSeverity{Value: "FATAL"}, map[string]string{"level": "debug"}, otellog.SeverityFatal, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm, err := compileSeverity(tt.spec)
+			if err != nil {
+				t.Fatalf("compileSeverity() = %v", err)
+			}
+			if severity, text := sm.resolve(tt.values); severity != tt.want || text != tt.wantText {
+				t.Errorf("resolve() = %v, %q, want %v, %q", severity, text, tt.want, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestCompileSeverityRejects(t *testing.T) {
+	for _, spec := range []*api.LogSeverity{
+		{},
+		{Value: "LOUD"},
+		{Value: "25"},
+		{Field: "level", Mapping: map[string]string{"audit": "0"}},
+	} {
+		if _, err := compileSeverity(spec); err == nil {
+			t.Errorf("compileSeverity(%+v) accepted an invalid severity", spec)
+		}
+	}
+}
+
+func TestForwardedLogAttributes(t *testing.T) {
+	metrics := []api.MetricTemplate{{Name: "lines", Kind: "Int64Counter", Value: api.MetricValue{Type: "Int64", ManualValue: "1"}}}
+	tests := []struct {
+		name  string
+		attrs *api.LogAttributes
+		want  []attribute.KeyValue
+	}{
+		{"none", nil, nil},
+		{"fields, in the given order", &api.LogAttributes{Fields: []string{"user", "status", "missing"}},
+			[]attribute.KeyValue{attribute.String("user", "bob"), attribute.String("status", "500")}},
+		{"all fields, in name order", &api.LogAttributes{All: true}, []attribute.KeyValue{
+			attribute.String("level", "warn"), attribute.String("status", "500"), attribute.String("user", "bob"),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := &api.Rule{Pattern: "%{WORD:level} %{WORD:user} %{NUMBER:status}", Action: "Forward", Metrics: metrics,
+				Severity: &api.LogSeverity{Field: "level"}, LogAttributes: tt.attrs}
+			items := evaluate(t, "warn bob 500", rule)
+			if len(items) != 1 {
+				t.Fatalf("EvaluateLine() = %v, want one item", items)
+			}
+			item := items[0]
+			if item.ForwardLog != "warn bob 500" || item.Severity != otellog.SeverityWarn || item.SeverityText != "warn" {
+				t.Errorf("item = %+v, want the line forwarded as a warning", item)
+			}
+			if !slices.Equal(item.LogAttributes, tt.want) {
+				t.Errorf("log attributes = %v, want %v", item.LogAttributes, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package log_processor
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/devon-caron/metrifuge/k8s"
+	"github.com/devon-caron/metrifuge/k8s/api"
+	"github.com/devon-caron/metrifuge/k8s/api/ruleset"
+	"github.com/devon-caron/metrifuge/status_tracker"
+	"github.com/vjeantet/grok"
+)
+
+// compiledRuleSet is a RuleSet whose patterns have all been compiled by its own grok instance, so
+// that lines are only ever matched against cached expressions.
+type compiledRuleSet struct {
+	ruleSet     ruleset.RuleSet
+	definitions map[string]string // custom patterns, from the RuleSet's ConfigMap and patternDefinitions
+	g           *grok.Grok
+	named       []bool                    // whether the pattern of each rule has named captures
+	transforms  [][]transform             // transforms of each rule
+	severities  []*severityMap            // severity settings of each rule, nil if it has none
+	rewriters   []*rewriter               // rewrite settings of each rule, nil if it has none
+	matchers    map[string]*regexp.Regexp // patterns of Matches conditions
+	expressions []*expressions            // expressions of each rule
+	onErrors    []string                  // error policy of each rule
+}
+
+// newGrok builds the grok instance of a RuleSet from the built-in patterns and its custom ones, which
+// it returns along with the instance as the library of every pattern the instance knows.
+func newGrok(definitions map[string]string) (*grok.Grok, map[string]string, error) {
+	library := maps.Clone(grokPatterns)
+	maps.Copy(library, definitions)
+	g, err := grok.NewWithConfig(&grok.Config{NamedCapturesOnly: true, SkipDefaultPatterns: true, Patterns: library})
+	if err != nil {
+		return nil, nil, err
+	}
+	return g, library, nil
+}
+
+// compilePattern returns the regular expression grok compiles a pattern to, given the library of
+// the grok instance. Grok keeps its compiled expressions to itself, so the pattern is expanded the
+// way grok expands it.
+func compilePattern(library map[string]string, pattern string) (*regexp.Regexp, error) {
+	expanded, err := expandPattern(library, pattern, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand pattern %q: %v", pattern, err)
+	}
+	return regexp.Compile(expanded)
+}
+
+// patternReference is a reference to a library pattern, as grok recognizes them: %{NAME},
+// %{NAME:field} or %{NAME:field:type}.
+var patternReference = regexp.MustCompile(`%{([\w-.]+(?::[\w-.]+(?::[\w-.]+)?)?)}`)
+
+// expandPattern replaces every reference in a pattern with the expression of the library pattern it
+// refers to, itself expanded. As with grok's NamedCapturesOnly, a reference naming a field becomes a
+// group named after the field's alias, and any other reference an unnamed group. expanding lists the
+// library patterns being expanded, so that a pattern that refers to itself is reported.
+func expandPattern(library map[string]string, pattern string, expanding []string) (string, error) {
+	var err error
+	expanded := patternReference.ReplaceAllStringFunc(pattern, func(ref string) string {
+		names := strings.Split(patternReference.FindStringSubmatch(ref)[1], ":")
+		definition, ok := library[names[0]]
+		if !ok {
+			err = errors.Join(err, fmt.Errorf("no pattern found for %s", ref))
+			return ref
+		}
+		if slices.Contains(expanding, names[0]) {
+			err = errors.Join(err, fmt.Errorf("pattern %s refers to itself", names[0]))
+			return ref
+		}
+		expression, expandErr := expandPattern(library, definition, append(expanding, names[0]))
+		if expandErr != nil {
+			err = errors.Join(err, expandErr)
+			return ref
+		}
+		if len(names) > 1 {
+			return "(?P<" + captureAlias(names[1]) + ">" + expression + ")"
+		}
+		return "(" + expression + ")"
+	})
+	return expanded, err
+}
+
+// captureAlias is the name of a capture in the expression grok compiles a pattern to, in which
+// characters that can't be in group names are replaced.
+func captureAlias(name string) string {
+	return nonWord.ReplaceAllString(name, "_")
+}
+
+var nonWord = regexp.MustCompile(`\W`)
+
+// captureNames lists the named captures of a pattern once compiled, including those of the library
+// and custom patterns it uses. Grok doesn't expose its compiled expressions, but parsing a line gives
+// every named capture if the line matches, so the pattern is parsed as an alternative to an empty
+// expression, which matches an empty line.
+func captureNames(g *grok.Grok, pattern string) ([]string, error) {
+	captured, err := g.Parse("(?:"+pattern+")|", "")
+	if err != nil {
+		return nil, err
+	}
+	return slices.Sorted(maps.Keys(captured)), nil
+}
+
+// ruleSetDefinitions are the pattern definitions of a RuleSet, or why they could not be loaded.
+type ruleSetDefinitions struct {
+	definitions map[string]string
+	err         error
+}
+
+// loadDefinitions gets the pattern definitions of every RuleSet by key. ConfigMaps are fetched once
+// each, however many RuleSets use them.
+func loadDefinitions(ruleSets []ruleset.RuleSet, k8sClient *api.K8sClientWrapper) map[string]ruleSetDefinitions {
+	type configMap struct {
+		data map[string]string
+		err  error
+	}
+	fetched := make(map[string]configMap)
+	getConfigMap := func(namespace, name string) (map[string]string, error) {
+		key := status_tracker.Key(namespace, name)
+		cm, ok := fetched[key]
+		if !ok {
+			cm.data, cm.err = k8s.GetConfigMapData(k8sClient, namespace, name)
+			fetched[key] = cm
+		}
+		return cm.data, cm.err
+	}
+
+	loaded := make(map[string]ruleSetDefinitions)
+	for _, rs := range ruleSets {
+		definitions, err := patternDefinitions(rs, getConfigMap)
+		loaded[status_tracker.Key(rs.Metadata.Namespace, rs.Metadata.Name)] = ruleSetDefinitions{definitions: definitions, err: err}
+	}
+	return loaded
+}
+
+// loadRuleSet returns the compiled version of a RuleSet, reusing the current one if neither the
+// RuleSet nor its pattern definitions changed. A RuleSet that fails to compile is reported on its
+// status and keeps its last good version, which is nil if it never compiled.
+func (lp *LogProcessor) loadRuleSet(key string, rs ruleset.RuleSet, loaded ruleSetDefinitions) *compiledRuleSet {
+	tracker := status_tracker.GetInstance()
+	prev := lp.ruleSets[key]
+
+	definitions, err := loaded.definitions, loaded.err
+	if err == nil {
+		if prev != nil && reflect.DeepEqual(prev.ruleSet.Spec, rs.Spec) && maps.Equal(prev.definitions, definitions) {
+			return prev
+		}
+		var crs *compiledRuleSet
+		if crs, err = compileRuleSet(rs, definitions); err == nil {
+			tracker.SetRuleSetLoadError(key, nil)
+			lp.log.Infof("compiled %d rules of rule set %s with %d custom patterns", len(rs.Spec.Rules), key, len(definitions))
+			return crs
+		}
+	}
+
+	tracker.SetRuleSetLoadError(key, err)
+	if prev != nil {
+		lp.log.Errorf("rejected rule set %s, keeping generation %d: %v", key, prev.ruleSet.Metadata.Generation, err)
+	} else {
+		lp.log.Errorf("rejected rule set %s: %v", key, err)
+	}
+	return prev
+}
+
+// compileRuleSet builds a grok instance with the custom patterns of a RuleSet and compiles the
+// pattern of every rule with it. A RuleSet with an invalid pattern or definition is rejected as a
+// whole.
+func compileRuleSet(rs ruleset.RuleSet, definitions map[string]string) (*compiledRuleSet, error) {
+	g, library, err := newGrok(definitions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern definitions: %v", err)
+	}
+
+	switch rs.Spec.Evaluation {
+	case "", ruleset.EvaluationAllMatch, ruleset.EvaluationFirstMatch:
+	default:
+		return nil, fmt.Errorf("unknown evaluation mode: %s", rs.Spec.Evaluation)
+	}
+
+	named := make([]bool, len(rs.Spec.Rules))
+	transforms := make([][]transform, len(rs.Spec.Rules))
+	severities := make([]*severityMap, len(rs.Spec.Rules))
+	rewriters := make([]*rewriter, len(rs.Spec.Rules))
+	matchers := make(map[string]*regexp.Regexp)
+	exprs := make([]*expressions, len(rs.Spec.Rules))
+	onErrors := make([]string, len(rs.Spec.Rules))
+	for i, rule := range rs.Spec.Rules {
+		// Parsing compiles the pattern and caches it in g, which is all that is needed here
+		if _, err := g.Parse(rule.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern of rule %d %q: %v", i, rule.Pattern, err)
+		}
+		captures, err := captureNames(g, rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern of rule %d %q: %v", i, rule.Pattern, err)
+		}
+		named[i] = len(captures) > 0
+		if transforms[i], err = compileTransforms(rule.Transforms); err != nil {
+			return nil, fmt.Errorf("invalid transforms of rule %d: %v", i, err)
+		}
+		if rule.Timestamp != nil && (rule.Timestamp.Field == "" || rule.Timestamp.Layout == "") {
+			return nil, fmt.Errorf("timestamp of rule %d needs both a field and a layout", i)
+		}
+		if severities[i], err = compileSeverity(rule.Severity); err != nil {
+			return nil, fmt.Errorf("invalid severity of rule %d: %v", i, err)
+		}
+		if rewriters[i], err = compileRewrite(rule.Rewrite, library, rule.Pattern); err != nil {
+			return nil, fmt.Errorf("invalid rewrite of rule %d: %v", i, err)
+		}
+		switch strings.ToLower(rule.Action) {
+		case "forward", "discard":
+		case "conditional":
+			if rule.Conditional == nil {
+				return nil, fmt.Errorf("rule %d has a conditional action but no conditional block", i)
+			}
+		default:
+			return nil, fmt.Errorf("unknown action of rule %d: %s", i, rule.Action)
+		}
+		exprs[i] = newExpressions(ruleFields(rule, captures))
+		if err := compileMetrics(rule.Metrics, exprs[i]); err != nil {
+			return nil, fmt.Errorf("invalid metrics of rule %d: %v", i, err)
+		}
+		if err := compileConditional(rule.Conditional, matchers, exprs[i]); err != nil {
+			return nil, fmt.Errorf("invalid conditional of rule %d: %v", i, err)
+		}
+		if onErrors[i], err = compileOnError(rule.OnError); err != nil {
+			return nil, fmt.Errorf("invalid rule %d: %v", i, err)
+		}
+	}
+
+	return &compiledRuleSet{
+		ruleSet:     rs,
+		definitions: definitions,
+		g:           g,
+		named:       named,
+		transforms:  transforms,
+		severities:  severities,
+		rewriters:   rewriters,
+		matchers:    matchers,
+		expressions: exprs,
+		onErrors:    onErrors,
+	}, nil
+}
+
+// ValidateRuleSet compiles a RuleSet the way it is compiled when loaded, without loading it, so
+// that an invalid RuleSet can be rejected before it is ever applied.
+func ValidateRuleSet(rs ruleset.RuleSet, k8sClient *api.K8sClientWrapper) error {
+	definitions, err := patternDefinitions(rs, func(namespace, name string) (map[string]string, error) {
+		return k8s.GetConfigMapData(k8sClient, namespace, name)
+	})
+	if err != nil {
+		return err
+	}
+	_, err = compileRuleSet(rs, definitions)
+	return err
+}
+
+// patternDefinitions merges the custom patterns of a RuleSet: those of its ConfigMap, overridden by
+// its own patternDefinitions.
+func patternDefinitions(rs ruleset.RuleSet, getConfigMap func(namespace, name string) (map[string]string, error)) (map[string]string, error) {
+	definitions := make(map[string]string)
+
+	if ref := rs.Spec.PatternConfigMap; ref != nil {
+		namespace := ref.Namespace
+		if namespace == "" {
+			namespace = rs.Metadata.Namespace
+		}
+		data, err := getConfigMap(namespace, ref.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load pattern library: %w", err)
+		}
+		// Entries are applied in key order, so that a pattern defined twice always resolves the same way
+		for _, key := range slices.Sorted(maps.Keys(data)) {
+			if err := parsePatternFile(data[key], definitions); err != nil {
+				return nil, fmt.Errorf("failed to parse pattern file %s of configmap %s/%s: %w", key, namespace, ref.Name, err)
+			}
+		}
+	}
+
+	maps.Copy(definitions, rs.Spec.PatternDefinitions)
+	return definitions, nil
+}
+
+// parsePatternFile reads grok pattern definitions in the usual pattern file format: one
+// "NAME expression" per line, with blank lines and lines starting with # ignored.
+func parsePatternFile(contents string, definitions map[string]string) error {
+	for i, line := range strings.Split(contents, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, expression, ok := strings.Cut(line, " ")
+		if !ok {
+			return fmt.Errorf("line %d has no expression: %q", i+1, line)
+		}
+		definitions[name] = strings.TrimSpace(expression)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package log_processor
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/devon-caron/metrifuge/k8s/api"
+	"github.com/devon-caron/metrifuge/k8s/api/ruleset"
+	"github.com/sirupsen/logrus"
+	"github.com/vjeantet/grok"
+)
+
+const apacheLine = `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326`
+
+func TestLibraryPatternCaptures(t *testing.T) {
+	rs := ruleset.RuleSet{Spec: ruleset.RuleSetSpec{Rules: []*api.Rule{
+		{Pattern: "%{COMMONAPACHELOG}", Action: "Forward"},
+		{Pattern: "%{APACHE}", Action: "Forward"},
+		{Pattern: "GET", Action: "Forward"},
+	}}}
+	crs, err := compileRuleSet(rs, map[string]string{"APACHE": "%{COMMONAPACHELOG}"})
+	if err != nil {
+		t.Fatalf("compileRuleSet() = %v", err)
+	}
+	if want := []bool{true, true, false}; !slices.Equal(crs.named, want) {
+		t.Errorf("named = %v, want %v", crs.named, want)
+	}
+
+	lp := &LogProcessor{log: logrus.New()}
+	for i, rule := range rs.Spec.Rules {
+		values := make(map[string]string)
+		matched, err := lp.parseLog(apacheLine, ruleRef{rule: rule, g: crs.g, named: crs.named[i]}, values)
+		if err != nil || !matched {
+			t.Fatalf("rule %d: parseLog() = %v, %v, want a match", i, matched, err)
+		}
+		if crs.named[i] && values["response"] != "200" {
+			t.Errorf("rule %d: response = %q, want 200", i, values["response"])
+		}
+	}
+}
+
+// TestCompiledPatternCaptures checks that the expression a pattern is expanded to captures what grok
+// captures, at the same offsets. Grok is built with its own defaults here, so that the built-in
+// patterns copied from it are checked too.
+func TestCompiledPatternCaptures(t *testing.T) {
+	definitions := map[string]string{
+		"REQUEST": `%{WORD:http.method} %{URIPATHPARAM:http.path}`,
+		"APACHE":  "%{COMMONAPACHELOG}",
+	}
+	tests := []struct {
+		pattern string
+		line    string
+	}{
+		{"%{COMMONAPACHELOG}", apacheLine},
+		{"%{APACHE}", apacheLine},
+		{`%{IPORHOST:client} "%{REQUEST}" %{NUMBER:status:int}`, `10.0.0.1 "GET /index.html?q=1" 404`},
+		{`%{SYSLOGBASE} %{GREEDYDATA:message}`, `Oct 11 22:14:15 host sshd[4123]: Accepted publickey for root`},
+		{`%{URIHOST} %{WORD:user}`, `example.com:8080 alice`},
+	}
+	g, err := grok.NewWithConfig(&grok.Config{NamedCapturesOnly: true, Patterns: definitions})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, library, err := newGrok(definitions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			values, err := g.Parse(tt.pattern, tt.line)
+			if err != nil || len(values) == 0 {
+				t.Fatalf("Parse() = %v, %v, want a match", values, err)
+			}
+			pattern, err := compilePattern(library, tt.pattern)
+			if err != nil {
+				t.Fatalf("compilePattern() = %v", err)
+			}
+			match := pattern.FindStringSubmatchIndex(tt.line)
+			if match == nil {
+				t.Fatalf("compiled pattern does not match %q", tt.line)
+			}
+			captured := make(map[string]string)
+			for i, name := range pattern.SubexpNames() {
+				if name != "" && match[2*i] >= 0 {
+					captured[name] = tt.line[match[2*i]:match[2*i+1]]
+				}
+			}
+			for name, value := range values {
+				if got := captured[captureAlias(name)]; got != value {
+					t.Errorf("capture %s = %q, want %q", name, got, value)
+				}
+			}
+		})
+	}
+}
+
+func TestCompilePatternRejects(t *testing.T) {
+	library := map[string]string{"LOOP": "a%{LOOP}", "WORD": `\w+`}
+	for _, pattern := range []string{"%{NOSUCHPATTERN:user}", "%{LOOP}"} {
+		if _, err := compilePattern(library, pattern); err == nil {
+			t.Errorf("compilePattern(%q) succeeded, want an error", pattern)
+		}
+	}
+}
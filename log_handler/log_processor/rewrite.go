@@ -0,0 +1,294 @@
+package log_processor
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/devon-caron/metrifuge/k8s/api"
+)
+
+const defaultMask = "[REDACTED]"
+
+// rewriter is the api.Rewrite of a rule, with its template parsed and its detectors looked up when
+// its RuleSet is loaded.
+type rewriter struct {
+	template  []templatePart // nil if the body is the line itself
+	fields    []string
+	pattern   *regexp.Regexp // the rule's pattern, to find where the redacted fields are in a line, nil if there are none
+	detectors []detector
+	mask      string
+}
+
+// templatePart is literal text of a template, or a reference to a field
+type templatePart struct {
+	literal string
+	field   string // expanded in place of the part if set
+}
+
+// detector finds sensitive text in a log line. If its pattern has a group, only the text of the
+// group is masked, so that "password=hunter2" keeps its key.
+type detector struct {
+	pattern *regexp.Regexp
+	valid   func(match string) bool // further check of a match, nil if every match is sensitive
+}
+
+// Built-in detectors by lowercased name
+var detectors = map[string][]detector{
+	"email": {
+		{pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+	},
+	"creditcard": {
+		{pattern: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), valid: luhn},
+	},
+	"token": {
+		{pattern: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9\-._~+/]+=*)`)},
+		{pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)},
+		{pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+		{pattern: regexp.MustCompile(`(?i)\b(?:api[_-]?key|access[_-]?key|secret|token|password|passwd|pwd)"?\s*[=:]\s*"?([^\s"&,;]+)`)},
+	},
+	"ip": {
+		{pattern: regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`)},
+		{pattern: regexp.MustCompile(`[0-9A-Fa-f]{0,4}:[0-9A-Fa-f:.]*:[0-9A-Fa-f.]*`), valid: isIPv6},
+	},
+}
+
+// compileRewrite checks the rewrite settings of a rule, which may be nil. Redacted fields are found
+// in lines by the rule's pattern, expanded from the library of its grok instance.
+func compileRewrite(spec *api.Rewrite, library map[string]string, pattern string) (*rewriter, error) {
+	if spec == nil {
+		return nil, nil
+	}
+	rw := &rewriter{fields: spec.RedactFields, mask: spec.Mask}
+	if rw.mask == "" {
+		rw.mask = defaultMask
+	}
+	if spec.Template != "" {
+		var err error
+		if rw.template, err = parseTemplate(spec.Template); err != nil {
+			return nil, err
+		}
+	}
+	if len(rw.fields) > 0 {
+		var err error
+		if rw.pattern, err = compilePattern(library, pattern); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range spec.Detectors {
+		found, ok := detectors[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown detector: %v", name)
+		}
+		rw.detectors = append(rw.detectors, found...)
+	}
+	return rw, nil
+}
+
+// parseTemplate splits a template into literal text and references to fields, written ${field} or
+// $field. $$ is a literal $, as is a $ that starts no reference.
+func parseTemplate(template string) ([]templatePart, error) {
+	parts := []templatePart{}
+	var literal strings.Builder
+	for i := 0; i < len(template); i++ {
+		if template[i] != '$' || i+1 == len(template) {
+			literal.WriteByte(template[i])
+			continue
+		}
+		field := ""
+		switch next := template[i+1]; {
+		case next == '$':
+			literal.WriteByte('$')
+			i++
+			continue
+		case next == '{':
+			end := strings.IndexByte(template[i+2:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed ${ at %d of template %q", i, template)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("empty ${} at %d of template %q", i, template)
+			}
+			field = template[i+2 : i+2+end]
+			i += 2 + end
+		default:
+			end := i + 1
+			for end < len(template) && isFieldNameByte(template[end]) {
+				end++
+			}
+			if end == i+1 {
+				literal.WriteByte('$')
+				continue
+			}
+			field = template[i+1 : end]
+			i = end - 1
+		}
+		if literal.Len() > 0 {
+			parts = append(parts, templatePart{literal: literal.String()})
+			literal.Reset()
+		}
+		parts = append(parts, templatePart{field: field})
+	}
+	if literal.Len() > 0 {
+		parts = append(parts, templatePart{literal: literal.String()})
+	}
+	return parts, nil
+}
+
+func isFieldNameByte(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// locate matches a line against the rule's pattern, so that the fields it captured can be found
+// in it. It returns nil if the rule redacts no fields or the line doesn't match.
+func (rw *rewriter) locate(line string) []int {
+	if rw == nil || rw.pattern == nil {
+		return nil
+	}
+	return rw.pattern.FindStringSubmatchIndex(line)
+}
+
+// spans returns where the redacted fields were captured in a line, given the match of locate.
+func (rw *rewriter) spans(match []int) [][2]int {
+	if match == nil {
+		return nil
+	}
+	var spans [][2]int
+	for _, field := range rw.fields {
+		for i, name := range rw.pattern.SubexpNames() {
+			if name == captureAlias(field) && match[2*i] >= 0 && match[2*i] < match[2*i+1] {
+				spans = append(spans, [2]int{match[2*i], match[2*i+1]})
+			}
+		}
+	}
+	return spans
+}
+
+// rewrite builds the body to forward for a line: its template expanded with the line's fields, or
+// the line itself, with redacted fields and whatever the detectors find masked.
+func (rw *rewriter) rewrite(line string, values map[string]string, match []int) string {
+	if rw.template == nil {
+		return rw.redact(line, rw.spans(match))
+	}
+	var b strings.Builder
+	for _, part := range rw.template {
+		if part.field == "" {
+			b.WriteString(part.literal)
+			continue
+		}
+		b.WriteString(rw.fieldValue(part.field, line, values, match))
+	}
+	return rw.redact(b.String(), nil)
+}
+
+// attribute returns a field to attach to a forwarded log, redacted like the body.
+func (rw *rewriter) attribute(field string, line string, values map[string]string, match []int) string {
+	if rw == nil {
+		return values[field]
+	}
+	return rw.redact(rw.fieldValue(field, line, values, match), nil)
+}
+
+// fieldValue returns a field of a line with its redacted text masked: all of it for a redacted
+// field, or the text of the redacted fields it contains if it was captured around them.
+func (rw *rewriter) fieldValue(field string, line string, values map[string]string, match []int) string {
+	if slices.Contains(rw.fields, field) {
+		return rw.mask
+	}
+	value := values[field]
+	i := -1
+	if match != nil {
+		i = rw.pattern.SubexpIndex(captureAlias(field))
+	}
+	// Fields changed by transforms or not captured by the pattern are kept as they are
+	if i < 0 || match[2*i] < 0 || line[match[2*i]:match[2*i+1]] != value {
+		return value
+	}
+	start := match[2*i]
+	var spans [][2]int
+	for _, span := range rw.spans(match) {
+		if span[0] < start+len(value) && span[1] > start {
+			spans = append(spans, [2]int{max(span[0], start) - start, min(span[1], start+len(value)) - start})
+		}
+	}
+	return maskSpans(value, spans, rw.mask)
+}
+
+// redact masks whatever the detectors find in a body and the given spans of it, such as where the
+// redacted fields of a line are. Lines of rules without rewrite settings are left alone.
+func (rw *rewriter) redact(body string, spans [][2]int) string {
+	if rw == nil {
+		return body
+	}
+	// Detectors run on the body as it is, so that a redacted field doesn't break up, say, an email address around it
+	for _, d := range rw.detectors {
+		spans = append(spans, d.find(body)...)
+	}
+	return maskSpans(body, spans, rw.mask)
+}
+
+// maskSpans replaces spans of a string with a mask, overlapping and adjacent spans with a single one.
+func maskSpans(s string, spans [][2]int, mask string) string {
+	if len(spans) == 0 {
+		return s
+	}
+	slices.SortFunc(spans, func(a, b [2]int) int { return a[0] - b[0] })
+	var b strings.Builder
+	end := 0 // of the text written so far
+	for i, span := range spans {
+		if i > 0 && span[0] <= end {
+			// Overlaps or follows the span masked last, whose mask covers it
+			end = max(end, span[1])
+			continue
+		}
+		b.WriteString(s[end:span[0]])
+		b.WriteString(mask)
+		end = span[1]
+	}
+	b.WriteString(s[end:])
+	return b.String()
+}
+
+// find returns the spans of a string that the detector considers sensitive.
+func (d detector) find(s string) [][2]int {
+	var spans [][2]int
+	for _, m := range d.pattern.FindAllStringSubmatchIndex(s, -1) {
+		start, end := m[0], m[1]
+		if len(m) > 2 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		if d.valid != nil && !d.valid(s[start:end]) {
+			continue
+		}
+		spans = append(spans, [2]int{start, end})
+	}
+	return spans
+}
+
+// luhn reports whether the digits of a number pass the Luhn checksum of card numbers.
+func luhn(number string) bool {
+	sum, digits := 0, 0
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if digits%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+	}
+	return digits >= 13 && sum%10 == 0
+}
+
+// isIPv6 reports whether a match is an IPv6 address rather than, say, a time of day.
+func isIPv6(match string) bool {
+	ip := net.ParseIP(match)
+	return ip != nil && strings.Count(match, ":") >= 2
+}
@@ -0,0 +1,112 @@
+package log_processor
+
+import (
+	"testing"
+
+	"github.com/devon-caron/metrifuge/k8s/api"
+	"github.com/vjeantet/grok"
+)
+
+func newTestRewriter(t *testing.T, spec *api.Rewrite, pattern string) (*rewriter, *grok.Grok) {
+	t.Helper()
+	g, library, err := newGrok(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Parse(pattern, ""); err != nil {
+		t.Fatal(err)
+	}
+	rw, err := compileRewrite(spec, library, pattern)
+	if err != nil {
+		t.Fatalf("compileRewrite() = %v", err)
+	}
+	return rw, g
+}
+
+func TestRewrite(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    *api.Rewrite
+		pattern string
+		line    string
+		want    string
+	}{
+		{
+			name:    "masks the capture, not other occurrences",
+			spec:    &api.Rewrite{RedactFields: []string{"user"}},
+			pattern: "%{WORD:user} logged in from %{WORD:host}",
+			line:    "a logged in from a",
+			want:    "[REDACTED] logged in from a",
+		},
+		{
+			name:    "masks captures of library patterns",
+			spec:    &api.Rewrite{RedactFields: []string{"clientip"}, Mask: "***"},
+			pattern: "%{COMMONAPACHELOG}",
+			line:    apacheLine,
+			want:    `*** - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326`,
+		},
+		{
+			name:    "merges a detector match around a redacted field",
+			spec:    &api.Rewrite{RedactFields: []string{"name"}, Detectors: []string{"Email"}},
+			pattern: `to %{WORD:name}@`,
+			line:    "mail to bob@example.com",
+			want:    "mail to [REDACTED]",
+		},
+		{
+			name:    "expands templates with literal dollars",
+			spec:    &api.Rewrite{Template: "$user paid $$${amount} $ in ${user}", RedactFields: []string{"amount"}},
+			pattern: "%{WORD:user} %{NUMBER:amount}",
+			line:    "bob 10",
+			want:    "bob paid $[REDACTED] $ in bob",
+		},
+		{
+			name:    "masks redacted captures inside template fields",
+			spec:    &api.Rewrite{Template: "${message}", RedactFields: []string{"password"}},
+			pattern: "(?P<message>login %{WORD:user} %{WORD:password})",
+			line:    "login bob bob",
+			want:    "login bob [REDACTED]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw, g := newTestRewriter(t, tt.spec, tt.pattern)
+			values, err := g.Parse(tt.pattern, tt.line)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := rw.rewrite(tt.line, values, rw.locate(tt.line)); got != tt.want {
+				t.Errorf("rewrite() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogAttributesAreRedacted(t *testing.T) {
+	spec := &api.Rewrite{RedactFields: []string{"password"}, Detectors: []string{"Email"}}
+	pattern := "%{WORD:user} %{WORD:password} (?P<message>.*)"
+	rw, g := newTestRewriter(t, spec, pattern)
+	line := "bob hunter2 contact bob@example.com"
+	values, err := g.Parse(pattern, line)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attrs := logAttributes(&api.LogAttributes{All: true}, line, values, rw, rw.locate(line))
+	want := map[string]string{"user": "bob", "password": "[REDACTED]", "message": "contact [REDACTED]"}
+	if len(attrs) != len(want) {
+		t.Fatalf("logAttributes() = %v, want %v", attrs, want)
+	}
+	for _, kv := range attrs {
+		if got := kv.Value.AsString(); got != want[string(kv.Key)] {
+			t.Errorf("attribute %s = %q, want %q", kv.Key, got, want[string(kv.Key)])
+		}
+	}
+}
+
+func TestParseTemplateRejectsUnclosedReference(t *testing.T) {
+	for _, template := range []string{"${user", "${}"} {
+		if _, err := parseTemplate(template); err == nil {
+			t.Errorf("parseTemplate(%q) accepted it", template)
+		}
+	}
+}
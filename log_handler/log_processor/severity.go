@@ -0,0 +1,95 @@
+package log_processor
+
+import (
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+
+	"github.com/devon-caron/metrifuge/k8s/api"
+	"go.opentelemetry.io/otel/log"
+)
+
+// severityMap is the api.LogSeverity of a rule, with its severities parsed when its RuleSet is loaded.
+type severityMap struct {
+	field   string
+	mapping map[string]log.Severity // lowercased levels, the defaults overridden by the rule's own mapping
+	value   log.Severity
+}
+
+// Severities of common level names, following the syslog mapping of the OpenTelemetry log data model
+var defaultSeverities = map[string]log.Severity{
+	"trace": log.SeverityTrace, "finest": log.SeverityTrace, "finer": log.SeverityTrace2,
+	"debug": log.SeverityDebug, "dbg": log.SeverityDebug, "fine": log.SeverityDebug, "d": log.SeverityDebug,
+	"info": log.SeverityInfo, "information": log.SeverityInfo, "informational": log.SeverityInfo, "i": log.SeverityInfo,
+	"notice": log.SeverityInfo2, "warn": log.SeverityWarn, "warning": log.SeverityWarn, "w": log.SeverityWarn,
+	"error": log.SeverityError, "err": log.SeverityError, "severe": log.SeverityError, "e": log.SeverityError,
+	"crit": log.SeverityError2, "critical": log.SeverityError2, "alert": log.SeverityError3,
+	"fatal": log.SeverityFatal, "panic": log.SeverityFatal, "emerg": log.SeverityFatal, "emergency": log.SeverityFatal, "f": log.SeverityFatal,
+}
+
+// compileSeverity checks the severity settings of a rule, which may be nil.
+func compileSeverity(spec *api.LogSeverity) (*severityMap, error) {
+	if spec == nil {
+		return nil, nil
+	}
+	if spec.Field == "" && spec.Value == "" {
+		return nil, fmt.Errorf("severity needs a field or a value")
+	}
+
+	sm := &severityMap{field: spec.Field, mapping: defaultSeverities}
+	if len(spec.Mapping) > 0 {
+		sm.mapping = maps.Clone(defaultSeverities)
+		for level, name := range spec.Mapping {
+			severity, err := parseSeverity(name)
+			if err != nil {
+				return nil, fmt.Errorf("invalid severity for level %q: %v", level, err)
+			}
+			sm.mapping[strings.ToLower(level)] = severity
+		}
+	}
+	if spec.Value != "" {
+		severity, err := parseSeverity(spec.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid severity value: %v", err)
+		}
+		sm.value = severity
+	}
+	return sm, nil
+}
+
+// resolve returns the severity of a line and the level it was found from, which is empty if the
+// line's field is missing. Levels that aren't mapped get the rule's value, if any.
+func (sm *severityMap) resolve(values map[string]string) (log.Severity, string) {
+	if sm == nil {
+		return log.SeverityUndefined, ""
+	}
+	if level, ok := values[sm.field]; ok && sm.field != "" {
+		if severity, ok := sm.mapping[strings.ToLower(strings.TrimSpace(level))]; ok {
+			return severity, level
+		}
+		return sm.value, level
+	}
+	return sm.value, ""
+}
+
+// parseSeverity parses a severity given as an OpenTelemetry severity name such as WARN or ERROR2,
+// a common level name such as warning, or a severity number from 1 to 24.
+func parseSeverity(name string) (log.Severity, error) {
+	name = strings.TrimSpace(name)
+	if number, err := strconv.Atoi(name); err == nil {
+		if number < int(log.SeverityTrace1) || number > int(log.SeverityFatal4) {
+			return log.SeverityUndefined, fmt.Errorf("severity number %d out of range", number)
+		}
+		return log.Severity(number), nil
+	}
+	for severity := log.SeverityTrace1; severity <= log.SeverityFatal4; severity++ {
+		if strings.EqualFold(severity.String(), name) {
+			return severity, nil
+		}
+	}
+	if severity, ok := defaultSeverities[strings.ToLower(name)]; ok {
+		return severity, nil
+	}
+	return log.SeverityUndefined, fmt.Errorf("unknown severity: %v", name)
+}
@@ -0,0 +1,246 @@
+package log_processor
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/devon-caron/metrifuge/k8s/api"
+)
+
+// transform is an api.Transform checked and prepared when its RuleSet is loaded.
+type transform struct {
+	api.Transform
+	kind    string         // lowercased Type
+	pattern *regexp.Regexp // Replace only
+}
+
+// Multipliers of byte size units. Units are matched case-insensitively, and the IEC ones are binary.
+var byteUnits = map[string]float64{
+	"": 1, "b": 1,
+	"k": 1e3, "kb": 1e3, "kib": 1 << 10,
+	"m": 1e6, "mb": 1e6, "mib": 1 << 20,
+	"g": 1e9, "gb": 1e9, "gib": 1 << 30,
+	"t": 1e12, "tb": 1e12, "tib": 1 << 40,
+}
+
+var byteSize = regexp.MustCompile(`^\s*([0-9]*\.?[0-9]+)\s*([a-zA-Z]*)\s*$`)
+
+// Named layouts accepted by Timestamp transforms, besides Go layouts
+var timestampLayouts = map[string]string{
+	"rfc3339":   time.RFC3339Nano,
+	"rfc1123":   time.RFC1123,
+	"rfc1123z":  time.RFC1123Z,
+	"unixdate":  time.UnixDate,
+	"ansic":     time.ANSIC,
+	"datetime":  time.DateTime,
+	"stamp":     time.Stamp,
+	"commonlog": "02/Jan/2006:15:04:05 -0700",
+}
+
+// compileTransforms checks the transforms of a rule and compiles their regular expressions.
+func compileTransforms(transforms []api.Transform) ([]transform, error) {
+	compiled := make([]transform, 0, len(transforms))
+	for i, t := range transforms {
+		ct := transform{Transform: t, kind: strings.ToLower(t.Type)}
+		if t.Field == "" {
+			return nil, fmt.Errorf("transform %d has no field", i)
+		}
+		if ct.Target == "" {
+			ct.Target = t.Field
+		}
+
+		switch ct.kind {
+		case "lowercase", "duration", "bytesize", "lookup":
+		case "replace":
+			pattern, err := regexp.Compile(t.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern of transform %d: %v", i, err)
+			}
+			ct.pattern = pattern
+		case "split":
+			if t.Separator == "" {
+				return nil, fmt.Errorf("split transform %d has no separator", i)
+			}
+		case "coerce":
+			switch strings.ToLower(t.To) {
+			case "int64", "float64", "bool":
+			default:
+				return nil, fmt.Errorf("coerce transform %d has unknown type: %v", i, t.To)
+			}
+		case "timestamp":
+			if t.Layout == "" {
+				return nil, fmt.Errorf("timestamp transform %d has no layout", i)
+			}
+		case "hash":
+			if _, err := newHash(t.Algorithm); err != nil {
+				return nil, fmt.Errorf("hash transform %d: %v", i, err)
+			}
+		default:
+			return nil, fmt.Errorf("unknown transform type: %v", t.Type)
+		}
+		compiled = append(compiled, ct)
+	}
+	return compiled, nil
+}
+
+// applyTransforms runs the transforms of a rule in order on the fields captured from a line. A
+// transform whose field is missing is skipped, so optional captures don't fail the line.
+func applyTransforms(transforms []transform, values map[string]string) error {
+	for _, t := range transforms {
+		value, ok := values[t.Field]
+		if !ok {
+			continue
+		}
+		result, err := t.apply(value)
+		if err != nil {
+			return fmt.Errorf("failed to apply %s transform to field %s: %w", t.Type, t.Field, err)
+		}
+		values[t.Target] = result
+	}
+	return nil
+}
+
+func (t transform) apply(value string) (string, error) {
+	switch t.kind {
+	case "lowercase":
+		return strings.ToLower(value), nil
+	case "replace":
+		return t.pattern.ReplaceAllString(value, t.Replacement), nil
+	case "split":
+		parts := strings.Split(value, t.Separator)
+		index := t.Index
+		if index < 0 {
+			index += len(parts)
+		}
+		if index < 0 || index >= len(parts) {
+			return "", fmt.Errorf("index %d out of range of %d elements", t.Index, len(parts))
+		}
+		return parts[index], nil
+	case "coerce":
+		return coerce(value, t.To)
+	case "duration":
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatFloat(d.Seconds(), 'f', -1, 64), nil
+	case "bytesize":
+		return parseByteSize(value)
+	case "timestamp":
+		ts, err := parseTimestamp(value, t.Layout)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatFloat(float64(ts.UnixNano())/float64(time.Second), 'f', -1, 64), nil
+	case "hash":
+		h, _ := newHash(t.Algorithm)
+		h.Write([]byte(value))
+		return hex.EncodeToString(h.Sum(nil)), nil
+	case "lookup":
+		if mapped, ok := t.Table[value]; ok {
+			return mapped, nil
+		}
+		if t.Default != "" {
+			return t.Default, nil
+		}
+		return value, nil
+	}
+	return "", fmt.Errorf("unknown transform type: %v", t.Type)
+}
+
+// coerce normalizes a value to the canonical string form of a type, so that "3.0" becomes "3" for
+// Int64 and "yes" becomes "true" for Bool.
+func coerce(value, to string) (string, error) {
+	value = strings.TrimSpace(value)
+	switch strings.ToLower(to) {
+	case "int64":
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return strconv.FormatInt(i, 10), nil
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "", fmt.Errorf("cannot coerce %q to int64", value)
+		}
+		return strconv.FormatInt(int64(f), 10), nil
+	case "float64":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "", fmt.Errorf("cannot coerce %q to float64", value)
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	case "bool":
+		switch strings.ToLower(value) {
+		case "true", "t", "yes", "y", "on", "1":
+			return "true", nil
+		case "false", "f", "no", "n", "off", "0", "":
+			return "false", nil
+		}
+		return "", fmt.Errorf("cannot coerce %q to bool", value)
+	}
+	return "", fmt.Errorf("unknown coercion type: %v", to)
+}
+
+// parseByteSize converts a size such as "3.2MB" or "512KiB" to a number of bytes.
+func parseByteSize(value string) (string, error) {
+	match := byteSize.FindStringSubmatch(value)
+	if match == nil {
+		return "", fmt.Errorf("invalid byte size: %q", value)
+	}
+	multiplier, ok := byteUnits[strings.ToLower(match[2])]
+	if !ok {
+		return "", fmt.Errorf("unknown byte size unit: %q", match[2])
+	}
+	size, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid byte size: %q", value)
+	}
+	return strconv.FormatInt(int64(math.Round(size*multiplier)), 10), nil
+}
+
+// parseTimestamp parses a timestamp with a Go layout, one of the named layouts, or as seconds or
+// milliseconds since the epoch for the Unix and UnixMilli layouts.
+func parseTimestamp(value, layout string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	switch strings.ToLower(layout) {
+	case "unix":
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid unix timestamp: %q", value)
+		}
+		return time.Unix(0, int64(seconds*float64(time.Second))), nil
+	case "unixmilli":
+		millis, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid unix millisecond timestamp: %q", value)
+		}
+		return time.UnixMilli(millis), nil
+	}
+	if named, ok := timestampLayouts[strings.ToLower(layout)]; ok {
+		layout = named
+	}
+	return time.Parse(layout, value)
+}
+
+func newHash(algorithm string) (hash.Hash, error) {
+	switch strings.ToLower(algorithm) {
+	case "", "sha256":
+		return sha256.New(), nil
+	case "sha1":
+		return sha1.New(), nil
+	case "md5":
+		return md5.New(), nil
+	case "fnv":
+		return fnv.New64a(), nil
+	}
+	return nil, fmt.Errorf("unknown hash algorithm: %v", algorithm)
+}
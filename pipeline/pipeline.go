@@ -0,0 +1,267 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/devon-caron/metrifuge/exporter_manager/retry_queue"
+	"github.com/devon-caron/metrifuge/k8s/api"
+	"github.com/devon-caron/metrifuge/log_handler/log_processor"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	instance *Pipeline
+	once     sync.Once
+)
+
+// Config holds the sizes of the pipeline's channels and worker pools.
+type Config struct {
+	BufferSize    int           // capacity of the channel in front of each stage
+	ParseWorkers  int           // workers running rule patterns against lines, one per parse lane
+	EvalWorkers   int           // workers building metrics and forwarded logs from parsed lines, one per evaluation lane, at most ParseWorkers
+	ExportWorkers int           // workers sending batches to the exporters
+	BatchSize     int           // items an export worker collects before exporting them
+	FlushInterval time.Duration // longest an export worker holds on to an incomplete batch
+}
+
+// Processor parses and evaluates the lines of the pipeline, as log_processor.LogProcessor does.
+type Processor interface {
+	ParseLine(line log_processor.LogLine) *log_processor.ParsedLine
+	EvaluateLine(parsed *log_processor.ParsedLine) []api.ProcessedDataItem
+	ReleaseLine(parsed *log_processor.ParsedLine)
+}
+
+// Exporter exports the items of the pipeline and returns those that failed, as
+// exporter_manager.ExporterManager does.
+type Exporter interface {
+	ProcessItems(ctx context.Context, items []api.ProcessedDataItem) ([]api.ProcessedDataItem, error)
+}
+
+// item is a processed item on its way to the exporters, with the time its line was read.
+type item struct {
+	api.ProcessedDataItem
+	readAt time.Time
+}
+
+/**
+ * Pipeline moves log lines from the sources to the exporters through bounded channels:
+ * source → parse → rule eval → export. Each stage has its own pool of workers, and a full channel
+ * blocks the stage in front of it, so memory stays bounded and slow exporters slow down the
+ * sources instead of items piling up. Every batch is written to the retry queue before it is
+ * exported, and is only removed from it once every item of it is exported.
+ *
+ * Parse and evaluation workers each own a lane, and lines keep their order within a lane. Every
+ * line is dispatched to a lane picked by its log source, so sources whose rules need ordering stay
+ * on a single lane through parsing and evaluation, while the lines of other sources are spread over
+ * as many lanes as their parallelism allows. Export workers share the items of every lane, so the
+ * items of a source are only exported in order with a single export worker, the default.
+ */
+type Pipeline struct {
+	config Config
+	lp     Processor
+	em     Exporter
+	rq     *retry_queue.RetryQueue
+	log    *logrus.Logger
+	lines  chan log_processor.LogLine
+	lanes  []chan log_processor.LogLine     // parse lanes, each drained by one parse worker
+	parsed []chan *log_processor.ParsedLine // evaluation lanes, each drained by one evaluation worker
+	items  chan item
+	done   chan struct{} // Closed once the export stage has finished
+	stages map[string]*stage
+}
+
+// GetInstance returns the singleton instance of Pipeline
+func GetInstance() *Pipeline {
+	once.Do(func() {
+		instance = &Pipeline{}
+	})
+	return instance
+}
+
+func (p *Pipeline) Initialize(config Config, lp Processor, em Exporter,
+	rq *retry_queue.RetryQueue, log *logrus.Logger) error {
+	if config.BufferSize <= 0 || config.ParseWorkers <= 0 || config.EvalWorkers <= 0 ||
+		config.ExportWorkers <= 0 || config.BatchSize <= 0 || config.FlushInterval <= 0 {
+		return fmt.Errorf("pipeline sizes must be positive, got %+v", config)
+	}
+	// Every parse lane feeds a single evaluation lane, so extra evaluation workers would never get a line
+	if config.EvalWorkers > config.ParseWorkers {
+		return fmt.Errorf("pipeline evaluation workers (%d) must not outnumber parse workers (%d)", config.EvalWorkers, config.ParseWorkers)
+	}
+
+	p.config = config
+	p.lp = lp
+	p.em = em
+	p.rq = rq
+	p.log = log
+	p.lines = make(chan log_processor.LogLine, config.BufferSize)
+	// Lanes split the buffer of their stage between them
+	p.lanes = make([]chan log_processor.LogLine, config.ParseWorkers)
+	for i := range p.lanes {
+		p.lanes[i] = make(chan log_processor.LogLine, max(1, config.BufferSize/config.ParseWorkers))
+	}
+	p.parsed = make([]chan *log_processor.ParsedLine, config.EvalWorkers)
+	for i := range p.parsed {
+		p.parsed[i] = make(chan *log_processor.ParsedLine, max(1, config.BufferSize/config.EvalWorkers))
+	}
+	p.items = make(chan item, config.BufferSize)
+	p.done = make(chan struct{})
+	p.stages = map[string]*stage{
+		StageSource:   {},
+		StageParse:    {},
+		StageEvaluate: {},
+		StageExport:   {},
+		StageTotal:    {},
+	}
+	return nil
+}
+
+// Input returns the channel that log sources send their lines to.
+func (p *Pipeline) Input() chan<- log_processor.LogLine {
+	return p.lines
+}
+
+// Start runs the worker pools of every stage until Stop is called.
+func (p *Pipeline) Start(ctx context.Context) {
+	p.runStage(1, func(int) { p.dispatch() }, func() {
+		for _, lane := range p.lanes {
+			close(lane)
+		}
+	})
+	p.runStage(p.config.ParseWorkers, p.parseWorker, func() {
+		for _, lane := range p.parsed {
+			close(lane)
+		}
+	})
+	p.runStage(p.config.EvalWorkers, p.evaluateWorker, func() { close(p.items) })
+	p.runStage(p.config.ExportWorkers, func(int) { p.exportWorker(ctx) }, func() { close(p.done) })
+	p.log.Infof("pipeline started: %+v", p.config)
+}
+
+// Stop closes the pipeline's input and waits until every line already in it has been exported.
+// Nothing may be sent to Input once Stop has been called.
+func (p *Pipeline) Stop(ctx context.Context) error {
+	close(p.lines)
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to drain pipeline: %w", ctx.Err())
+	}
+}
+
+// runStage starts a pool of workers, each given its index, and calls after once all of them have
+// returned.
+func (p *Pipeline) runStage(workers int, worker func(lane int), after func()) {
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for lane := range workers {
+		go func() {
+			defer wg.Done()
+			worker(lane)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		after()
+	}()
+}
+
+// dispatch sends every line to the parse lane its log source picks. A full lane holds up the lines
+// behind it, as the shared input channel did before lanes.
+func (p *Pipeline) dispatch() {
+	for line := range p.lines {
+		p.lanes[line.SRU.Lane(len(p.lanes))] <- line
+	}
+}
+
+// parseWorker parses the lines of a parse lane. Lines of a parse lane all go to the same
+// evaluation lane, so their order is kept through evaluation.
+func (p *Pipeline) parseWorker(lane int) {
+	next := p.parsed[lane%len(p.parsed)]
+	for line := range p.lanes[lane] {
+		start := time.Now()
+		p.stages[StageSource].observe(start.Sub(line.ReadAt))
+		parsed := p.lp.ParseLine(line)
+		p.stages[StageParse].observe(time.Since(start))
+		next <- parsed
+	}
+}
+
+func (p *Pipeline) evaluateWorker(lane int) {
+	for parsed := range p.parsed[lane] {
+		start := time.Now()
+		processedDataItems := p.lp.EvaluateLine(parsed)
+		p.stages[StageEvaluate].observe(time.Since(start))
+		readAt := parsed.ReadAt
+		p.lp.ReleaseLine(parsed)
+		for _, processedDataItem := range processedDataItems {
+			p.items <- item{ProcessedDataItem: processedDataItem, readAt: readAt}
+		}
+	}
+}
+
+// exportWorker exports items in batches of up to BatchSize, and flushes incomplete batches every
+// FlushInterval so that quiet sources are not held back.
+func (p *Pipeline) exportWorker(ctx context.Context) {
+	ticker := time.NewTicker(p.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]item, 0, p.config.BatchSize)
+	for {
+		select {
+		case it, ok := <-p.items:
+			if !ok {
+				p.export(ctx, batch)
+				return
+			}
+			batch = append(batch, it)
+			if len(batch) >= p.config.BatchSize {
+				p.export(ctx, batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			p.export(ctx, batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (p *Pipeline) export(ctx context.Context, batch []item) {
+	if len(batch) == 0 {
+		return
+	}
+
+	items := make([]api.ProcessedDataItem, len(batch))
+	for i, it := range batch {
+		items[i] = it.ProcessedDataItem
+	}
+
+	// The batch is written ahead of its export, so that it is exported again after a crash
+	seq, walErr := p.rq.Enqueue(items)
+	if walErr != nil {
+		p.log.Errorf("failed to write %d items to the retry queue ahead of their export: %v", len(items), walErr)
+	}
+
+	start := time.Now()
+	failed, err := p.em.ProcessItems(ctx, items)
+	p.stages[StageExport].observe(time.Since(start))
+	if err != nil {
+		p.log.Errorf("failed to export %d of %d items: %v", len(failed), len(items), err)
+	}
+	if walErr == nil {
+		if err := p.rq.Settle(seq, failed, err); err != nil {
+			p.log.Errorf("failed to record the export of batch %d in the retry queue: %v", seq, err)
+		}
+	} else if len(failed) > 0 {
+		p.log.Errorf("dropping %d items that failed to export and could not be queued for retry", len(failed))
+	}
+
+	now := time.Now()
+	for _, it := range batch {
+		p.stages[StageTotal].observe(now.Sub(it.readAt))
+	}
+}
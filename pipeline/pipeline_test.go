@@ -0,0 +1,237 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/devon-caron/metrifuge/exporter_manager/retry_queue"
+	"github.com/devon-caron/metrifuge/k8s/api"
+	logsource "github.com/devon-caron/metrifuge/k8s/api/log_source"
+	"github.com/devon-caron/metrifuge/k8s/api/ruleset"
+	"github.com/devon-caron/metrifuge/log_handler/log_processor"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeProcessor turns every line into one forwarded log of the line, taking a random moment to
+// parse it so that lanes run out of step.
+type fakeProcessor struct{}
+
+func (fakeProcessor) ParseLine(line log_processor.LogLine) *log_processor.ParsedLine {
+	time.Sleep(time.Duration(rand.IntN(50)) * time.Microsecond)
+	return &log_processor.ParsedLine{LogLine: line}
+}
+
+func (fakeProcessor) EvaluateLine(parsed *log_processor.ParsedLine) []api.ProcessedDataItem {
+	return []api.ProcessedDataItem{{ForwardLog: parsed.Line, LogSourceInfo: parsed.Source}}
+}
+
+func (fakeProcessor) ReleaseLine(*log_processor.ParsedLine) {}
+
+// fakeExporter records the logs it exports by log source. If blocked is set, every export waits
+// until it is closed.
+type fakeExporter struct {
+	blocked chan struct{}
+	mu      sync.Mutex
+	logs    map[string][]string
+}
+
+func (fe *fakeExporter) ProcessItems(_ context.Context, items []api.ProcessedDataItem) ([]api.ProcessedDataItem, error) {
+	if fe.blocked != nil {
+		<-fe.blocked
+	}
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+	for _, item := range items {
+		fe.logs[item.LogSourceInfo.Name] = append(fe.logs[item.LogSourceInfo.Name], item.ForwardLog)
+	}
+	return nil, nil
+}
+
+func (fe *fakeExporter) exported() int {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+	n := 0
+	for _, logs := range fe.logs {
+		n += len(logs)
+	}
+	return n
+}
+
+func quietLogger() *logrus.Logger {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	return log
+}
+
+// sources returns the SRUs of log sources with the given parallelism, by name.
+func sources(t *testing.T, parallelism map[string]int) map[string]*log_processor.SourceRuleUnion {
+	t.Helper()
+	var logSources []logsource.LogSource
+	for name, n := range parallelism {
+		logSources = append(logSources, logsource.LogSource{
+			Metadata: api.Metadata{Namespace: "default", Name: name},
+			Spec:     logsource.LogSourceSpec{Parallelism: n},
+		})
+	}
+	lp := &log_processor.LogProcessor{}
+	lp.Initialize(logSources, []ruleset.RuleSet{}, 1, nil, quietLogger())
+	srus := make(map[string]*log_processor.SourceRuleUnion)
+	for _, ls := range logSources {
+		sru, err := lp.FindSRU(ls)
+		if err != nil {
+			t.Fatal(err)
+		}
+		srus[ls.Metadata.Name] = sru
+	}
+	return srus
+}
+
+func newTestPipeline(t *testing.T, config Config, em Exporter) *Pipeline {
+	t.Helper()
+	rq := &retry_queue.RetryQueue{}
+	if err := rq.Initialize(t.TempDir(), 1000, 3, time.Second, time.Minute, quietLogger()); err != nil {
+		t.Fatal(err)
+	}
+	p := &Pipeline{}
+	if err := p.Initialize(config, fakeProcessor{}, em, rq, quietLogger()); err != nil {
+		t.Fatalf("Initialize() = %v", err)
+	}
+	p.Start(context.Background())
+	return p
+}
+
+// send sends n numbered lines of a log source to the pipeline.
+func send(p *Pipeline, sru *log_processor.SourceRuleUnion, name string, n int) {
+	for i := range n {
+		p.Input() <- log_processor.LogLine{SRU: sru, Source: api.LogSourceInfo{Namespace: "default", Name: name},
+			Line: strconv.Itoa(i), ReadAt: time.Now()}
+	}
+}
+
+func stop(t *testing.T, p *Pipeline) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := p.Stop(ctx); err != nil {
+		t.Fatalf("Stop() = %v", err)
+	}
+}
+
+func TestInitializeValidatesLanes(t *testing.T) {
+	config := Config{BufferSize: 10, ParseWorkers: 2, EvalWorkers: 2, ExportWorkers: 1, BatchSize: 5, FlushInterval: time.Second}
+	if err := (&Pipeline{}).Initialize(config, nil, nil, nil, logrus.New()); err != nil {
+		t.Fatalf("Initialize() = %v, want no error", err)
+	}
+
+	config.EvalWorkers = 3
+	if err := (&Pipeline{}).Initialize(config, nil, nil, nil, logrus.New()); err == nil {
+		t.Error("Initialize() accepted more evaluation workers than parse workers")
+	}
+}
+
+func TestOrderedSourcesKeepTheirOrder(t *testing.T) {
+	tests := []struct {
+		parseWorkers, evalWorkers int
+	}{
+		{1, 1},
+		{2, 2}, // the defaults
+		{4, 2}, // parse lanes share evaluation lanes
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d parse, %d eval workers", tt.parseWorkers, tt.evalWorkers), func(t *testing.T) {
+			config := Config{BufferSize: 16, ParseWorkers: tt.parseWorkers, EvalWorkers: tt.evalWorkers,
+				ExportWorkers: 1, BatchSize: 7, FlushInterval: 10 * time.Millisecond}
+			em := &fakeExporter{logs: make(map[string][]string)}
+			p := newTestPipeline(t, config, em)
+			srus := sources(t, map[string]int{"a": 1, "b": 1, "c": 1})
+
+			const lines = 300
+			var wg sync.WaitGroup
+			for name, sru := range srus {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					send(p, sru, name, lines)
+				}()
+			}
+			wg.Wait()
+			stop(t, p)
+
+			for name := range srus {
+				logs := em.logs[name]
+				if len(logs) != lines {
+					t.Fatalf("source %s: exported %d lines, want %d", name, len(logs), lines)
+				}
+				for i, log := range logs {
+					if log != strconv.Itoa(i) {
+						t.Fatalf("source %s: line %d exported as %s, out of order", name, i, log)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestStopDrainsEverything(t *testing.T) {
+	config := Config{BufferSize: 64, ParseWorkers: 2, EvalWorkers: 2, ExportWorkers: 2, BatchSize: 10, FlushInterval: time.Hour}
+	em := &fakeExporter{logs: make(map[string][]string)}
+	p := newTestPipeline(t, config, em)
+	srus := sources(t, map[string]int{"spread": 2})
+
+	// Lines are still in the channels and in incomplete batches, which only a flush would export
+	send(p, srus["spread"], "spread", 55)
+	stop(t, p)
+	if got := em.exported(); got != 55 {
+		t.Errorf("Stop() returned with %d of 55 lines exported", got)
+	}
+	if n := p.rq.Len(); n != 0 {
+		t.Errorf("%d batches left in the retry queue, want none once exported", n)
+	}
+}
+
+func TestBlockedExportHoldsBackSources(t *testing.T) {
+	config := Config{BufferSize: 4, ParseWorkers: 1, EvalWorkers: 1, ExportWorkers: 1, BatchSize: 1, FlushInterval: time.Hour}
+	em := &fakeExporter{blocked: make(chan struct{}), logs: make(map[string][]string)}
+	p := newTestPipeline(t, config, em)
+	srus := sources(t, map[string]int{"app": 1})
+
+	const lines = 100
+	var sent sync.WaitGroup
+	sent.Add(1)
+	var mu sync.Mutex
+	accepted := 0
+	go func() {
+		defer sent.Done()
+		for i := range lines {
+			p.Input() <- log_processor.LogLine{SRU: srus["app"], Source: api.LogSourceInfo{Namespace: "default", Name: "app"},
+				Line: strconv.Itoa(i), ReadAt: time.Now()}
+			mu.Lock()
+			accepted++
+			mu.Unlock()
+		}
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	mu.Lock()
+	held := accepted
+	mu.Unlock()
+	// The channels of every stage and the line each worker holds, nothing more
+	if limit := 4 + 4 + 4 + 4 + 4; held > limit {
+		t.Errorf("sources sent %d lines past a blocked export, want at most %d", held, limit)
+	}
+	if held == lines {
+		t.Fatal("a blocked export did not hold back the sources")
+	}
+
+	close(em.blocked)
+	sent.Wait()
+	stop(t, p)
+	if got := em.exported(); got != lines {
+		t.Errorf("exported %d lines once unblocked, want %d", got, lines)
+	}
+}
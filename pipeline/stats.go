@@ -0,0 +1,83 @@
+package pipeline
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	StageSource   = "source"   // from a line being read to a parse worker picking it up
+	StageParse    = "parse"    // running the patterns of every rule against a line
+	StageEvaluate = "evaluate" // building metrics and forwarded logs from a parsed line
+	StageExport   = "export"   // sending a batch to the exporters
+	StageTotal    = "total"    // from a line being read to its items being exported
+)
+
+// StageStats reports the throughput and latency of a pipeline stage since startup.
+type StageStats struct {
+	Name          string  `json:"name"`
+	Workers       int     `json:"workers,omitempty"`
+	Processed     int64   `json:"processed"`
+	QueueLength   int     `json:"queueLength"`
+	QueueCapacity int     `json:"queueCapacity"`
+	AvgLatencyMs  float64 `json:"avgLatencyMs"`
+	MaxLatencyMs  float64 `json:"maxLatencyMs"`
+}
+
+// stage accumulates the latencies observed by a stage's workers.
+type stage struct {
+	count int64
+	total time.Duration
+	max   time.Duration
+	mu    sync.Mutex
+}
+
+func (s *stage) observe(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count++
+	s.total += latency
+	s.max = max(s.max, latency)
+}
+
+func (s *stage) stats(name string) StageStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := StageStats{
+		Name:         name,
+		Processed:    s.count,
+		MaxLatencyMs: float64(s.max) / float64(time.Millisecond),
+	}
+	if s.count > 0 {
+		stats.AvgLatencyMs = float64(s.total) / float64(s.count) / float64(time.Millisecond)
+	}
+	return stats
+}
+
+// Stats returns the stats of every stage, in pipeline order. The queue of a stage is the channel
+// in front of it, including the stage's lanes.
+func (p *Pipeline) Stats() []StageStats {
+	if p.stages == nil {
+		return nil
+	}
+
+	source := p.stages[StageSource].stats(StageSource)
+	parse := p.stages[StageParse].stats(StageParse)
+	parse.Workers = p.config.ParseWorkers
+	parse.QueueLength, parse.QueueCapacity = len(p.lines), cap(p.lines)
+	for _, lane := range p.lanes {
+		parse.QueueLength += len(lane)
+		parse.QueueCapacity += cap(lane)
+	}
+	evaluate := p.stages[StageEvaluate].stats(StageEvaluate)
+	evaluate.Workers = p.config.EvalWorkers
+	for _, lane := range p.parsed {
+		evaluate.QueueLength += len(lane)
+		evaluate.QueueCapacity += cap(lane)
+	}
+	export := p.stages[StageExport].stats(StageExport)
+	export.Workers = p.config.ExportWorkers
+	export.QueueLength, export.QueueCapacity = len(p.items), cap(p.items)
+
+	return []StageStats{source, parse, evaluate, export, p.stages[StageTotal].stats(StageTotal)}
+}
@@ -7,6 +7,7 @@ import (
 	e "github.com/devon-caron/metrifuge/k8s/api/exporter"
 	ls "github.com/devon-caron/metrifuge/k8s/api/log_source"
 	rs "github.com/devon-caron/metrifuge/k8s/api/ruleset"
+	"github.com/devon-caron/metrifuge/k8s/client/clientset/versioned"
 	"k8s.io/client-go/rest"
 )
 
@@ -22,6 +23,7 @@ type Resources struct {
 	exporters  []e.Exporter
 	kubeConfig *rest.Config
 	k8sClient  *api.K8sClientWrapper
+	mfClient   versioned.Interface
 }
 
 // GetInstance returns the singleton instance of Resources
@@ -63,6 +65,13 @@ func (r *Resources) GetK8sClient() *api.K8sClientWrapper {
 	return r.k8sClient
 }
 
+// GetMetrifugeClient returns the typed clientset of the metrifuge resources
+func (r *Resources) GetMetrifugeClient() versioned.Interface {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.mfClient
+}
+
 // Setters with write locks
 func (r *Resources) SetRuleSets(ruleSets []rs.RuleSet) {
 	r.mu.Lock()
@@ -93,3 +102,9 @@ func (r *Resources) SetK8sClient(k8sClient *api.K8sClientWrapper) {
 	defer r.mu.Unlock()
 	r.k8sClient = k8sClient
 }
+
+func (r *Resources) SetMetrifugeClient(mfClient versioned.Interface) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mfClient = mfClient
+}
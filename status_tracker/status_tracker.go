@@ -0,0 +1,237 @@
+package status_tracker
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	instance *StatusTracker
+	once     sync.Once
+)
+
+// Offending lines kept for each RuleSet, and the length they are truncated to
+const (
+	maxErrorSamples = 10
+	maxSampleLength = 512
+)
+
+// LogSourceStats is what metrifuge has observed about a single LogSource
+type LogSourceStats struct {
+	LinesRead   int64
+	LateEvents  int64 // timestamped events whose metrics were dropped for arriving too late
+	Streaming   bool
+	Ready       bool
+	ReadyReason string
+	LastError   string
+}
+
+// RuleSetStats is what metrifuge has observed about a single RuleSet
+type RuleSetStats struct {
+	RulesMatched int64
+	Errors       int64
+	LastError    string
+	LoadError    string           // why the current generation of the RuleSet was rejected, empty once it loads
+	RuleErrors   map[string]int64 // lines each rule failed on, by rule name or index
+	ErrorSamples []ErrorSample    // most recent lines the rules failed on, oldest first
+}
+
+// ErrorSample is a line that a rule failed on
+type ErrorSample struct {
+	Rule  string
+	Stage string
+	Line  string
+	Error string
+	Time  time.Time
+}
+
+// ExporterStats is what metrifuge has observed about a single Exporter
+type ExporterStats struct {
+	ItemsExported int64
+	Errors        int64
+	LastError     string
+}
+
+/**
+ * StatusTracker collects runtime statistics for every metrifuge resource, so that they can be
+ * written back to the status subresource of the corresponding CR.
+ */
+type StatusTracker struct {
+	mu         sync.RWMutex
+	logSources map[string]*LogSourceStats
+	ruleSets   map[string]*RuleSetStats
+	exporters  map[string]*ExporterStats
+}
+
+// GetInstance returns the singleton instance of StatusTracker
+func GetInstance() *StatusTracker {
+	once.Do(func() {
+		instance = &StatusTracker{
+			logSources: make(map[string]*LogSourceStats),
+			ruleSets:   make(map[string]*RuleSetStats),
+			exporters:  make(map[string]*ExporterStats),
+		}
+	})
+	return instance
+}
+
+// Key identifies a resource by namespace and name
+func Key(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+func (st *StatusTracker) logSource(key string) *LogSourceStats {
+	stats, ok := st.logSources[key]
+	if !ok {
+		stats = &LogSourceStats{}
+		st.logSources[key] = stats
+	}
+	return stats
+}
+
+func (st *StatusTracker) ruleSet(key string) *RuleSetStats {
+	stats, ok := st.ruleSets[key]
+	if !ok {
+		stats = &RuleSetStats{}
+		st.ruleSets[key] = stats
+	}
+	return stats
+}
+
+func (st *StatusTracker) exporter(key string) *ExporterStats {
+	stats, ok := st.exporters[key]
+	if !ok {
+		stats = &ExporterStats{}
+		st.exporters[key] = stats
+	}
+	return stats
+}
+
+func (st *StatusTracker) AddLinesRead(key string, n int) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.logSource(key).LinesRead += int64(n)
+}
+
+func (st *StatusTracker) AddLateEvents(key string, n int) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.logSource(key).LateEvents += int64(n)
+}
+
+// SetStreaming records whether the log stream of a source is running, and why it stopped if not.
+func (st *StatusTracker) SetStreaming(key string, streaming bool, err error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	stats := st.logSource(key)
+	stats.Streaming = streaming
+	if err != nil {
+		stats.LastError = err.Error()
+	}
+}
+
+// SetLogSourceReady records whether a source could be set up, with the reason if it could not.
+func (st *StatusTracker) SetLogSourceReady(key string, ready bool, reason string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	stats := st.logSource(key)
+	stats.Ready = ready
+	stats.ReadyReason = reason
+}
+
+func (st *StatusTracker) AddRulesMatched(key string, n int) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.ruleSet(key).RulesMatched += int64(n)
+}
+
+func (st *StatusTracker) RecordRuleError(key string, err error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	stats := st.ruleSet(key)
+	stats.Errors++
+	stats.LastError = err.Error()
+}
+
+// RecordRuleFailure records a line that a rule of a RuleSet failed on, keeping it as a sample of
+// the RuleSet's offending lines.
+func (st *StatusTracker) RecordRuleFailure(key, rule, stage, line string, err error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	stats := st.ruleSet(key)
+	stats.Errors++
+	stats.LastError = err.Error()
+	if stats.RuleErrors == nil {
+		stats.RuleErrors = make(map[string]int64)
+	}
+	stats.RuleErrors[rule]++
+
+	if len(line) > maxSampleLength {
+		line = strings.ToValidUTF8(line[:maxSampleLength], "")
+	}
+	if len(stats.ErrorSamples) == maxErrorSamples {
+		stats.ErrorSamples = slices.Delete(stats.ErrorSamples, 0, 1)
+	}
+	stats.ErrorSamples = append(stats.ErrorSamples, ErrorSample{
+		Rule: rule, Stage: stage, Line: line, Error: err.Error(), Time: time.Now(),
+	})
+}
+
+// SetRuleSetLoadError records why a RuleSet could not be loaded, or clears it if err is nil.
+func (st *StatusTracker) SetRuleSetLoadError(key string, err error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	stats := st.ruleSet(key)
+	stats.LoadError = ""
+	if err != nil {
+		stats.LoadError = err.Error()
+	}
+}
+
+func (st *StatusTracker) AddItemsExported(key string, n int) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.exporter(key).ItemsExported += int64(n)
+}
+
+func (st *StatusTracker) RecordExportError(key string, err error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	stats := st.exporter(key)
+	stats.Errors++
+	stats.LastError = err.Error()
+}
+
+// Getters return copies, so callers can read them without holding the lock
+func (st *StatusTracker) GetLogSourceStats(key string) LogSourceStats {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	if stats, ok := st.logSources[key]; ok {
+		return *stats
+	}
+	return LogSourceStats{}
+}
+
+func (st *StatusTracker) GetRuleSetStats(key string) RuleSetStats {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	if stats, ok := st.ruleSets[key]; ok {
+		copied := *stats
+		copied.RuleErrors = maps.Clone(stats.RuleErrors)
+		copied.ErrorSamples = slices.Clone(stats.ErrorSamples)
+		return copied
+	}
+	return RuleSetStats{}
+}
+
+func (st *StatusTracker) GetExporterStats(key string) ExporterStats {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	if stats, ok := st.exporters[key]; ok {
+		return *stats
+	}
+	return ExporterStats{}
+}
@@ -0,0 +1,67 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/devon-caron/metrifuge/api/errhandler"
+	"github.com/devon-caron/metrifuge/k8s"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ConvertHandler answers a ConversionReview, converting RuleSets, LogSources and Exporters to the
+// version the API server asks for.
+func ConvertHandler(w http.ResponseWriter, r *http.Request) {
+	var review apiextensionsv1.ConversionReview
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+		errhandler.RequestErrorHandler(w, fmt.Errorf("failed to decode conversion review: %v", err))
+		return
+	}
+	if review.Request == nil {
+		errhandler.RequestErrorHandler(w, fmt.Errorf("conversion review has no request"))
+		return
+	}
+
+	review.Response = Convert(review.Request)
+	review.Request = nil
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		errhandler.InternalErrorHandler(w)
+		return
+	}
+}
+
+// Convert converts the objects of a conversion request. Either all of them are converted, or the
+// conversion fails as a whole.
+func Convert(request *apiextensionsv1.ConversionRequest) *apiextensionsv1.ConversionResponse {
+	response := &apiextensionsv1.ConversionResponse{UID: request.UID}
+	for _, object := range request.Objects {
+		converted, err := convertObject(object.Raw, request.DesiredAPIVersion)
+		if err != nil {
+			log.Warnf("failed to convert to %s: %v", request.DesiredAPIVersion, err)
+			response.ConvertedObjects = nil
+			response.Result = metav1.Status{Status: metav1.StatusFailure, Message: err.Error()}
+			return response
+		}
+		response.ConvertedObjects = append(response.ConvertedObjects, runtime.RawExtension{Raw: converted})
+	}
+	response.Result = metav1.Status{Status: metav1.StatusSuccess}
+	return response
+}
+
+func convertObject(raw []byte, apiVersion string) ([]byte, error) {
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(raw); err != nil {
+		return nil, fmt.Errorf("failed to decode object: %v", err)
+	}
+	converted, err := k8s.ConvertObject(obj, apiVersion)
+	if err != nil {
+		return nil, err
+	}
+	return converted.MarshalJSON()
+}
@@ -0,0 +1,143 @@
+package webhook
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/devon-caron/metrifuge/k8s/api/metrifuge/v1beta1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// alphaRuleSet is a v1alpha1 RuleSet with a nested conditional on a string field.
+const alphaRuleSet = `{
+	"apiVersion": "metrifuge.com/v1alpha1",
+	"kind": "RuleSet",
+	"metadata": {"name": "zips", "namespace": "default"},
+	"spec": {
+		"selector": {"matchLabels": {"app": "test"}},
+		"rules": [{
+			"pattern": "%{WORD:zip} %{NUMBER:bytes}",
+			"action": "Conditional",
+			"conditional": {
+				"field1": {"type": "String", "grokKey": "zip"},
+				"operator": "Equals",
+				"field2": {"type": "String", "manualValue": "007"},
+				"actionTrue": "Conditional",
+				"conditionalTrue": {
+					"field1": {"type": "Int64", "grokKey": "bytes"},
+					"operator": "GreaterThan",
+					"field2": {"type": "Int64", "manualValue": "100"},
+					"actionTrue": "Forward",
+					"actionFalse": "Discard"
+				},
+				"actionFalse": "Discard"
+			}
+		}]
+	}
+}`
+
+// convert converts objects through a ConversionReview request, failing the test if it fails.
+func convert(t *testing.T, apiVersion string, objects ...*unstructured.Unstructured) []*unstructured.Unstructured {
+	t.Helper()
+	request := &apiextensionsv1.ConversionRequest{UID: "uid", DesiredAPIVersion: apiVersion}
+	for _, obj := range objects {
+		raw, err := obj.MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		request.Objects = append(request.Objects, runtime.RawExtension{Raw: raw})
+	}
+	response := Convert(request)
+	if response.UID != "uid" || response.Result.Status != metav1.StatusSuccess {
+		t.Fatalf("Convert() to %s = %+v", apiVersion, response)
+	}
+	converted := make([]*unstructured.Unstructured, len(response.ConvertedObjects))
+	for i, object := range response.ConvertedObjects {
+		converted[i] = &unstructured.Unstructured{}
+		if err := converted[i].UnmarshalJSON(object.Raw); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return converted
+}
+
+func alpha(t *testing.T) *unstructured.Unstructured {
+	t.Helper()
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON([]byte(alphaRuleSet)); err != nil {
+		t.Fatal(err)
+	}
+	return obj
+}
+
+func TestConvertRoundTrip(t *testing.T) {
+	original := alpha(t)
+	beta := convert(t, "metrifuge.com/v1beta1", original)[0]
+
+	var spec v1beta1.RuleSetSpec
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(beta.Object["spec"].(map[string]any), &spec); err != nil {
+		t.Fatalf("converted spec doesn't decode as v1beta1: %v", err)
+	}
+	conditional := spec.Rules[0].Conditional
+	if want := `raw["zip"] == "007"`; conditional.When != want {
+		t.Errorf("when = %s, want %s", conditional.When, want)
+	}
+	if nested := conditional.Then.Conditional; nested == nil || nested.When != `double(fields["bytes"]) > 100.0` {
+		t.Errorf("nested conditional = %+v, want one on bytes", nested)
+	}
+	if _, ok := beta.GetAnnotations()[v1beta1.ConditionsAnnotation]; !ok {
+		t.Errorf("annotations = %v, want the saved conditions", beta.GetAnnotations())
+	}
+	if err := Validate(beta); err != nil {
+		t.Errorf("Validate() of the converted RuleSet = %v", err)
+	}
+
+	back := convert(t, "metrifuge.com/v1alpha1", beta)[0]
+	if !reflect.DeepEqual(back.Object, original.Object) {
+		got, _ := json.MarshalIndent(back.Object, "", "  ")
+		t.Errorf("round trip gave\n%s\nwant the original RuleSet", got)
+	}
+}
+
+func TestConvertKeepsEditedExpressions(t *testing.T) {
+	beta := convert(t, "metrifuge.com/v1beta1", alpha(t))[0]
+	edited := `raw["zip"] == "008"`
+	rules := beta.Object["spec"].(map[string]any)["rules"].([]any)
+	rules[0].(map[string]any)["conditional"].(map[string]any)["when"] = edited
+
+	back := convert(t, "metrifuge.com/v1alpha1", beta)[0]
+	conditional, _, _ := unstructured.NestedMap(back.Object["spec"].(map[string]any)["rules"].([]any)[0].(map[string]any), "conditional")
+	if conditional["expression"] != edited || conditional["operator"] != nil {
+		t.Errorf("conditional = %v, want the edited expression instead of the saved condition", conditional)
+	}
+	// The unedited nested condition is still restored
+	if nested, _, _ := unstructured.NestedString(conditional, "conditionalTrue", "operator"); nested != "GreaterThan" {
+		t.Errorf("nested operator = %q, want GreaterThan", nested)
+	}
+	if _, ok := back.GetAnnotations()[v1beta1.ConditionsAnnotation]; ok {
+		t.Errorf("annotations = %v, want the saved conditions removed", back.GetAnnotations())
+	}
+}
+
+func TestConvertFailsAsAWhole(t *testing.T) {
+	unsupported := alpha(t)
+	unsupported.SetAPIVersion("metrifuge.com/v2")
+	raw := func(obj *unstructured.Unstructured) runtime.RawExtension {
+		data, err := obj.MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return runtime.RawExtension{Raw: data}
+	}
+	response := Convert(&apiextensionsv1.ConversionRequest{
+		DesiredAPIVersion: "metrifuge.com/v1beta1",
+		Objects:           []runtime.RawExtension{raw(alpha(t)), raw(unsupported)},
+	})
+	if response.Result.Status != metav1.StatusFailure || response.ConvertedObjects != nil {
+		t.Errorf("Convert() = %+v, want a failure without converted objects", response)
+	}
+}
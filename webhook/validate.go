@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/devon-caron/metrifuge/api/errhandler"
+	"github.com/devon-caron/metrifuge/exporter_manager"
+	"github.com/devon-caron/metrifuge/k8s"
+	e "github.com/devon-caron/metrifuge/k8s/api/exporter"
+	ls "github.com/devon-caron/metrifuge/k8s/api/log_source"
+	rs "github.com/devon-caron/metrifuge/k8s/api/ruleset"
+	"github.com/devon-caron/metrifuge/log_handler/log_processor"
+	"github.com/devon-caron/metrifuge/resources"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ValidateHandler answers an AdmissionReview for a RuleSet, LogSource or Exporter, rejecting it
+// if metrifuge would fail to load it.
+func ValidateHandler(w http.ResponseWriter, r *http.Request) {
+	var review admissionv1.AdmissionReview
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+		errhandler.RequestErrorHandler(w, fmt.Errorf("failed to decode admission review: %v", err))
+		return
+	}
+	if review.Request == nil {
+		errhandler.RequestErrorHandler(w, fmt.Errorf("admission review has no request"))
+		return
+	}
+
+	review.Response = Review(review.Request)
+	review.Request = nil
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		errhandler.InternalErrorHandler(w)
+		return
+	}
+}
+
+// Review decides whether an admission request may go through. Only the objects being created or
+// updated are validated, deletions are always allowed.
+func Review(request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	response := &admissionv1.AdmissionResponse{UID: request.UID, Allowed: true}
+	if request.Operation != admissionv1.Create && request.Operation != admissionv1.Update {
+		return response
+	}
+
+	obj := &unstructured.Unstructured{}
+	err := obj.UnmarshalJSON(request.Object.Raw)
+	if err == nil {
+		// Objects created without a namespace get the one of the request
+		if obj.GetNamespace() == "" {
+			obj.SetNamespace(request.Namespace)
+		}
+		err = Validate(obj)
+	}
+	if err != nil {
+		log.Infof("rejected %s %s/%s: %v", request.Kind.Kind, request.Namespace, request.Name, err)
+		response.Allowed = false
+		response.Result = &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    http.StatusUnprocessableEntity,
+			Reason:  metav1.StatusReasonInvalid,
+			Message: err.Error(),
+		}
+	}
+	return response
+}
+
+// Validate converts a metrifuge resource and compiles or checks it the same way metrifuge does
+// when it loads the resource.
+func Validate(obj *unstructured.Unstructured) error {
+	resource, err := k8s.ConvertResource(obj, obj.GetKind())
+	if err != nil {
+		return err
+	}
+
+	switch resource := resource.(type) {
+	case rs.RuleSet:
+		return log_processor.ValidateRuleSet(resource, resources.GetInstance().GetK8sClient())
+	case ls.LogSource:
+		if resource.Spec.Parallelism < 0 {
+			return fmt.Errorf("parallelism must not be negative, got %d", resource.Spec.Parallelism)
+		}
+		_, err := resource.Spec.GetSource()
+		return err
+	case e.Exporter:
+		return exporter_manager.ValidateExporter(resource)
+	default:
+		return fmt.Errorf("unsupported kind: %s", obj.GetKind())
+	}
+}
@@ -0,0 +1,114 @@
+package webhook
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ruleSetReview is an AdmissionReview request for a v1alpha1 RuleSet with the given rules.
+func ruleSetReview(t *testing.T, operation admissionv1.Operation, rules ...map[string]any) *admissionv1.AdmissionRequest {
+	t.Helper()
+	raw, err := json.Marshal(map[string]any{
+		"apiVersion": "metrifuge.com/v1alpha1",
+		"kind":       "RuleSet",
+		"metadata":   map[string]any{"name": "rules"},
+		"spec": map[string]any{
+			"selector": map[string]any{"matchLabels": map[string]any{"app": "test"}},
+			"rules":    rules,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &admissionv1.AdmissionRequest{
+		UID:       "uid",
+		Kind:      metav1.GroupVersionKind{Group: "metrifuge.com", Version: "v1alpha1", Kind: "RuleSet"},
+		Namespace: "default",
+		Name:      "rules",
+		Operation: operation,
+		Object:    runtime.RawExtension{Raw: raw},
+	}
+}
+
+func TestReview(t *testing.T) {
+	counter := func(value map[string]any) map[string]any {
+		return map[string]any{"name": "lines", "kind": "Int64Counter", "value": value}
+	}
+	tests := []struct {
+		name      string
+		operation admissionv1.Operation
+		rule      map[string]any
+		reason    string // part of the rejection message, empty if the request is allowed
+	}{
+		{
+			name:      "valid",
+			operation: admissionv1.Create,
+			rule: map[string]any{"pattern": "%{WORD:user} %{NUMBER:bytes}", "action": "Forward",
+				"metrics": []any{counter(map[string]any{"type": "Int64", "grokKey": "bytes"})}},
+		},
+		{
+			name:      "bad grok pattern",
+			operation: admissionv1.Create,
+			rule:      map[string]any{"pattern": "%{NOSUCHPATTERN:user}", "action": "Forward"},
+			reason:    "invalid pattern of rule 0",
+		},
+		{
+			name:      "unknown action",
+			operation: admissionv1.Update,
+			rule:      map[string]any{"pattern": "%{WORD:user}", "action": "Analyze"},
+			reason:    "unknown action of rule 0",
+		},
+		{
+			name:      "non-numeric Int64 manual value",
+			operation: admissionv1.Create,
+			rule: map[string]any{"pattern": "%{WORD:user}", "action": "Forward",
+				"metrics": []any{counter(map[string]any{"type": "Int64", "manualValue": "one"})}},
+			reason: "value of metric lines",
+		},
+		{
+			name:      "missing conditional",
+			operation: admissionv1.Create,
+			rule:      map[string]any{"pattern": "%{WORD:user}", "action": "Conditional"},
+			reason:    "no conditional block",
+		},
+		{
+			name:      "deletion of an invalid rule set",
+			operation: admissionv1.Delete,
+			rule:      map[string]any{"pattern": "%{WORD:user}", "action": "Analyze"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := Review(ruleSetReview(t, tt.operation, tt.rule))
+			if response.UID != "uid" {
+				t.Errorf("UID = %q, want the request's", response.UID)
+			}
+			if tt.reason == "" {
+				if !response.Allowed {
+					t.Errorf("Review() rejected the request: %v", response.Result.Message)
+				}
+				return
+			}
+			if response.Allowed {
+				t.Fatalf("Review() allowed the request, want it rejected for %q", tt.reason)
+			}
+			if response.Result == nil || response.Result.Reason != metav1.StatusReasonInvalid ||
+				!strings.Contains(response.Result.Message, tt.reason) {
+				t.Errorf("Review() result = %+v, want an invalid reason mentioning %q", response.Result, tt.reason)
+			}
+		})
+	}
+}
+
+func TestValidateUnsupportedKind(t *testing.T) {
+	request := ruleSetReview(t, admissionv1.Create)
+	request.Object.Raw = []byte(strings.Replace(string(request.Object.Raw), `"kind":"RuleSet"`, `"kind":"Pipeline"`, 1))
+	if response := Review(request); response.Allowed {
+		t.Error("Review() allowed a kind metrifuge doesn't know")
+	}
+}
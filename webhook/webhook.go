@@ -0,0 +1,53 @@
+package webhook
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+
+	"github.com/devon-caron/metrifuge/global"
+	"github.com/devon-caron/metrifuge/logger"
+	"github.com/go-chi/chi"
+)
+
+var (
+	log    = logger.Get()
+	server *http.Server
+)
+
+// StartWebhook serves the validating admission webhook and the conversion webhook of RuleSets over
+// TLS, with the tls.crt and tls.key of MF_WEBHOOK_CERT_DIR, which the API server must trust through
+// the caBundle of the webhook configuration and of the RuleSet CRD.
+func StartWebhook() error {
+	certFile := filepath.Join(global.WEBHOOK_CERT_DIR, "tls.crt")
+	keyFile := filepath.Join(global.WEBHOOK_CERT_DIR, "tls.key")
+	// Loaded up front so that a missing certificate stops startup rather than every request
+	if _, err := tls.LoadX509KeyPair(certFile, keyFile); err != nil {
+		return fmt.Errorf("failed to load webhook certificate: %v", err)
+	}
+
+	router := chi.NewRouter()
+	router.Post("/validate", ValidateHandler)
+	router.Post("/convert", ConvertHandler)
+
+	server = &http.Server{Addr: ":" + global.WEBHOOK_PORT, Handler: router}
+	go func() {
+		log.Infof("webhooks listening on port %s", global.WEBHOOK_PORT)
+		if err := server.ListenAndServeTLS(certFile, keyFile); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("webhooks stopped: %v", err)
+		}
+	}()
+	return nil
+}
+
+// StopWebhook stops accepting admission and conversion requests and waits for the ones in progress
+// to complete.
+func StopWebhook(ctx context.Context) error {
+	if server == nil {
+		return nil
+	}
+	return server.Shutdown(ctx)
+}